@@ -0,0 +1,426 @@
+// Package compress provides an HTTP middleware that compresses responses
+// with brotli, zstd, gzip or deflate based on Accept-Encoding, and
+// transparently decompresses gzip-encoded request bodies.
+package compress
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/vietpham102301/lightway/pkg/context"
+	aerror "github.com/vietpham102301/lightway/pkg/errors"
+)
+
+// Supported content codings.
+const (
+	Brotli  = "br"
+	Zstd    = "zstd"
+	Gzip    = "gzip"
+	Deflate = "deflate"
+)
+
+const (
+	// DefaultMinLength is the smallest body that is worth compressing.
+	DefaultMinLength = 1024
+
+	// DefaultMaxRequestBodySize caps decompressed request bodies, guarding
+	// against decompression bombs (10 MiB).
+	DefaultMaxRequestBodySize = 10 << 20
+)
+
+// DefaultExcludedContentTypes lists media types that are already compressed.
+// Entries ending in "/" match a whole top-level type.
+var DefaultExcludedContentTypes = []string{
+	"image/",
+	"video/",
+	"audio/",
+	"font/woff",
+	"font/woff2",
+	"application/zip",
+	"application/gzip",
+	"application/x-gzip",
+	"application/zstd",
+	"application/x-brotli",
+	"application/x-7z-compressed",
+	"application/x-rar-compressed",
+	"application/pdf",
+	"application/octet-stream",
+}
+
+// Config holds compression middleware options.
+type Config struct {
+	// Encodings lists the codings the server offers, most preferred first.
+	// Default: br, zstd, gzip, deflate
+	Encodings []string
+
+	// MinLength is the minimum response size in bytes to compress.
+	// Smaller bodies are sent as-is unless the handler flushes first.
+	// Default: 1024
+	MinLength int
+
+	// ExcludedContentTypes are media types (or "type/" prefixes) that are
+	// never compressed. Default: DefaultExcludedContentTypes
+	ExcludedContentTypes []string
+
+	// MaxRequestBodySize caps the decompressed size of gzip request bodies.
+	// Reading past it fails with an error. Negative means unlimited.
+	// Default: DefaultMaxRequestBodySize (10 MiB)
+	MaxRequestBodySize int64
+}
+
+func (c *Config) applyDefaults() {
+	if len(c.Encodings) == 0 {
+		c.Encodings = []string{Brotli, Zstd, Gzip, Deflate}
+	}
+	if c.MinLength <= 0 {
+		c.MinLength = DefaultMinLength
+	}
+	if c.ExcludedContentTypes == nil {
+		c.ExcludedContentTypes = DefaultExcludedContentTypes
+	}
+	if c.MaxRequestBodySize == 0 {
+		c.MaxRequestBodySize = DefaultMaxRequestBodySize
+	}
+}
+
+// encoder is the common surface of the gzip, flate, brotli and zstd writers.
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+var encoderPools = map[string]*sync.Pool{
+	Gzip: {New: func() any {
+		w, _ := gzip.NewWriterLevel(io.Discard, gzip.DefaultCompression)
+		return w
+	}},
+	Deflate: {New: func() any {
+		w, _ := flate.NewWriter(io.Discard, flate.DefaultCompression)
+		return w
+	}},
+	Brotli: {New: func() any {
+		return brotli.NewWriterLevel(io.Discard, brotli.DefaultCompression)
+	}},
+	Zstd: {New: func() any {
+		w, _ := zstd.NewWriter(io.Discard,
+			zstd.WithEncoderConcurrency(1),
+			zstd.WithLowerEncoderMem(true),
+		)
+		return w
+	}},
+}
+
+// New returns a compression middleware configured by cfg.
+func New(cfg Config) func(http.Handler) http.Handler {
+	cfg.applyDefaults()
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Content-Encoding") == Gzip {
+				if !decompressRequest(w, r, cfg.MaxRequestBodySize) {
+					return
+				}
+			}
+
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			encoding := negotiate(r.Header.Get("Accept-Encoding"), cfg.Encodings)
+			if encoding == "" || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{
+				ResponseWriter: w,
+				cfg:            &cfg,
+				encoding:       encoding,
+			}
+			completed := false
+			defer func() {
+				if !completed {
+					// The handler panicked: leave an unsent response to
+					// the recovery middleware instead of committing it.
+					cw.abort()
+					return
+				}
+				cw.Close()
+			}()
+
+			next.ServeHTTP(cw, r)
+			completed = true
+		})
+	}
+}
+
+// Default returns a compression middleware with default configuration.
+func Default() func(http.Handler) http.Handler {
+	return New(Config{})
+}
+
+// decompressRequest replaces a gzip request body with a decompressing reader.
+// It writes a 400 response and returns false if the body is not valid gzip.
+func decompressRequest(w http.ResponseWriter, r *http.Request, maxSize int64) bool {
+	zr, err := gzip.NewReader(r.Body)
+	if err != nil {
+		c := &context.Context{W: w, R: r}
+		c.JSONResponse(http.StatusBadRequest, nil, aerror.InvalidRequest(err))
+		return false
+	}
+
+	var body io.Reader = zr
+	if maxSize > 0 {
+		body = http.MaxBytesReader(w, io.NopCloser(zr), maxSize)
+	}
+	r.Body = &gzipBody{Reader: body, zr: zr, orig: r.Body}
+	r.Header.Del("Content-Encoding")
+	r.Header.Del("Content-Length")
+	r.ContentLength = -1
+	return true
+}
+
+// gzipBody closes both the gzip reader and the original request body.
+type gzipBody struct {
+	io.Reader
+	zr   *gzip.Reader
+	orig io.ReadCloser
+}
+
+func (b *gzipBody) Close() error {
+	b.zr.Close()
+	return b.orig.Close()
+}
+
+// negotiate picks the best coding from offered for an Accept-Encoding
+// header. Higher client q-values win; ties go to the server's order.
+// It returns "" when the response should not be compressed.
+func negotiate(header string, offered []string) string {
+	if header == "" {
+		return ""
+	}
+
+	accepted := make(map[string]float64)
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		accepted[name] = q
+	}
+
+	best, bestQ := "", 0.0
+	for _, enc := range offered {
+		q, ok := accepted[enc]
+		if !ok {
+			q, ok = accepted["*"]
+		}
+		if ok && q > bestQ {
+			best, bestQ = enc, q
+		}
+	}
+	return best
+}
+
+// compressWriter buffers the start of a response until it can decide whether
+// compressing is worthwhile, then streams through the negotiated encoder.
+type compressWriter struct {
+	http.ResponseWriter
+	cfg      *Config
+	encoding string
+
+	status      int
+	wroteHeader bool
+	decided     bool
+	buf         []byte
+	enc         encoder
+}
+
+// HeaderWritten reports whether the handler has written the status line.
+// It mirrors the router's responseWriter so JSONResponse and the recovery
+// middleware see the handler's view even while the body is still buffered.
+func (cw *compressWriter) HeaderWritten() bool {
+	return cw.wroteHeader
+}
+
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.wroteHeader {
+		return
+	}
+	cw.wroteHeader = true
+	cw.status = code
+
+	// Responses without a body, or already encoded by the handler, pass through.
+	if code < http.StatusOK || code == http.StatusNoContent || code == http.StatusNotModified ||
+		cw.Header().Get("Content-Encoding") != "" {
+		cw.commit(false)
+	}
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.decided {
+		if cw.enc != nil {
+			return cw.enc.Write(b)
+		}
+		return cw.ResponseWriter.Write(b)
+	}
+
+	cw.buf = append(cw.buf, b...)
+	if len(cw.buf) >= cw.cfg.MinLength {
+		if err := cw.decide(); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// Flush sends buffered data immediately, compressing it if the content type
+// allows regardless of MinLength, so streaming responses are not held back.
+func (cw *compressWriter) Flush() {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if !cw.decided {
+		cw.commit(cw.compressible())
+		cw.writeBuffered()
+	}
+	if cw.enc != nil {
+		cw.enc.Flush()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// Close finishes the response: small bodies are written uncompressed and the
+// encoder, if any, is flushed and returned to its pool.
+func (cw *compressWriter) Close() error {
+	if !cw.wroteHeader {
+		return nil
+	}
+	if !cw.decided {
+		cw.commit(len(cw.buf) >= cw.cfg.MinLength && cw.compressible())
+		if err := cw.writeBuffered(); err != nil {
+			return err
+		}
+	}
+	if cw.enc == nil {
+		return nil
+	}
+	err := cw.enc.Close()
+	cw.release()
+	return err
+}
+
+// abort drops whatever the handler has not sent yet, without writing the
+// status line if it is still held back, and releases the encoder.
+func (cw *compressWriter) abort() {
+	cw.buf = nil
+	if cw.enc != nil {
+		cw.release()
+	}
+}
+
+// release returns the encoder to its pool.
+func (cw *compressWriter) release() {
+	cw.enc.Reset(io.Discard)
+	encoderPools[cw.encoding].Put(cw.enc)
+	cw.enc = nil
+}
+
+// decide commits to compressing (subject to content type) once enough of the
+// body has been buffered, then writes the buffer out.
+func (cw *compressWriter) decide() error {
+	cw.commit(cw.compressible())
+	return cw.writeBuffered()
+}
+
+// compressible reports whether the response's content type may be compressed.
+// A missing Content-Type is sniffed from the buffered bytes, as net/http would.
+func (cw *compressWriter) compressible() bool {
+	h := cw.Header()
+	if h.Get("Content-Encoding") != "" || h.Get("Content-Range") != "" {
+		return false
+	}
+	ct := h.Get("Content-Type")
+	if ct == "" {
+		if len(cw.buf) == 0 {
+			return false
+		}
+		ct = http.DetectContentType(cw.buf)
+		h.Set("Content-Type", ct)
+	}
+	mediaType, _, _ := strings.Cut(ct, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	for _, excluded := range cw.cfg.ExcludedContentTypes {
+		if strings.HasSuffix(excluded, "/") {
+			if strings.HasPrefix(mediaType, excluded) {
+				return false
+			}
+		} else if mediaType == excluded {
+			return false
+		}
+	}
+	return true
+}
+
+// commit fixes the compression decision and writes the status line.
+func (cw *compressWriter) commit(compress bool) {
+	if cw.decided {
+		return
+	}
+	cw.decided = true
+
+	if compress {
+		h := cw.Header()
+		h.Set("Content-Encoding", cw.encoding)
+		h.Del("Content-Length")
+		// The compressed bytes differ from the ones a strong validator
+		// names, so it can only be kept as a weak one.
+		if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			h.Set("ETag", "W/"+etag)
+		}
+		cw.enc = encoderPools[cw.encoding].Get().(encoder)
+		cw.enc.Reset(cw.ResponseWriter)
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+}
+
+// writeBuffered writes and releases any bytes held before the decision.
+func (cw *compressWriter) writeBuffered() error {
+	if len(cw.buf) == 0 {
+		return nil
+	}
+	var err error
+	if cw.enc != nil {
+		_, err = cw.enc.Write(cw.buf)
+	} else {
+		_, err = cw.ResponseWriter.Write(cw.buf)
+	}
+	cw.buf = nil
+	return err
+}
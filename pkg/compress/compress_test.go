@@ -0,0 +1,520 @@
+package compress
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/vietpham102301/lightway/pkg/context"
+	"github.com/vietpham102301/lightway/pkg/router"
+)
+
+var largeBody = strings.Repeat("lightway compresses this text nicely. ", 100)
+
+func textHandler(body string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(body))
+	})
+}
+
+func decode(t *testing.T, encoding string, body []byte) string {
+	t.Helper()
+	var r io.Reader
+	switch encoding {
+	case Gzip:
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("gzip reader: %v", err)
+		}
+		r = zr
+	case Deflate:
+		r = flate.NewReader(bytes.NewReader(body))
+	case Brotli:
+		r = brotli.NewReader(bytes.NewReader(body))
+	case Zstd:
+		zr, err := zstd.NewReader(bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("zstd reader: %v", err)
+		}
+		defer zr.Close()
+		r = zr
+	default:
+		return string(body)
+	}
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("decode %s: %v", encoding, err)
+	}
+	return string(out)
+}
+
+func serve(h http.Handler, acceptEncoding string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	return rr
+}
+
+// ===========================================================================
+// Negotiation
+// ===========================================================================
+
+func TestNegotiate(t *testing.T) {
+	offered := []string{Brotli, Zstd, Gzip, Deflate}
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{"identity", ""},
+		{"gzip", Gzip},
+		{"gzip, deflate, br", Brotli},
+		{"gzip;q=1.0, br;q=0.5", Gzip},
+		{"br;q=0, gzip", Gzip},
+		{"*", Brotli},
+		{"*;q=0.5, zstd;q=0", Brotli},
+		{"br;q=0, zstd;q=0, *", Gzip},
+		{"GZIP", Gzip},
+		{"gzip;q=0", ""},
+		{"gzip;q=abc, deflate", Deflate},
+	}
+
+	for _, tt := range tests {
+		if got := negotiate(tt.header, offered); got != tt.want {
+			t.Errorf("negotiate(%q): expected %q, got %q", tt.header, tt.want, got)
+		}
+	}
+}
+
+func TestNew_AllEncodings(t *testing.T) {
+	h := Default()(textHandler(largeBody))
+
+	for _, enc := range []string{Brotli, Zstd, Gzip, Deflate} {
+		rr := serve(h, enc)
+
+		if got := rr.Header().Get("Content-Encoding"); got != enc {
+			t.Errorf("expected Content-Encoding %s, got %q", enc, got)
+			continue
+		}
+		if rr.Body.Len() >= len(largeBody) {
+			t.Errorf("%s: expected compressed body smaller than %d, got %d", enc, len(largeBody), rr.Body.Len())
+		}
+		if got := decode(t, enc, rr.Body.Bytes()); got != largeBody {
+			t.Errorf("%s: decoded body does not match original", enc)
+		}
+	}
+}
+
+func TestNew_EncodingsPreference(t *testing.T) {
+	h := New(Config{Encodings: []string{Gzip}})(textHandler(largeBody))
+
+	rr := serve(h, "br, gzip")
+
+	if got := rr.Header().Get("Content-Encoding"); got != Gzip {
+		t.Errorf("expected gzip, got %q", got)
+	}
+}
+
+// ===========================================================================
+// Skipping
+// ===========================================================================
+
+func TestNew_VaryAlwaysSet(t *testing.T) {
+	h := Default()(textHandler("tiny"))
+
+	for _, ae := range []string{"", "gzip"} {
+		rr := serve(h, ae)
+		if got := rr.Header().Get("Vary"); got != "Accept-Encoding" {
+			t.Errorf("Accept-Encoding %q: expected Vary Accept-Encoding, got %q", ae, got)
+		}
+	}
+}
+
+func TestNew_SmallBodyNotCompressed(t *testing.T) {
+	h := Default()(textHandler("tiny"))
+
+	rr := serve(h, "gzip")
+
+	if got := rr.Header().Get("Content-Encoding"); got != "" {
+		t.Errorf("expected no Content-Encoding, got %q", got)
+	}
+	if rr.Body.String() != "tiny" {
+		t.Errorf("expected body %q, got %q", "tiny", rr.Body.String())
+	}
+}
+
+func TestNew_WeakensStrongETag(t *testing.T) {
+	tests := []struct {
+		name, etag, acceptEncoding, body, want string
+	}{
+		{"compressed strong", `"abc"`, "gzip", largeBody, `W/"abc"`},
+		{"compressed weak", `W/"abc"`, "gzip", largeBody, `W/"abc"`},
+		{"small body", `"abc"`, "gzip", "tiny", `"abc"`},
+		{"identity", `"abc"`, "", largeBody, `"abc"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := Default()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("ETag", tt.etag)
+				textHandler(tt.body).ServeHTTP(w, r)
+			}))
+
+			if got := serve(h, tt.acceptEncoding).Header().Get("ETag"); got != tt.want {
+				t.Errorf("expected ETag %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNew_PanicLeavesResponseToRecoverer(t *testing.T) {
+	r := router.NewRouter()
+	r.Use(router.Recovery(), Default())
+	r.GET("/boom", func(c *context.Context) error {
+		c.W.Header().Set("Content-Type", "text/plain")
+		c.W.Write([]byte("partial"))
+		panic("boom")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/boom", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status 500, got %d", rr.Code)
+	}
+	if strings.Contains(rr.Body.String(), "partial") {
+		t.Errorf("expected the partial body to be dropped, got %q", rr.Body.String())
+	}
+}
+
+func TestNew_NoAcceptEncoding(t *testing.T) {
+	h := Default()(textHandler(largeBody))
+
+	rr := serve(h, "")
+
+	if got := rr.Header().Get("Content-Encoding"); got != "" {
+		t.Errorf("expected no Content-Encoding, got %q", got)
+	}
+	if rr.Body.String() != largeBody {
+		t.Error("expected body to be unchanged")
+	}
+}
+
+func TestNew_ExcludedContentType(t *testing.T) {
+	png := append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 2048)...)
+
+	tests := []struct {
+		name        string
+		contentType string
+	}{
+		{"explicit image", "image/png"},
+		{"explicit zip", "application/zip"},
+		{"sniffed image", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := Default()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.contentType != "" {
+					w.Header().Set("Content-Type", tt.contentType)
+				}
+				w.Write(png)
+			}))
+
+			rr := serve(h, "gzip")
+
+			if got := rr.Header().Get("Content-Encoding"); got != "" {
+				t.Errorf("expected no Content-Encoding, got %q", got)
+			}
+			if !bytes.Equal(rr.Body.Bytes(), png) {
+				t.Error("expected body to be unchanged")
+			}
+		})
+	}
+}
+
+func TestNew_AlreadyEncoded(t *testing.T) {
+	h := Default()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write([]byte(largeBody))
+	}))
+
+	rr := serve(h, "br")
+
+	if got := rr.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Errorf("expected handler's Content-Encoding to be kept, got %q", got)
+	}
+	if rr.Body.String() != largeBody {
+		t.Error("expected body to pass through untouched")
+	}
+}
+
+func TestNew_NoContentAndStatus(t *testing.T) {
+	h := Default()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	rr := serve(h, "gzip")
+
+	if rr.Code != http.StatusNoContent {
+		t.Errorf("expected status 204, got %d", rr.Code)
+	}
+	if got := rr.Header().Get("Content-Encoding"); got != "" {
+		t.Errorf("expected no Content-Encoding, got %q", got)
+	}
+}
+
+func TestNew_StatusPreserved(t *testing.T) {
+	h := Default()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Content-Length", "99999")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(largeBody))
+	}))
+
+	rr := serve(h, "gzip")
+
+	if rr.Code != http.StatusCreated {
+		t.Errorf("expected status 201, got %d", rr.Code)
+	}
+	if got := rr.Header().Get("Content-Length"); got != "" {
+		t.Errorf("expected Content-Length to be removed, got %q", got)
+	}
+	if decode(t, Gzip, rr.Body.Bytes()) != largeBody {
+		t.Error("decoded body does not match original")
+	}
+}
+
+// ===========================================================================
+// Streaming
+// ===========================================================================
+
+func TestNew_FlushStreamsSmallChunks(t *testing.T) {
+	h := Default()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: one\n\n"))
+		w.(http.Flusher).Flush()
+		w.Write([]byte("data: two\n\n"))
+	}))
+
+	rr := serve(h, "gzip")
+
+	if !rr.Flushed {
+		t.Error("expected underlying writer to be flushed")
+	}
+	if got := rr.Header().Get("Content-Encoding"); got != Gzip {
+		t.Errorf("expected streamed response to be compressed, got %q", got)
+	}
+	if got := decode(t, Gzip, rr.Body.Bytes()); got != "data: one\n\ndata: two\n\n" {
+		t.Errorf("unexpected decoded body %q", got)
+	}
+}
+
+func TestNew_FlushDeliversDataImmediately(t *testing.T) {
+	released := make(chan struct{})
+	srv := httptest.NewServer(Default()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("first"))
+		w.(http.Flusher).Flush()
+		<-released
+	})))
+	defer srv.Close()
+	defer close(released)
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	zr, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		t.Fatalf("gzip reader: %v", err)
+	}
+	buf := make([]byte, 5)
+	if _, err := io.ReadFull(zr, buf); err != nil {
+		t.Fatalf("read flushed chunk: %v", err)
+	}
+	if string(buf) != "first" {
+		t.Errorf("expected %q, got %q", "first", buf)
+	}
+}
+
+// ===========================================================================
+// Request decompression
+// ===========================================================================
+
+func gzipBytes(t *testing.T, s string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(s))
+	zw.Close()
+	return buf.Bytes()
+}
+
+func TestNew_DecompressesGzipRequest(t *testing.T) {
+	var got string
+	h := Default()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Encoding") != "" {
+			t.Error("expected Content-Encoding to be removed from request")
+		}
+		b, _ := io.ReadAll(r.Body)
+		got = string(b)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(gzipBytes(t, `{"name":"x"}`)))
+	req.Header.Set("Content-Encoding", "gzip")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	if got != `{"name":"x"}` {
+		t.Errorf("expected decompressed body, got %q", got)
+	}
+}
+
+func TestNew_InvalidGzipRequest(t *testing.T) {
+	called := false
+	h := Default()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("not gzip"))
+	req.Header.Set("Content-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+
+	if called {
+		t.Error("expected handler not to be called")
+	}
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", rr.Code)
+	}
+}
+
+func TestNew_MaxRequestBodySize(t *testing.T) {
+	var readErr error
+	h := New(Config{MaxRequestBodySize: 10})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, readErr = io.ReadAll(r.Body)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(gzipBytes(t, largeBody)))
+	req.Header.Set("Content-Encoding", "gzip")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	if readErr == nil {
+		t.Error("expected error reading past MaxRequestBodySize")
+	}
+}
+
+func TestNew_MaxRequestBodySizeDefault(t *testing.T) {
+	bomb := gzipBytes(t, strings.Repeat("0", DefaultMaxRequestBodySize+1))
+
+	cases := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{"default caps at 10 MiB", Config{}, true},
+		{"negative is unlimited", Config{MaxRequestBodySize: -1}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var n int
+			var readErr error
+			h := New(tc.cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var b []byte
+				b, readErr = io.ReadAll(r.Body)
+				n = len(b)
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(bomb))
+			req.Header.Set("Content-Encoding", "gzip")
+			h.ServeHTTP(httptest.NewRecorder(), req)
+
+			if tc.wantErr && readErr == nil {
+				t.Errorf("expected error reading past %d bytes, read %d", DefaultMaxRequestBodySize, n)
+			}
+			if !tc.wantErr && (readErr != nil || n != DefaultMaxRequestBodySize+1) {
+				t.Errorf("expected the whole body, got %d bytes, err %v", n, readErr)
+			}
+		})
+	}
+}
+
+// ===========================================================================
+// Router integration
+// ===========================================================================
+
+func TestNew_RouterIntegration(t *testing.T) {
+	type payload struct {
+		Name string `json:"name"`
+	}
+
+	r := router.NewRouter()
+	r.Use(Default())
+	r.POST("/echo", func(c *context.Context) error {
+		var p payload
+		if err := c.BindJSON(&p); err != nil {
+			return err
+		}
+		c.JSONResponse(http.StatusOK, strings.Repeat(p.Name, 2000), nil)
+		if flusher, ok := c.W.(http.Flusher); ok {
+			flusher.Flush()
+		}
+		return nil
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/echo", bytes.NewReader(gzipBytes(t, `{"name":"x"}`)))
+	req.Header.Set("Content-Encoding", "gzip")
+	req.Header.Set("Accept-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rr.Code)
+	}
+	if got := rr.Header().Get("Content-Encoding"); got != Gzip {
+		t.Fatalf("expected gzip response, got %q", got)
+	}
+	if got := decode(t, Gzip, rr.Body.Bytes()); !strings.Contains(got, strings.Repeat("x", 2000)) {
+		t.Errorf("unexpected decoded body %q", got)
+	}
+}
+
+func TestNew_HeaderWrittenVisibleToContext(t *testing.T) {
+	r := router.NewRouter()
+	r.Use(Default())
+	r.GET("/twice", func(c *context.Context) error {
+		c.JSONResponse(http.StatusCreated, "first", nil)
+		c.JSONResponse(http.StatusInternalServerError, "second", nil)
+		return nil
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/twice", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Errorf("expected status 201, got %d", rr.Code)
+	}
+	if strings.Contains(decode(t, rr.Header().Get("Content-Encoding"), rr.Body.Bytes()), "second") {
+		t.Error("expected second response to be suppressed")
+	}
+}
@@ -6,26 +6,40 @@ import (
 	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 
+	aerror "github.com/vietpham102301/lightway/pkg/errors"
+	"github.com/vietpham102301/lightway/pkg/i18n"
 	"github.com/vietpham102301/lightway/pkg/logger"
 )
 
 type contextKey string
 
-// UserIDKey are the single source of truth for request context keys.
+// UserIDKey, RequestIDKey, CSPNonceKey and CSRFTokenKey are the single source
+// of truth for request context keys.
 const (
-	UserIDKey contextKey = "user_id"
+	UserIDKey    contextKey = "user_id"
+	RequestIDKey contextKey = "request_id"
+	CSPNonceKey  contextKey = "csp_nonce"
+	CSRFTokenKey contextKey = "csrf_token"
 )
 
+// RequestIDHeader is the header used to propagate request IDs.
+const RequestIDHeader = "X-Request-ID"
+
 type Context struct {
 	W http.ResponseWriter
 	R *http.Request
 }
 
 type AppResponse struct {
-	Code  int    `json:"code"`
-	Data  any    `json:"data"`
-	Error string `json:"error"`
+	Code      int                 `json:"code"`
+	Data      any                 `json:"data"`
+	Error     string              `json:"error"`
+	ErrorCode string              `json:"error_code,omitempty"`
+	Details   map[string]any      `json:"details,omitempty"`
+	Fields    []aerror.FieldError `json:"fields,omitempty"`
+	Errors    []*aerror.AppError  `json:"errors,omitempty"`
 }
 
 func (c *Context) JSONResponse(status int, data any, err error) {
@@ -46,6 +60,7 @@ func (c *Context) JSONResponse(status int, data any, err error) {
 
 	if err != nil {
 		formatedResponse.Error = err.Error()
+		applyAppErrors(c.Context(), &formatedResponse, err)
 	}
 
 	if err := enc.Encode(formatedResponse); err != nil {
@@ -53,6 +68,72 @@ func (c *Context) JSONResponse(status int, data any, err error) {
 	}
 }
 
+// applyAppErrors copies machine-readable fields from the AppErrors in err
+// onto resp. A single AppError fills ErrorCode, Details and Fields directly;
+// several (e.g. from errors.Join) are listed under Errors instead, and Error
+// becomes their messages joined by "; " so non-AppError members never leak.
+// Messages are translated when ctx carries an i18n catalog.
+func applyAppErrors(ctx context.Context, resp *AppResponse, err error) {
+	appErrs := aerror.Collect(err)
+	switch len(appErrs) {
+	case 0:
+	case 1:
+		appErr := i18n.Localize(ctx, appErrs[0])
+		if appErr.Message != appErrs[0].Message {
+			resp.Error = appErr.Message
+		}
+		resp.ErrorCode = appErr.ErrorCode
+		resp.Details = appErr.Details
+		resp.Fields = appErr.Fields
+	default:
+		msgs := make([]string, len(appErrs))
+		for i, e := range appErrs {
+			appErrs[i] = i18n.Localize(ctx, e)
+			msgs[i] = appErrs[i].Message
+		}
+		resp.Error = strings.Join(msgs, "; ")
+		resp.Errors = appErrs
+	}
+}
+
+// Problem is an RFC 7807 problem details object rendered as application/problem+json.
+// Code, Details, Fields and Errors are extension members carrying AppError data.
+type Problem struct {
+	Type     string              `json:"type,omitempty"`
+	Title    string              `json:"title"`
+	Status   int                 `json:"status"`
+	Detail   string              `json:"detail,omitempty"`
+	Instance string              `json:"instance,omitempty"`
+	Code     string              `json:"code,omitempty"`
+	Details  map[string]any      `json:"details,omitempty"`
+	Fields   []aerror.FieldError `json:"fields,omitempty"`
+	Errors   []*aerror.AppError  `json:"errors,omitempty"`
+}
+
+// ProblemResponse writes p as an RFC 7807 application/problem+json response.
+// An empty Type defaults to "about:blank" and an empty Title to the status text.
+func (c *Context) ProblemResponse(p Problem) {
+	if rw, ok := c.W.(interface{ HeaderWritten() bool }); ok && rw.HeaderWritten() {
+		return
+	}
+
+	if p.Type == "" {
+		p.Type = "about:blank"
+	}
+	if p.Title == "" {
+		p.Title = http.StatusText(p.Status)
+	}
+
+	c.W.Header().Set("Content-Type", "application/problem+json")
+	c.W.WriteHeader(p.Status)
+
+	enc := json.NewEncoder(c.W)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(p); err != nil {
+		logger.Error("encoding problem json failed", logger.Err(err))
+	}
+}
+
 // WriteErrorResponse writes a JSON error response with the same format as AppResponse (code, data, error).
 func WriteErrorResponse(w http.ResponseWriter, status int, message string, _ error) {
 	w.Header().Set("Content-Type", "application/json")
@@ -66,8 +147,15 @@ func WriteErrorResponse(w http.ResponseWriter, status int, message string, _ err
 	})
 }
 
+// BindJSON decodes the request body into v. A body cut off by
+// http.MaxBytesReader yields a 413 PayloadTooLarge AppError.
 func (c *Context) BindJSON(v any) error {
-	return json.NewDecoder(c.R.Body).Decode(v)
+	err := json.NewDecoder(c.R.Body).Decode(v)
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return aerror.PayloadTooLarge("Request body too large")
+	}
+	return err
 }
 
 func (c *Context) Param(key string) string {
@@ -121,6 +209,35 @@ func (c *Context) GetUserID() (int, error) {
 	return userID, nil
 }
 
+// GetRequestID returns the request ID stored under RequestIDKey, falling
+// back to the X-Request-ID header. It returns "" if neither is set.
+func (c *Context) GetRequestID() string {
+	return RequestID(c.R)
+}
+
+// RequestID returns the request ID of r from its context or X-Request-ID header.
+func RequestID(r *http.Request) string {
+	if id, ok := r.Context().Value(RequestIDKey).(string); ok && id != "" {
+		return id
+	}
+	return r.Header.Get(RequestIDHeader)
+}
+
+// CSPNonce returns the Content-Security-Policy nonce for this request, as set
+// by the security middleware. It returns "" if no nonce was generated.
+func (c *Context) CSPNonce() string {
+	nonce, _ := c.Context().Value(CSPNonceKey).(string)
+	return nonce
+}
+
+// CSRFToken returns the CSRF token to embed in forms or send back in the
+// X-CSRF-Token header, as set by the CSRF middleware. It returns "" if the
+// middleware did not run.
+func (c *Context) CSRFToken() string {
+	token, _ := c.Context().Value(CSRFTokenKey).(string)
+	return token
+}
+
 func (c *Context) Context() context.Context {
 	return c.R.Context()
 }
@@ -4,10 +4,14 @@ import (
 	"bytes"
 	_context "context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+
+	aerror "github.com/vietpham102301/lightway/pkg/errors"
+	"github.com/vietpham102301/lightway/pkg/i18n"
 )
 
 func newContext(method, target string, body []byte) (*Context, *httptest.ResponseRecorder) {
@@ -70,6 +74,64 @@ func TestJSONResponse_WithError(t *testing.T) {
 	}
 }
 
+func TestJSONResponse_AppErrorFields(t *testing.T) {
+	c, w := newContext("GET", "/", nil)
+
+	appErr := aerror.Conflict("email taken").WithDetail("email", "a@b.c")
+	c.JSONResponse(http.StatusConflict, nil, appErr)
+
+	var resp AppResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if resp.ErrorCode != aerror.CodeConflict {
+		t.Errorf("expected error code %q, got %q", aerror.CodeConflict, resp.ErrorCode)
+	}
+	if resp.Details["email"] != "a@b.c" {
+		t.Errorf("expected details.email 'a@b.c', got %v", resp.Details["email"])
+	}
+}
+
+func TestJSONResponse_JoinedAppErrors(t *testing.T) {
+	c, w := newContext("GET", "/", nil)
+
+	err := errors.Join(aerror.NotFound("no user"), fmt.Errorf("secret"), aerror.Forbidden("no access"))
+	c.JSONResponse(http.StatusForbidden, nil, err)
+
+	var resp AppResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if resp.Error != "no user; no access" {
+		t.Errorf("expected only AppError messages, got %q", resp.Error)
+	}
+	if len(resp.Errors) != 2 {
+		t.Errorf("expected 2 entries in errors, got %d", len(resp.Errors))
+	}
+}
+
+func TestJSONResponse_TranslatesAppError(t *testing.T) {
+	c, w := newContext("GET", "/", nil)
+
+	catalog := i18n.NewCatalog("en")
+	catalog.Add("vi", "USER_NOT_FOUND", "Không tìm thấy người dùng {id}")
+	c.R = c.R.WithContext(i18n.WithCatalog(i18n.WithLocale(c.R.Context(), "vi"), catalog))
+
+	appErr := aerror.NotFound("user not found").WithCode("USER_NOT_FOUND").WithParam("id", 5)
+	c.JSONResponse(http.StatusNotFound, nil, appErr)
+
+	var resp AppResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if resp.Error != "Không tìm thấy người dùng 5" {
+		t.Errorf("expected translated error, got %q", resp.Error)
+	}
+	if appErr.Error() != "user not found" {
+		t.Errorf("expected canonical message to be preserved, got %q", appErr.Error())
+	}
+}
+
 // ===========================================================================
 // WriteErrorResponse
 // ===========================================================================
@@ -98,6 +160,37 @@ func TestWriteErrorResponse(t *testing.T) {
 	}
 }
 
+// ===========================================================================
+// ProblemResponse
+// ===========================================================================
+
+func TestProblemResponse_Defaults(t *testing.T) {
+	c, w := newContext("GET", "/", nil)
+
+	c.ProblemResponse(Problem{Status: http.StatusConflict, Detail: "already exists"})
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected status %d, got %d", http.StatusConflict, w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Errorf("expected Content-Type 'application/problem+json', got %q", ct)
+	}
+
+	var p Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+		t.Fatalf("failed to unmarshal problem: %v", err)
+	}
+	if p.Type != "about:blank" {
+		t.Errorf("expected type 'about:blank', got %q", p.Type)
+	}
+	if p.Title != "Conflict" {
+		t.Errorf("expected title 'Conflict', got %q", p.Title)
+	}
+	if p.Detail != "already exists" {
+		t.Errorf("expected detail 'already exists', got %q", p.Detail)
+	}
+}
+
 // ===========================================================================
 // BindJSON
 // ===========================================================================
@@ -131,6 +224,18 @@ func TestBindJSON_InvalidJSON(t *testing.T) {
 	}
 }
 
+func TestBindJSON_BodyTooLarge(t *testing.T) {
+	c, w := newContext("POST", "/", []byte(`{"name":"a long name"}`))
+	c.R.Body = http.MaxBytesReader(w, c.R.Body, 5)
+
+	var result struct{}
+	err := c.BindJSON(&result)
+	var appErr *aerror.AppError
+	if !errors.As(err, &appErr) || appErr.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413 AppError, got %v", err)
+	}
+}
+
 // ===========================================================================
 // Query Parameters
 // ===========================================================================
@@ -292,3 +397,46 @@ func withUserID(ctx _context.Context, id int) _context.Context {
 func withValue(ctx _context.Context, key contextKey, val any) _context.Context {
 	return _context.WithValue(ctx, key, val)
 }
+
+// ===========================================================================
+// GetRequestID
+// ===========================================================================
+
+func TestGetRequestID(t *testing.T) {
+	c, _ := newContext("GET", "/", nil)
+	if id := c.GetRequestID(); id != "" {
+		t.Errorf("expected empty request id, got %q", id)
+	}
+
+	c.R.Header.Set(RequestIDHeader, "from-header")
+	if id := c.GetRequestID(); id != "from-header" {
+		t.Errorf("expected 'from-header', got %q", id)
+	}
+
+	c.R = c.R.WithContext(withValue(c.R.Context(), RequestIDKey, "from-context"))
+	if id := c.GetRequestID(); id != "from-context" {
+		t.Errorf("expected context value to win, got %q", id)
+	}
+}
+
+// ===========================================================================
+// CSPNonce / CSRFToken
+// ===========================================================================
+
+func TestCSPNonceAndCSRFToken(t *testing.T) {
+	c, _ := newContext("GET", "/", nil)
+	if c.CSPNonce() != "" || c.CSRFToken() != "" {
+		t.Error("expected empty nonce and token without middleware")
+	}
+
+	ctx := withValue(c.R.Context(), CSPNonceKey, "nonce")
+	ctx = withValue(ctx, CSRFTokenKey, "token")
+	c.R = c.R.WithContext(ctx)
+
+	if c.CSPNonce() != "nonce" {
+		t.Errorf("expected nonce, got %q", c.CSPNonce())
+	}
+	if c.CSRFToken() != "token" {
+		t.Errorf("expected token, got %q", c.CSRFToken())
+	}
+}
@@ -2,6 +2,7 @@ package cors
 
 import (
 	"net/http"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -18,18 +19,27 @@ const (
 type Config struct {
 	// AllowedOrigins is a list of origins a cross-domain request can be executed from.
 	// If the special "*" value is present in the list, all origins will be allowed.
+	// Entries may contain "*" wildcards matching one or more DNS labels, e.g.
+	// "https://*.example.com" matches https://api.example.com but not
+	// https://example.com.
 	// Can be a comma-separated string or []string slice.
 	AllowedOrigins []string
 
+	// AllowedOriginPatterns are regular expressions matched against the full
+	// origin, in addition to AllowedOrigins. Anchor them with ^ and $.
+	AllowedOriginPatterns []*regexp.Regexp
+
 	// AllowOriginFunc is a custom function to validate the origin.
-	// If set, AllowedOrigins is ignored.
+	// If set, AllowedOrigins and AllowedOriginPatterns are ignored.
 	AllowOriginFunc func(origin string) bool
 
 	// AllowedMethods is a list of methods the client is allowed to use.
+	// Preflights requesting any other method are rejected.
 	// Default: GET, POST, PUT, DELETE, OPTIONS
 	AllowedMethods []string
 
 	// AllowedHeaders is a list of non-simple headers the client is allowed to use.
+	// Preflights requesting any other header are rejected; "*" allows any header.
 	AllowedHeaders []string
 
 	// ExposedHeaders indicates which headers are safe to expose to the API.
@@ -38,6 +48,11 @@ type Config struct {
 	// AllowCredentials indicates whether the request can include user credentials.
 	AllowCredentials bool
 
+	// AllowPrivateNetwork answers Private Network Access preflights
+	// (Access-Control-Request-Private-Network: true) from allowed origins,
+	// letting public sites reach this server on a private address.
+	AllowPrivateNetwork bool
+
 	// MaxAge indicates how long the results of a preflight request can be cached.
 	MaxAge time.Duration
 }
@@ -53,17 +68,31 @@ func DefaultConfig() Config {
 }
 
 // New creates a new CORS middleware with the provided configuration.
+//
+// Preflight requests (OPTIONS with an Origin) are answered directly: the
+// requested method and headers are checked against AllowedMethods and
+// AllowedHeaders and echoed back, or the preflight is rejected with 403.
+// Actual requests only receive Access-Control-Allow-Origin,
+// Access-Control-Allow-Credentials and Access-Control-Expose-Headers.
+//
+// To give route groups different policies, register a separate middleware
+// on each group with Use; the router runs group middleware for preflights.
 func New(config Config) func(http.Handler) http.Handler {
 	allowedMap := make(map[string]bool)
 	allowAll := false
+	patterns := append([]*regexp.Regexp(nil), config.AllowedOriginPatterns...)
 
 	if config.AllowOriginFunc == nil {
 		for _, origin := range config.AllowedOrigins {
 			trimmed := strings.TrimSpace(origin)
-			if trimmed == "*" {
+			switch {
+			case trimmed == "*":
 				allowAll = true
+			case strings.Contains(trimmed, "*"):
+				patterns = append(patterns, compileGlob(trimmed))
+			default:
+				allowedMap[trimmed] = true
 			}
-			allowedMap[trimmed] = true
 		}
 	}
 
@@ -77,56 +106,110 @@ func New(config Config) func(http.Handler) http.Handler {
 		config.MaxAge = DefaultMaxAge
 	}
 
+	allowedMethods := make(map[string]bool, len(config.AllowedMethods))
+	for _, m := range config.AllowedMethods {
+		allowedMethods[strings.ToUpper(strings.TrimSpace(m))] = true
+	}
+	allowAllHeaders := false
+	allowedHeaders := make(map[string]bool, len(config.AllowedHeaders))
+	for _, h := range config.AllowedHeaders {
+		h = strings.TrimSpace(h)
+		if h == "*" {
+			allowAllHeaders = true
+		}
+		allowedHeaders[http.CanonicalHeaderKey(h)] = true
+	}
+
 	methodsStr := strings.Join(config.AllowedMethods, ", ")
 	headersStr := strings.Join(config.AllowedHeaders, ", ")
 	exposedHeadersStr := strings.Join(config.ExposedHeaders, ", ")
 
+	originAllowed := func(origin string) bool {
+		if config.AllowOriginFunc != nil {
+			return config.AllowOriginFunc(origin)
+		}
+		if origin == "" {
+			return false
+		}
+		if allowAll || allowedMap[origin] {
+			return true
+		}
+		for _, p := range patterns {
+			if p.MatchString(origin) {
+				return true
+			}
+		}
+		return false
+	}
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			origin := r.Header.Get("Origin")
-			w.Header().Set("Vary", "Origin")
-
-			var isAllowed bool
-			if config.AllowOriginFunc != nil {
-				isAllowed = config.AllowOriginFunc(origin)
-			} else {
-				isAllowed = (allowAll && origin != "") || allowedMap[origin]
-			}
+			h := w.Header()
+			h.Add("Vary", "Origin")
 
 			// Handle preflight requests
-			if r.Method == http.MethodOptions {
-				if isAllowed {
-					w.Header().Set("Access-Control-Allow-Origin", origin)
-					w.Header().Set("Access-Control-Allow-Methods", methodsStr)
-					w.Header().Set("Access-Control-Allow-Headers", headersStr)
-					if config.AllowCredentials {
-						w.Header().Set("Access-Control-Allow-Credentials", "true")
-					}
-					if config.MaxAge > 0 {
-						w.Header().Set("Access-Control-Max-Age", formatMaxAge(config.MaxAge))
-					}
-					if exposedHeadersStr != "" {
-						w.Header().Set("Access-Control-Expose-Headers", exposedHeadersStr)
-					}
-					w.WriteHeader(http.StatusNoContent)
+			if r.Method == http.MethodOptions && origin != "" {
+				h.Add("Vary", "Access-Control-Request-Method")
+				h.Add("Vary", "Access-Control-Request-Headers")
+				if config.AllowPrivateNetwork {
+					h.Add("Vary", "Access-Control-Request-Private-Network")
+				}
+
+				if !originAllowed(origin) {
+					logger.Warn("CORS forbidden", "origin", origin)
+					w.WriteHeader(http.StatusForbidden)
 					return
 				}
 
-				logger.Warn("CORS forbidden", "origin", origin)
-				w.WriteHeader(http.StatusForbidden)
+				reqMethod := r.Header.Get("Access-Control-Request-Method")
+				if reqMethod != "" && !allowedMethods[reqMethod] {
+					logger.Warn("CORS preflight method not allowed", "origin", origin, "method", reqMethod)
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+				reqHeaders := parseHeaderList(r.Header.Values("Access-Control-Request-Headers"))
+				if !allowAllHeaders {
+					for _, name := range reqHeaders {
+						if !allowedHeaders[http.CanonicalHeaderKey(name)] {
+							logger.Warn("CORS preflight header not allowed", "origin", origin, "header", name)
+							w.WriteHeader(http.StatusForbidden)
+							return
+						}
+					}
+				}
+
+				h.Set("Access-Control-Allow-Origin", origin)
+				if reqMethod != "" {
+					h.Set("Access-Control-Allow-Methods", reqMethod)
+					if len(reqHeaders) > 0 {
+						h.Set("Access-Control-Allow-Headers", strings.Join(reqHeaders, ", "))
+					}
+				} else {
+					h.Set("Access-Control-Allow-Methods", methodsStr)
+					h.Set("Access-Control-Allow-Headers", headersStr)
+				}
+				if config.AllowCredentials {
+					h.Set("Access-Control-Allow-Credentials", "true")
+				}
+				if config.AllowPrivateNetwork && r.Header.Get("Access-Control-Request-Private-Network") == "true" {
+					h.Set("Access-Control-Allow-Private-Network", "true")
+				}
+				if config.MaxAge > 0 {
+					h.Set("Access-Control-Max-Age", formatMaxAge(config.MaxAge))
+				}
+				w.WriteHeader(http.StatusNoContent)
 				return
 			}
 
 			// Handle actual requests
-			if isAllowed {
-				w.Header().Set("Access-Control-Allow-Origin", origin)
-				w.Header().Set("Access-Control-Allow-Methods", methodsStr)
-				w.Header().Set("Access-Control-Allow-Headers", headersStr)
+			if originAllowed(origin) {
+				h.Set("Access-Control-Allow-Origin", origin)
 				if config.AllowCredentials {
-					w.Header().Set("Access-Control-Allow-Credentials", "true")
+					h.Set("Access-Control-Allow-Credentials", "true")
 				}
 				if exposedHeadersStr != "" {
-					w.Header().Set("Access-Control-Expose-Headers", exposedHeadersStr)
+					h.Set("Access-Control-Expose-Headers", exposedHeadersStr)
 				}
 			}
 
@@ -156,3 +239,26 @@ func Handler(allowedOrigins string) func(http.Handler) http.Handler {
 func formatMaxAge(d time.Duration) string {
 	return strconv.Itoa(int(d.Seconds()))
 }
+
+// compileGlob turns an origin containing "*" wildcards into an anchored,
+// case-insensitive regexp. Each "*" matches one or more DNS labels.
+func compileGlob(pattern string) *regexp.Regexp {
+	parts := strings.Split(pattern, "*")
+	for i, p := range parts {
+		parts[i] = regexp.QuoteMeta(p)
+	}
+	return regexp.MustCompile(`(?i)^` + strings.Join(parts, `[a-z0-9-]+(?:\.[a-z0-9-]+)*`) + `$`)
+}
+
+// parseHeaderList splits comma-separated header names, dropping empty entries.
+func parseHeaderList(values []string) []string {
+	var names []string
+	for _, v := range values {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
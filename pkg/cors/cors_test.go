@@ -3,6 +3,8 @@ package cors
 import (
 	"net/http"
 	"net/http/httptest"
+	"regexp"
+	"strings"
 	"testing"
 	"time"
 )
@@ -157,3 +159,202 @@ func TestDefault(t *testing.T) {
 		}
 	})
 }
+
+func TestOriginPatterns(t *testing.T) {
+	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	config := DefaultConfig()
+	config.AllowedOrigins = []string{"https://*.example.com", "http://localhost:3000"}
+	config.AllowedOriginPatterns = []*regexp.Regexp{regexp.MustCompile(`^https://pr-\d+\.preview\.dev$`)}
+	handler := New(config)(nextHandler)
+
+	tests := []struct {
+		origin  string
+		allowed bool
+	}{
+		{"https://api.example.com", true},
+		{"https://a.b.example.com", true},
+		{"https://API.Example.com", true},
+		{"https://example.com", false},
+		{"http://api.example.com", false},
+		{"https://api.example.com.evil.com", false},
+		{"https://evil.com/.example.com", false},
+		{"http://localhost:3000", true},
+		{"https://pr-42.preview.dev", true},
+		{"https://pr-x.preview.dev", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.origin, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("Origin", tt.origin)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			got := rr.Header().Get("Access-Control-Allow-Origin") == tt.origin
+			if got != tt.allowed {
+				t.Errorf("expected allowed=%v for %s, got %v", tt.allowed, tt.origin, got)
+			}
+		})
+	}
+}
+
+func TestPreflightValidation(t *testing.T) {
+	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("preflight should not reach the handler")
+	})
+
+	config := Config{
+		AllowedOrigins: []string{"https://app.com"},
+		AllowedMethods: []string{"GET", "POST"},
+		AllowedHeaders: []string{"Content-Type", "X-Request-ID"},
+	}
+	handler := New(config)(nextHandler)
+
+	preflight := func(method, headers string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("OPTIONS", "/", nil)
+		req.Header.Set("Origin", "https://app.com")
+		req.Header.Set("Access-Control-Request-Method", method)
+		if headers != "" {
+			req.Header.Set("Access-Control-Request-Headers", headers)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("Allowed method and headers are echoed", func(t *testing.T) {
+		rr := preflight("POST", "content-type, x-request-id")
+
+		if rr.Code != http.StatusNoContent {
+			t.Fatalf("expected 204, got %d", rr.Code)
+		}
+		if got := rr.Header().Get("Access-Control-Allow-Methods"); got != "POST" {
+			t.Errorf("expected Allow-Methods POST, got %q", got)
+		}
+		if got := rr.Header().Get("Access-Control-Allow-Headers"); got != "content-type, x-request-id" {
+			t.Errorf("expected requested headers echoed, got %q", got)
+		}
+	})
+
+	t.Run("Disallowed method", func(t *testing.T) {
+		rr := preflight("DELETE", "")
+
+		if rr.Code != http.StatusForbidden {
+			t.Errorf("expected 403, got %d", rr.Code)
+		}
+		if rr.Header().Get("Access-Control-Allow-Origin") != "" {
+			t.Error("expected no Allow-Origin on rejected preflight")
+		}
+	})
+
+	t.Run("Disallowed header", func(t *testing.T) {
+		rr := preflight("GET", "Content-Type, X-Secret")
+
+		if rr.Code != http.StatusForbidden {
+			t.Errorf("expected 403, got %d", rr.Code)
+		}
+	})
+
+	t.Run("Wildcard headers", func(t *testing.T) {
+		config := config
+		config.AllowedHeaders = []string{"*"}
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest("OPTIONS", "/", nil)
+		req.Header.Set("Origin", "https://app.com")
+		req.Header.Set("Access-Control-Request-Method", "GET")
+		req.Header.Set("Access-Control-Request-Headers", "X-Anything")
+
+		New(config)(nextHandler).ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusNoContent {
+			t.Errorf("expected 204, got %d", rr.Code)
+		}
+		if got := rr.Header().Get("Access-Control-Allow-Headers"); got != "X-Anything" {
+			t.Errorf("expected X-Anything, got %q", got)
+		}
+	})
+
+	t.Run("Vary covers request headers", func(t *testing.T) {
+		rr := preflight("GET", "")
+
+		vary := rr.Header().Values("Vary")
+		want := []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"}
+		if strings.Join(vary, ",") != strings.Join(want, ",") {
+			t.Errorf("expected Vary %v, got %v", want, vary)
+		}
+	})
+}
+
+func TestPrivateNetwork(t *testing.T) {
+	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	newPreflight := func() *http.Request {
+		req := httptest.NewRequest("OPTIONS", "/", nil)
+		req.Header.Set("Origin", "https://public.site")
+		req.Header.Set("Access-Control-Request-Method", "GET")
+		req.Header.Set("Access-Control-Request-Private-Network", "true")
+		return req
+	}
+
+	t.Run("Enabled", func(t *testing.T) {
+		config := DefaultConfig()
+		config.AllowedOrigins = []string{"https://public.site"}
+		config.AllowPrivateNetwork = true
+		rr := httptest.NewRecorder()
+
+		New(config)(nextHandler).ServeHTTP(rr, newPreflight())
+
+		if got := rr.Header().Get("Access-Control-Allow-Private-Network"); got != "true" {
+			t.Errorf("expected Allow-Private-Network true, got %q", got)
+		}
+		if !strings.Contains(strings.Join(rr.Header().Values("Vary"), ","), "Access-Control-Request-Private-Network") {
+			t.Errorf("expected Vary to include Access-Control-Request-Private-Network, got %v", rr.Header().Values("Vary"))
+		}
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		config := DefaultConfig()
+		config.AllowedOrigins = []string{"https://public.site"}
+		rr := httptest.NewRecorder()
+
+		New(config)(nextHandler).ServeHTTP(rr, newPreflight())
+
+		if got := rr.Header().Get("Access-Control-Allow-Private-Network"); got != "" {
+			t.Errorf("expected no Allow-Private-Network, got %q", got)
+		}
+	})
+}
+
+func TestActualRequestHeaders(t *testing.T) {
+	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		w.WriteHeader(http.StatusOK)
+	})
+
+	config := DefaultConfig()
+	config.AllowedOrigins = []string{"https://app.com"}
+	config.ExposedHeaders = []string{"X-Total-Count"}
+	handler := New(config)(nextHandler)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Origin", "https://app.com")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	for _, h := range []string{"Access-Control-Allow-Methods", "Access-Control-Allow-Headers", "Access-Control-Max-Age"} {
+		if got := rr.Header().Get(h); got != "" {
+			t.Errorf("expected no %s on actual response, got %q", h, got)
+		}
+	}
+	if got := rr.Header().Get("Access-Control-Expose-Headers"); got != "X-Total-Count" {
+		t.Errorf("expected Expose-Headers X-Total-Count, got %q", got)
+	}
+	if got := rr.Header().Values("Vary"); len(got) != 2 {
+		t.Errorf("expected Vary to keep both Origin and Accept-Encoding, got %v", got)
+	}
+}
@@ -1,37 +1,217 @@
 package errors
 
-import "net/http"
+import (
+	"net/http"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
 
-type AppError struct {
-	Code    int    `json:"code"`
+// Machine-readable error codes used by the built-in constructors.
+// Clients should branch on these rather than on Message, which is meant for humans.
+const (
+	CodeInvalidRequest      = "INVALID_REQUEST"
+	CodeUnauthorized        = "UNAUTHORIZED"
+	CodeForbidden           = "FORBIDDEN"
+	CodeNotFound            = "NOT_FOUND"
+	CodeConflict            = "CONFLICT"
+	CodePayloadTooLarge     = "PAYLOAD_TOO_LARGE"
+	CodeUnprocessableEntity = "UNPROCESSABLE_ENTITY"
+	CodeTooManyRequests     = "TOO_MANY_REQUESTS"
+	CodeInternal            = "INTERNAL"
+	CodeServiceUnavailable  = "SERVICE_UNAVAILABLE"
+	CodeGatewayTimeout      = "GATEWAY_TIMEOUT"
+)
+
+// captureStack controls whether new AppErrors record the caller stack.
+var captureStack atomic.Bool
+
+// SetStackCapture enables or disables recording the caller stack when an
+// AppError is created. It is disabled by default because every capture
+// costs an allocation.
+func SetStackCapture(enabled bool) {
+	captureStack.Store(enabled)
+}
+
+// FieldError describes a validation failure on a single request field.
+type FieldError struct {
+	Field   string `json:"field"`
 	Message string `json:"message"`
-	Err     error  `json:"-"`
+	Code    string `json:"code,omitempty"`
+}
+
+type AppError struct {
+	Code      int            `json:"code"`
+	Message   string         `json:"message"`
+	ErrorCode string         `json:"error_code,omitempty"`
+	Details   map[string]any `json:"details,omitempty"`
+	Fields    []FieldError   `json:"fields,omitempty"`
+	Params    map[string]any `json:"-"`
+	Err       error          `json:"-"`
+
+	stack []uintptr
 }
 
 func (e *AppError) Error() string {
 	return e.Message
 }
 
+// Unwrap exposes the underlying error so errors.Is and errors.As can see through the AppError.
+func (e *AppError) Unwrap() error {
+	return e.Err
+}
+
+// WithCode sets the machine-readable error code and returns e for chaining.
+func (e *AppError) WithCode(code string) *AppError {
+	e.ErrorCode = code
+	return e
+}
+
+// WithDetail adds a key/value pair to Details and returns e for chaining.
+func (e *AppError) WithDetail(key string, value any) *AppError {
+	if e.Details == nil {
+		e.Details = make(map[string]any)
+	}
+	e.Details[key] = value
+	return e
+}
+
+// WithParam sets a parameter used to interpolate a translated message
+// template (see pkg/i18n) and returns e for chaining.
+func (e *AppError) WithParam(key string, value any) *AppError {
+	if e.Params == nil {
+		e.Params = make(map[string]any)
+	}
+	e.Params[key] = value
+	return e
+}
+
+// WithFields appends field-level validation errors and returns e for chaining.
+func (e *AppError) WithFields(fields ...FieldError) *AppError {
+	e.Fields = append(e.Fields, fields...)
+	return e
+}
+
+// StackTrace returns the stack recorded when the error was created,
+// or an empty string if stack capture was disabled at the time.
+func (e *AppError) StackTrace() string {
+	if len(e.stack) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	frames := runtime.CallersFrames(e.stack)
+	for {
+		frame, more := frames.Next()
+		sb.WriteString(frame.Function)
+		sb.WriteString("\n\t")
+		sb.WriteString(frame.File)
+		sb.WriteByte(':')
+		sb.WriteString(strconv.Itoa(frame.Line))
+		sb.WriteByte('\n')
+		if !more {
+			break
+		}
+	}
+	return sb.String()
+}
+
 func NewAppError(code int, msg string, err error) *AppError {
-	return &AppError{
-		Code:    code,
-		Message: msg,
-		Err:     err,
+	return newAppError(code, "", msg, err)
+}
+
+// newAppError builds an AppError and records the stack of the exported
+// constructor's caller when capture is enabled. It must only be called
+// directly from exported constructors so the skip depth stays correct.
+func newAppError(code int, errorCode, msg string, err error) *AppError {
+	appErr := &AppError{
+		Code:      code,
+		Message:   msg,
+		ErrorCode: errorCode,
+		Err:       err,
 	}
+	if captureStack.Load() {
+		pcs := make([]uintptr, 32)
+		// Skip runtime.Callers, newAppError and the exported constructor.
+		n := runtime.Callers(3, pcs)
+		appErr.stack = pcs[:n]
+	}
+	return appErr
 }
 
 func InvalidRequest(err error) *AppError {
-	return NewAppError(http.StatusBadRequest, "Invalid Request", err)
+	return newAppError(http.StatusBadRequest, CodeInvalidRequest, "Invalid Request", err)
 }
 
 func NotFound(msg string) *AppError {
-	return NewAppError(http.StatusNotFound, msg, nil)
+	return newAppError(http.StatusNotFound, CodeNotFound, msg, nil)
 }
 
 func Unauthorized(msg string) *AppError {
-	return NewAppError(http.StatusUnauthorized, msg, nil)
+	return newAppError(http.StatusUnauthorized, CodeUnauthorized, msg, nil)
+}
+
+func Forbidden(msg string) *AppError {
+	return newAppError(http.StatusForbidden, CodeForbidden, msg, nil)
+}
+
+func Conflict(msg string) *AppError {
+	return newAppError(http.StatusConflict, CodeConflict, msg, nil)
+}
+
+func PayloadTooLarge(msg string) *AppError {
+	return newAppError(http.StatusRequestEntityTooLarge, CodePayloadTooLarge, msg, nil)
+}
+
+// UnprocessableEntity reports a well-formed request that failed validation.
+// Attach per-field failures with WithFields.
+func UnprocessableEntity(msg string, fields ...FieldError) *AppError {
+	appErr := newAppError(http.StatusUnprocessableEntity, CodeUnprocessableEntity, msg, nil)
+	appErr.Fields = fields
+	return appErr
+}
+
+func TooManyRequests(msg string) *AppError {
+	return newAppError(http.StatusTooManyRequests, CodeTooManyRequests, msg, nil)
 }
 
 func InternalServerError() *AppError {
-	return NewAppError(http.StatusInternalServerError, "Internal Server Error", nil)
+	return newAppError(http.StatusInternalServerError, CodeInternal, "Internal Server Error", nil)
+}
+
+func ServiceUnavailable(msg string) *AppError {
+	return newAppError(http.StatusServiceUnavailable, CodeServiceUnavailable, msg, nil)
+}
+
+func GatewayTimeout(msg string) *AppError {
+	return newAppError(http.StatusGatewayTimeout, CodeGatewayTimeout, msg, nil)
+}
+
+// Collect returns every *AppError reachable from err. It descends through
+// both single wrapping (Unwrap() error) and errors.Join (Unwrap() []error),
+// stopping at the first AppError on each branch.
+func Collect(err error) []*AppError {
+	var out []*AppError
+	collect(err, &out)
+	return out
+}
+
+func collect(err error, out *[]*AppError) {
+	for err != nil {
+		if appErr, ok := err.(*AppError); ok {
+			*out = append(*out, appErr)
+			return
+		}
+		switch x := err.(type) {
+		case interface{ Unwrap() []error }:
+			for _, e := range x.Unwrap() {
+				collect(e, out)
+			}
+			return
+		case interface{ Unwrap() error }:
+			err = x.Unwrap()
+		default:
+			return
+		}
+	}
 }
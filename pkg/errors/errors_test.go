@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 	"testing"
 )
 
@@ -102,3 +103,130 @@ func TestInternalServerError(t *testing.T) {
 		t.Errorf("expected message 'Internal Server Error', got %q", appErr.Message)
 	}
 }
+
+func TestAppError_Unwrap(t *testing.T) {
+	sentinel := errors.New("db down")
+	appErr := NewAppError(http.StatusServiceUnavailable, "unavailable", sentinel)
+
+	if !errors.Is(appErr, sentinel) {
+		t.Error("errors.Is should see the wrapped error through AppError")
+	}
+	if errors.Unwrap(appErr) != sentinel {
+		t.Error("Unwrap should return the inner error")
+	}
+}
+
+func TestAppError_ErrorsAs_ThroughAppError(t *testing.T) {
+	inner := NotFound("missing")
+	outer := NewAppError(http.StatusBadGateway, "upstream failed", fmt.Errorf("call: %w", inner))
+
+	var target *AppError
+	if !errors.As(outer.Err, &target) || target != inner {
+		t.Error("errors.As should reach the inner AppError")
+	}
+}
+
+func TestAppError_ErrorCodes(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      *AppError
+		status   int
+		expected string
+	}{
+		{"InvalidRequest", InvalidRequest(nil), http.StatusBadRequest, CodeInvalidRequest},
+		{"Unauthorized", Unauthorized("x"), http.StatusUnauthorized, CodeUnauthorized},
+		{"Forbidden", Forbidden("x"), http.StatusForbidden, CodeForbidden},
+		{"NotFound", NotFound("x"), http.StatusNotFound, CodeNotFound},
+		{"Conflict", Conflict("x"), http.StatusConflict, CodeConflict},
+		{"UnprocessableEntity", UnprocessableEntity("x"), http.StatusUnprocessableEntity, CodeUnprocessableEntity},
+		{"TooManyRequests", TooManyRequests("x"), http.StatusTooManyRequests, CodeTooManyRequests},
+		{"InternalServerError", InternalServerError(), http.StatusInternalServerError, CodeInternal},
+		{"ServiceUnavailable", ServiceUnavailable("x"), http.StatusServiceUnavailable, CodeServiceUnavailable},
+		{"PayloadTooLarge", PayloadTooLarge("x"), http.StatusRequestEntityTooLarge, CodePayloadTooLarge},
+		{"GatewayTimeout", GatewayTimeout("x"), http.StatusGatewayTimeout, CodeGatewayTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.err.Code != tt.status {
+				t.Errorf("expected status %d, got %d", tt.status, tt.err.Code)
+			}
+			if tt.err.ErrorCode != tt.expected {
+				t.Errorf("expected error code %q, got %q", tt.expected, tt.err.ErrorCode)
+			}
+		})
+	}
+}
+
+func TestAppError_Builders(t *testing.T) {
+	appErr := Conflict("email taken").
+		WithCode("EMAIL_TAKEN").
+		WithDetail("email", "a@b.c").
+		WithFields(FieldError{Field: "email", Message: "already registered"})
+
+	if appErr.ErrorCode != "EMAIL_TAKEN" {
+		t.Errorf("expected error code 'EMAIL_TAKEN', got %q", appErr.ErrorCode)
+	}
+	if appErr.Details["email"] != "a@b.c" {
+		t.Errorf("expected detail email 'a@b.c', got %v", appErr.Details["email"])
+	}
+	if len(appErr.Fields) != 1 || appErr.Fields[0].Field != "email" {
+		t.Errorf("expected one field error for 'email', got %+v", appErr.Fields)
+	}
+}
+
+func TestUnprocessableEntity_Fields(t *testing.T) {
+	appErr := UnprocessableEntity("validation failed",
+		FieldError{Field: "name", Message: "required"},
+		FieldError{Field: "age", Message: "must be positive"},
+	)
+	if len(appErr.Fields) != 2 {
+		t.Fatalf("expected 2 field errors, got %d", len(appErr.Fields))
+	}
+}
+
+func TestAppError_StackCapture(t *testing.T) {
+	if NotFound("x").StackTrace() != "" {
+		t.Error("expected no stack when capture is disabled")
+	}
+
+	SetStackCapture(true)
+	defer SetStackCapture(false)
+
+	stack := NotFound("x").StackTrace()
+	if !strings.Contains(stack, "TestAppError_StackCapture") {
+		t.Errorf("expected stack to start at the caller, got:\n%s", stack)
+	}
+	if strings.Contains(stack, "newAppError") {
+		t.Errorf("expected constructor frames to be skipped, got:\n%s", stack)
+	}
+}
+
+func TestCollect(t *testing.T) {
+	a := NotFound("a")
+	b := Conflict("b")
+
+	tests := []struct {
+		name     string
+		err      error
+		expected []*AppError
+	}{
+		{"nil", nil, nil},
+		{"plain", errors.New("x"), nil},
+		{"single", a, []*AppError{a}},
+		{"wrapped", fmt.Errorf("ctx: %w", a), []*AppError{a}},
+		{"joined", errors.Join(a, errors.New("x"), fmt.Errorf("w: %w", b)), []*AppError{a, b}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Collect(tt.err)
+			if len(got) != len(tt.expected) {
+				t.Fatalf("expected %d AppErrors, got %d", len(tt.expected), len(got))
+			}
+			for i := range got {
+				if got[i] != tt.expected[i] {
+					t.Errorf("index %d: expected %v, got %v", i, tt.expected[i], got[i])
+				}
+			}
+		})
+	}
+}
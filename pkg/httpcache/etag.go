@@ -0,0 +1,79 @@
+package httpcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// ETag returns a middleware that adds a strong ETag, derived from the body,
+// to 200 responses for GET and HEAD requests and answers a matching
+// If-None-Match with 304 Not Modified. Handlers may set their own ETag, which
+// is kept. Responses larger than DefaultMaxBodySize, or that the handler
+// flushes, are streamed without an ETag.
+func ETag() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			rec := newRecorder(w, DefaultMaxBodySize)
+			next.ServeHTTP(rec, r)
+			if rec.streaming {
+				return
+			}
+
+			if rec.statusCode() == http.StatusOK {
+				etag := rec.header.Get("ETag")
+				if etag == "" {
+					etag = computeETag(rec.body.Bytes())
+					rec.header.Set("ETag", etag)
+				}
+				if noneMatch(r.Header.Get("If-None-Match"), etag) {
+					writeNotModified(w, rec.header)
+					return
+				}
+			}
+			rec.stream()
+		})
+	}
+}
+
+// computeETag returns a strong entity tag for body.
+func computeETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// noneMatch reports whether an If-None-Match header matches etag, using the
+// weak comparison RFC 9110 requires for If-None-Match.
+func noneMatch(header, etag string) bool {
+	if header == "" || etag == "" {
+		return false
+	}
+	want := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == want {
+			return true
+		}
+	}
+	return false
+}
+
+// notModifiedHeaders are the headers a 304 response must repeat.
+var notModifiedHeaders = []string{"Cache-Control", "Content-Location", "Date", "ETag", "Expires", "Vary"}
+
+// writeNotModified sends a 304 carrying the relevant headers from header.
+func writeNotModified(w http.ResponseWriter, header http.Header) {
+	h := w.Header()
+	for _, k := range notModifiedHeaders {
+		if v := header.Values(k); len(v) > 0 {
+			h[k] = append([]string(nil), v...)
+		}
+	}
+	w.WriteHeader(http.StatusNotModified)
+}
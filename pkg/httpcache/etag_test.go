@@ -0,0 +1,146 @@
+package httpcache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestETag_SetsHeaderAndBody(t *testing.T) {
+	h := ETag()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("hello"))
+	}))
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", rr.Code)
+	}
+	if got := rr.Header().Get("ETag"); got != computeETag([]byte("hello")) {
+		t.Errorf("expected computed ETag, got %q", got)
+	}
+	if rr.Header().Get("Content-Type") != "text/plain" {
+		t.Error("expected handler headers to be copied")
+	}
+	if rr.Body.String() != "hello" {
+		t.Errorf("expected body hello, got %q", rr.Body.String())
+	}
+}
+
+func TestETag_NotModified(t *testing.T) {
+	h := ETag()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Write([]byte("hello"))
+	}))
+	etag := computeETag([]byte("hello"))
+
+	for _, inm := range []string{etag, "W/" + etag, `"other", ` + etag, "*"} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("If-None-Match", inm)
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusNotModified {
+			t.Errorf("If-None-Match %q: expected status 304, got %d", inm, rr.Code)
+		}
+		if rr.Body.Len() != 0 {
+			t.Errorf("If-None-Match %q: expected empty body", inm)
+		}
+		if rr.Header().Get("Cache-Control") != "max-age=60" {
+			t.Errorf("If-None-Match %q: expected Cache-Control on 304", inm)
+		}
+	}
+}
+
+func TestETag_Mismatch(t *testing.T) {
+	h := ETag()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("If-None-Match", `"stale"`)
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", rr.Code)
+	}
+}
+
+func TestETag_KeepsHandlerETag(t *testing.T) {
+	h := ETag()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("hello"))
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("If-None-Match", `"v1"`)
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusNotModified {
+		t.Errorf("expected status 304, got %d", rr.Code)
+	}
+}
+
+func TestETag_SkipsNonGETAndErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		status int
+	}{
+		{"post", http.MethodPost, http.StatusOK},
+		{"error status", http.MethodGet, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := ETag()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte("body"))
+			}))
+
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, httptest.NewRequest(tt.method, "/", nil))
+
+			if rr.Header().Get("ETag") != "" {
+				t.Errorf("expected no ETag, got %q", rr.Header().Get("ETag"))
+			}
+			if rr.Code != tt.status {
+				t.Errorf("expected status %d, got %d", tt.status, rr.Code)
+			}
+		})
+	}
+}
+
+func TestETag_StreamsLargeAndFlushed(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{"large", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(strings.Repeat("x", DefaultMaxBodySize+1)))
+		}},
+		{"flushed", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("chunk"))
+			w.(http.Flusher).Flush()
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			ETag()(tt.handler).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if rr.Header().Get("ETag") != "" {
+				t.Error("expected streamed response without ETag")
+			}
+			if rr.Body.Len() == 0 {
+				t.Error("expected body to be streamed")
+			}
+		})
+	}
+}
@@ -0,0 +1,588 @@
+// Package httpcache provides HTTP response caching: an ETag middleware for
+// conditional GETs, and a server-side cache that stores whole responses in an
+// in-process LRU or in Redis, with tag-based invalidation and request
+// coalescing.
+package httpcache
+
+import (
+	stdctx "context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/vietpham102301/lightway/pkg/context"
+	"github.com/vietpham102301/lightway/pkg/logger"
+)
+
+const (
+	// DefaultTTL is how long public responses without max-age or s-maxage
+	// are kept.
+	DefaultTTL = time.Minute
+
+	// DefaultMaxBodySize is the largest response body that is buffered for
+	// hashing or caching. Larger responses are streamed untouched.
+	DefaultMaxBodySize = 1 << 20
+)
+
+// ErrNoCache is returned by Invalidate when the request context was not
+// populated by a Cache middleware.
+var ErrNoCache = errors.New("httpcache: no cache in context")
+
+// Store holds encoded responses. Implementations must be safe for
+// concurrent use.
+type Store interface {
+	// Get returns the value stored under key. ok is false on a miss.
+	Get(ctx stdctx.Context, key string) (value []byte, ok bool, err error)
+
+	// Set stores value under key for ttl and records it under every tag.
+	Set(ctx stdctx.Context, key string, value []byte, ttl time.Duration, tags []string) error
+
+	// Invalidate removes every entry recorded under any of tags.
+	Invalidate(ctx stdctx.Context, tags ...string) error
+}
+
+// KeyFunc derives the cache key for a request. Returning "" bypasses the
+// cache for that request.
+type KeyFunc func(r *http.Request) string
+
+// DefaultKey keys requests by method, path and the sorted query string.
+func DefaultKey(r *http.Request) string {
+	key := r.Method + " " + r.URL.Path
+	if q := r.URL.Query(); len(q) > 0 {
+		key += "?" + q.Encode()
+	}
+	return key
+}
+
+// Config holds the response cache configuration.
+type Config struct {
+	// Store holds cached responses. Default: NewMemoryStore(DefaultMaxEntries)
+	Store Store
+
+	// TTL applies to responses marked public, or private and cached per
+	// user, that have no max-age or s-maxage. Responses with none of these
+	// are not stored. Default: 1m
+	TTL time.Duration
+
+	// KeyFunc derives the base cache key. Default: DefaultKey
+	KeyFunc KeyFunc
+
+	// VaryByUser adds the user ID from context.UserIDKey to the key, so
+	// authenticated and private responses are cached per user. The auth
+	// middleware must run before the cache. Requests carrying an
+	// Authorization or Cookie header bypass the cache unless a user ID was
+	// found.
+	VaryByUser bool
+
+	// VaryHeaders are request headers whose values are added to the key.
+	// Responses that Vary on any other header are not stored.
+	VaryHeaders []string
+
+	// Prefix namespaces keys and tags in the store. Default: "hc"
+	Prefix string
+
+	// MaxBodySize is the largest body that is cached. Default: 1 MiB
+	MaxBodySize int
+}
+
+func (c *Config) applyDefaults() {
+	if c.Store == nil {
+		c.Store = NewMemoryStore(DefaultMaxEntries)
+	}
+	if c.TTL <= 0 {
+		c.TTL = DefaultTTL
+	}
+	if c.KeyFunc == nil {
+		c.KeyFunc = DefaultKey
+	}
+	if c.Prefix == "" {
+		c.Prefix = "hc"
+	}
+	if c.MaxBodySize <= 0 {
+		c.MaxBodySize = DefaultMaxBodySize
+	}
+	vary := make([]string, len(c.VaryHeaders))
+	for i, h := range c.VaryHeaders {
+		vary[i] = http.CanonicalHeaderKey(h)
+	}
+	c.VaryHeaders = vary
+}
+
+// Snapshot is a point-in-time view of cache counters.
+type Snapshot struct {
+	Hits      int64 // served from the store
+	Misses    int64 // handler ran and the response was considered for storing
+	Coalesced int64 // served from a concurrent identical request
+	Bypasses  int64 // cache skipped because of the request
+}
+
+type stats struct {
+	hits      atomic.Int64
+	misses    atomic.Int64
+	coalesced atomic.Int64
+	bypasses  atomic.Int64
+}
+
+// Cache is a server-side response cache for GET and HEAD requests.
+type Cache struct {
+	cfg     Config
+	flights flightGroup
+	stats   stats
+	inval   invalidations
+}
+
+// NewCache creates a Cache configured by cfg.
+func NewCache(cfg Config) *Cache {
+	cfg.applyDefaults()
+	return &Cache{cfg: cfg}
+}
+
+// Stats returns the current cache counters.
+func (c *Cache) Stats() Snapshot {
+	return Snapshot{
+		Hits:      c.stats.hits.Load(),
+		Misses:    c.stats.misses.Load(),
+		Coalesced: c.stats.coalesced.Load(),
+		Bypasses:  c.stats.bypasses.Load(),
+	}
+}
+
+// Invalidate removes every cached response tagged with any of tags.
+// Responses being rendered while it runs are not stored if they carry any
+// of tags.
+func (c *Cache) Invalidate(ctx stdctx.Context, tags ...string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	c.inval.store.Lock()
+	defer c.inval.store.Unlock()
+	c.inval.record(tags)
+	return c.cfg.Store.Invalidate(ctx, c.tagKeys(tags)...)
+}
+
+// invalidations lets a fill tell whether any of its tags was invalidated
+// while its handler ran, so a response rendered before an Invalidate is not
+// stored after it. Only invalidations made during a fill are remembered.
+type invalidations struct {
+	// store orders each fill's check and Store.Set against
+	// Store.Invalidate: fills hold it shared, Invalidate exclusively.
+	store sync.RWMutex
+
+	mu     sync.Mutex
+	gen    uint64
+	fills  int
+	tagGen map[string]uint64 // tag → gen of its last invalidation
+}
+
+// begin registers a fill and returns the generation it started at.
+func (v *invalidations) begin() uint64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.fills++
+	return v.gen
+}
+
+// end unregisters a fill, forgetting invalidations once none is running.
+func (v *invalidations) end() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.fills--
+	if v.fills == 0 {
+		v.tagGen = nil
+	}
+}
+
+func (v *invalidations) record(tags []string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.gen++
+	if v.fills == 0 {
+		return
+	}
+	if v.tagGen == nil {
+		v.tagGen = make(map[string]uint64)
+	}
+	for _, t := range tags {
+		v.tagGen[t] = v.gen
+	}
+}
+
+// since reports whether any of tags was invalidated after generation gen.
+func (v *invalidations) since(gen uint64, tags []string) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for _, t := range tags {
+		if v.tagGen[t] > gen {
+			return true
+		}
+	}
+	return false
+}
+
+// entry is a cached response.
+type entry struct {
+	Status   int         `json:"status"`
+	Header   http.Header `json:"header"`
+	Body     []byte      `json:"body"`
+	StoredAt time.Time   `json:"stored_at"`
+
+	// shareable reports whether coalesced requests may reuse the entry.
+	shareable bool
+}
+
+// Middleware returns the caching middleware. Every request gets the Cache in
+// its context, so handlers on any method can call Invalidate; only GET and
+// HEAD responses are cached. Responses carry X-Cache: HIT, MISS or BYPASS.
+func (c *Cache) Middleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r = r.WithContext(stdctx.WithValue(r.Context(), cacheKey, c))
+
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			reqCC := parseCacheControl(r.Header.Get("Cache-Control"))
+			key := c.key(r)
+			if key == "" || reqCC.has("no-store") {
+				c.stats.bypasses.Add(1)
+				w.Header().Set("X-Cache", "BYPASS")
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !reqCC.has("no-cache") && reqCC["max-age"] != "0" {
+				if e := c.lookup(r.Context(), key); e != nil {
+					c.stats.hits.Add(1)
+					serve(w, r, e, "HIT")
+					return
+				}
+			}
+
+			leader := false
+			e, ok := c.flights.do(r.Context(), key, func() *entry {
+				leader = true
+				return c.fill(w, r, key, next)
+			})
+			switch {
+			case leader:
+				if e != nil {
+					serve(w, r, e, "MISS")
+				}
+			case ok && e != nil && e.shareable:
+				c.stats.coalesced.Add(1)
+				serve(w, r, e, "HIT")
+			case ok:
+				// The leader's response cannot be shared; run our own.
+				w.Header().Set("X-Cache", "MISS")
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+// key builds the full store key for r, or "" to bypass the cache.
+func (c *Cache) key(r *http.Request) string {
+	base := c.cfg.KeyFunc(r)
+	if base == "" {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString(c.cfg.Prefix)
+	sb.WriteString(":")
+	sb.WriteString(base)
+
+	if id, ok := c.userID(r); ok {
+		sb.WriteString("|user:")
+		sb.WriteString(strconv.Itoa(id))
+	} else if r.Header.Get("Authorization") != "" || r.Header.Get("Cookie") != "" {
+		// Credentials may personalise the response; don't share it.
+		return ""
+	}
+
+	for _, h := range c.cfg.VaryHeaders {
+		sb.WriteString("|")
+		sb.WriteString(h)
+		sb.WriteString("=")
+		sb.WriteString(url.QueryEscape(strings.Join(r.Header.Values(h), ",")))
+	}
+	return sb.String()
+}
+
+// userID returns the user the key is scoped to when VaryByUser is set.
+func (c *Cache) userID(r *http.Request) (int, bool) {
+	if !c.cfg.VaryByUser {
+		return 0, false
+	}
+	id, ok := r.Context().Value(context.UserIDKey).(int)
+	return id, ok
+}
+
+func (c *Cache) tagKeys(tags []string) []string {
+	keys := make([]string, len(tags))
+	for i, t := range tags {
+		keys[i] = c.cfg.Prefix + ":tag:" + t
+	}
+	return keys
+}
+
+// lookup returns the stored entry for key, or nil on a miss or store error.
+func (c *Cache) lookup(ctx stdctx.Context, key string) *entry {
+	data, ok, err := c.cfg.Store.Get(ctx, key)
+	if err != nil {
+		logger.Error("httpcache: store get failed", "key", key, "err", err)
+		return nil
+	}
+	if !ok {
+		return nil
+	}
+	var e entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		logger.Error("httpcache: corrupt entry", "key", key, "err", err)
+		return nil
+	}
+	e.shareable = true
+	return &e
+}
+
+// fill runs the handler, stores the response when allowed and returns it.
+// It returns nil if the response was streamed straight to w.
+func (c *Cache) fill(w http.ResponseWriter, r *http.Request, key string, next http.Handler) *entry {
+	c.stats.misses.Add(1)
+	gen := c.inval.begin()
+	defer c.inval.end()
+
+	tags := &tagSet{}
+	r = r.WithContext(stdctx.WithValue(r.Context(), tagsKey, tags))
+
+	rec := newRecorder(w, c.cfg.MaxBodySize)
+	next.ServeHTTP(rec, r)
+	if rec.streaming {
+		return nil
+	}
+
+	e := &entry{
+		Status:   rec.statusCode(),
+		Header:   rec.header,
+		Body:     rec.body.Bytes(),
+		StoredAt: time.Now(),
+	}
+	if e.Status == http.StatusOK && e.Header.Get("ETag") == "" {
+		e.Header.Set("ETag", computeETag(e.Body))
+	}
+
+	_, perUser := c.userID(r)
+	ttl, ok := c.storable(e, perUser)
+	if !ok {
+		return e
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		logger.Error("httpcache: encode entry", "key", key, "err", err)
+		e.shareable = true
+		return e
+	}
+
+	c.inval.store.RLock()
+	defer c.inval.store.RUnlock()
+	tagList := tags.list()
+	if c.inval.since(gen, tagList) {
+		// Invalidated while rendering: this response is already stale.
+		return e
+	}
+	e.shareable = true
+	if err := c.cfg.Store.Set(r.Context(), key, data, ttl, c.tagKeys(tagList)); err != nil {
+		logger.Error("httpcache: store set failed", "key", key, "err", err)
+	}
+	return e
+}
+
+// storable reports whether e may be cached and for how long, following the
+// response's Cache-Control, Set-Cookie and Vary headers. perUser reports
+// whether the entry is keyed by user.
+func (c *Cache) storable(e *entry, perUser bool) (time.Duration, bool) {
+	if e.Status != http.StatusOK || e.Header.Get("Set-Cookie") != "" {
+		return 0, false
+	}
+
+	cc := parseCacheControl(e.Header.Get("Cache-Control"))
+	if cc.has("no-store") || cc.has("no-cache") {
+		return 0, false
+	}
+	if cc.has("private") && !perUser {
+		return 0, false
+	}
+
+	for _, v := range e.Header.Values("Vary") {
+		for _, h := range strings.Split(v, ",") {
+			h = http.CanonicalHeaderKey(strings.TrimSpace(h))
+			if h == "*" || (h != "" && !c.variesOn(h)) {
+				return 0, false
+			}
+		}
+	}
+
+	for _, directive := range []string{"s-maxage", "max-age"} {
+		if v, ok := cc[directive]; ok {
+			secs, err := strconv.Atoi(v)
+			if err != nil {
+				return 0, false
+			}
+			ttl := time.Duration(secs) * time.Second
+			return ttl, ttl > 0
+		}
+	}
+	// Without an explicit lifetime, only responses marked cacheable get TTL.
+	if cc.has("public") || cc.has("private") {
+		return c.cfg.TTL, true
+	}
+	return 0, false
+}
+
+func (c *Cache) variesOn(header string) bool {
+	for _, h := range c.cfg.VaryHeaders {
+		if h == header {
+			return true
+		}
+	}
+	return false
+}
+
+// serve writes e to w, answering If-None-Match with 304 when it matches.
+func serve(w http.ResponseWriter, r *http.Request, e *entry, status string) {
+	h := w.Header()
+	copyHeader(h, e.Header)
+	h.Set("X-Cache", status)
+	if status == "HIT" {
+		h.Set("Age", strconv.Itoa(int(time.Since(e.StoredAt).Seconds())))
+	}
+
+	if e.Status == http.StatusOK && noneMatch(r.Header.Get("If-None-Match"), e.Header.Get("ETag")) {
+		writeNotModified(w, e.Header)
+		return
+	}
+
+	w.WriteHeader(e.Status)
+	if r.Method != http.MethodHead {
+		w.Write(e.Body)
+	}
+}
+
+// cacheControl holds parsed Cache-Control directives, keyed in lower case.
+type cacheControl map[string]string
+
+func parseCacheControl(header string) cacheControl {
+	cc := cacheControl{}
+	for _, part := range strings.Split(header, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		cc[name] = strings.Trim(strings.TrimSpace(value), `"`)
+	}
+	return cc
+}
+
+func (cc cacheControl) has(directive string) bool {
+	_, ok := cc[directive]
+	return ok
+}
+
+type ctxKey int
+
+const (
+	cacheKey ctxKey = iota
+	tagsKey
+)
+
+// tagSet collects the tags a handler attaches to its response.
+type tagSet struct {
+	mu   sync.Mutex
+	tags []string
+}
+
+func (t *tagSet) add(tags ...string) {
+	t.mu.Lock()
+	t.tags = append(t.tags, tags...)
+	t.mu.Unlock()
+}
+
+func (t *tagSet) list() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]string(nil), t.tags...)
+}
+
+// Tag attaches tags to the response being cached for the current request,
+// so a later Invalidate with any of them evicts it. It is a no-op outside a
+// cached request.
+func Tag(ctx stdctx.Context, tags ...string) {
+	if t, ok := ctx.Value(tagsKey).(*tagSet); ok {
+		t.add(tags...)
+	}
+}
+
+// Invalidate evicts every response tagged with any of tags, using the Cache
+// whose middleware handled the current request. It returns ErrNoCache if no
+// Cache middleware ran.
+func Invalidate(ctx stdctx.Context, tags ...string) error {
+	c, ok := ctx.Value(cacheKey).(*Cache)
+	if !ok {
+		return ErrNoCache
+	}
+	return c.Invalidate(ctx, tags...)
+}
+
+// flightGroup coalesces concurrent misses for the same key, so only one
+// request runs the handler while the others wait for its response.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	done chan struct{}
+	val  *entry
+}
+
+// do runs fn once per key at a time. Callers that arrive while fn is running
+// wait for its result; ok is false if ctx ended first. If fn panics, waiters
+// receive nil and the panic propagates in the caller that ran fn.
+func (g *flightGroup) do(ctx stdctx.Context, key string, fn func() *entry) (e *entry, ok bool) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+	if call, exists := g.calls[key]; exists {
+		g.mu.Unlock()
+		select {
+		case <-call.done:
+			return call.val, true
+		case <-ctx.Done():
+			return nil, false
+		}
+	}
+	call := &flightCall{done: make(chan struct{})}
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(call.done)
+	}()
+
+	call.val = fn()
+	return call.val, true
+}
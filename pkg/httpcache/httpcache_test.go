@@ -0,0 +1,471 @@
+package httpcache
+
+import (
+	stdctx "context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/vietpham102301/lightway/pkg/context"
+	"github.com/vietpham102301/lightway/pkg/router"
+)
+
+// countingHandler writes "n=<call count>" so tests can tell fresh responses
+// from cached ones. Responses are public unless configure says otherwise.
+func countingHandler(calls *atomic.Int64, configure func(w http.ResponseWriter)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Cache-Control", "public")
+		if configure != nil {
+			configure(w)
+		}
+		w.Write([]byte("n=" + strconv.FormatInt(n, 10)))
+	})
+}
+
+func get(h http.Handler, target string, headers ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	return rr
+}
+
+// ===========================================================================
+// Basic caching
+// ===========================================================================
+
+func TestCache_MissThenHit(t *testing.T) {
+	var calls atomic.Int64
+	c := NewCache(Config{})
+	h := c.Middleware()(countingHandler(&calls, nil))
+
+	first := get(h, "/items")
+	second := get(h, "/items")
+
+	if first.Header().Get("X-Cache") != "MISS" {
+		t.Errorf("expected first X-Cache MISS, got %q", first.Header().Get("X-Cache"))
+	}
+	if second.Header().Get("X-Cache") != "HIT" {
+		t.Errorf("expected second X-Cache HIT, got %q", second.Header().Get("X-Cache"))
+	}
+	if second.Body.String() != "n=1" {
+		t.Errorf("expected cached body n=1, got %q", second.Body.String())
+	}
+	if second.Header().Get("Content-Type") != "text/plain" {
+		t.Error("expected cached headers to be replayed")
+	}
+	if second.Header().Get("Age") == "" {
+		t.Error("expected Age header on hit")
+	}
+	if first.Header().Get("ETag") == "" || first.Header().Get("ETag") != second.Header().Get("ETag") {
+		t.Error("expected a stable ETag across miss and hit")
+	}
+	if calls.Load() != 1 {
+		t.Errorf("expected handler to run once, ran %d times", calls.Load())
+	}
+
+	s := c.Stats()
+	if s.Hits != 1 || s.Misses != 1 {
+		t.Errorf("expected 1 hit and 1 miss, got %+v", s)
+	}
+}
+
+func TestCache_NotModifiedFromCache(t *testing.T) {
+	var calls atomic.Int64
+	h := NewCache(Config{}).Middleware()(countingHandler(&calls, nil))
+
+	etag := get(h, "/items").Header().Get("ETag")
+	rr := get(h, "/items", "If-None-Match", etag)
+
+	if rr.Code != http.StatusNotModified {
+		t.Errorf("expected status 304, got %d", rr.Code)
+	}
+	if rr.Body.Len() != 0 {
+		t.Error("expected empty body on 304")
+	}
+}
+
+func TestCache_KeyIncludesSortedQuery(t *testing.T) {
+	var calls atomic.Int64
+	h := NewCache(Config{}).Middleware()(countingHandler(&calls, nil))
+
+	get(h, "/items?b=2&a=1")
+	if rr := get(h, "/items?a=1&b=2"); rr.Header().Get("X-Cache") != "HIT" {
+		t.Error("expected query parameter order not to matter")
+	}
+	if rr := get(h, "/items?a=2"); rr.Header().Get("X-Cache") != "MISS" {
+		t.Error("expected different query to miss")
+	}
+}
+
+func TestCache_HEADServedFromCache(t *testing.T) {
+	var calls atomic.Int64
+	h := NewCache(Config{}).Middleware()(countingHandler(&calls, nil))
+
+	get(h, "/items")
+	req := httptest.NewRequest(http.MethodHead, "/items", nil)
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+
+	if rr.Body.Len() != 0 {
+		t.Errorf("expected no body for HEAD, got %q", rr.Body.String())
+	}
+}
+
+// ===========================================================================
+// Cache-Control
+// ===========================================================================
+
+func TestCache_RequestCacheControl(t *testing.T) {
+	var calls atomic.Int64
+	h := NewCache(Config{}).Middleware()(countingHandler(&calls, nil))
+	get(h, "/items")
+
+	if rr := get(h, "/items", "Cache-Control", "no-cache"); rr.Body.String() != "n=2" {
+		t.Errorf("expected no-cache to revalidate, got %q", rr.Body.String())
+	}
+	if rr := get(h, "/items"); rr.Body.String() != "n=2" {
+		t.Errorf("expected refreshed entry to be stored, got %q", rr.Body.String())
+	}
+	if rr := get(h, "/items", "Cache-Control", "no-store"); rr.Header().Get("X-Cache") != "BYPASS" {
+		t.Errorf("expected no-store to bypass, got %q", rr.Header().Get("X-Cache"))
+	}
+}
+
+func TestCache_ResponseNotStored(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(w http.ResponseWriter)
+	}{
+		{"no-store", func(w http.ResponseWriter) { w.Header().Set("Cache-Control", "no-store") }},
+		{"no-cache", func(w http.ResponseWriter) { w.Header().Set("Cache-Control", "no-cache") }},
+		{"private", func(w http.ResponseWriter) { w.Header().Set("Cache-Control", "private, max-age=60") }},
+		{"max-age zero", func(w http.ResponseWriter) { w.Header().Set("Cache-Control", "max-age=0") }},
+		{"set-cookie", func(w http.ResponseWriter) { w.Header().Set("Set-Cookie", "a=b") }},
+		{"vary star", func(w http.ResponseWriter) { w.Header().Set("Vary", "*") }},
+		{"vary unknown header", func(w http.ResponseWriter) { w.Header().Set("Vary", "Accept-Language") }},
+		{"error status", func(w http.ResponseWriter) { w.WriteHeader(http.StatusInternalServerError) }},
+		{"no cache-control", func(w http.ResponseWriter) { w.Header().Del("Cache-Control") }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int64
+			h := NewCache(Config{}).Middleware()(countingHandler(&calls, tt.configure))
+
+			get(h, "/items")
+			get(h, "/items")
+
+			if calls.Load() != 2 {
+				t.Errorf("expected response not to be cached, handler ran %d times", calls.Load())
+			}
+		})
+	}
+}
+
+func TestCache_MaxAgeOverridesTTL(t *testing.T) {
+	store := NewMemoryStore(10)
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	var calls atomic.Int64
+	h := NewCache(Config{Store: store, TTL: time.Hour}).Middleware()(countingHandler(&calls, func(w http.ResponseWriter) {
+		w.Header().Set("Cache-Control", "public, max-age=60, s-maxage=5")
+	}))
+
+	get(h, "/items")
+	now = now.Add(6 * time.Second)
+	get(h, "/items")
+
+	if calls.Load() != 2 {
+		t.Errorf("expected s-maxage to expire the entry, handler ran %d times", calls.Load())
+	}
+}
+
+// ===========================================================================
+// Keys
+// ===========================================================================
+
+func TestCache_AuthorizationBypassesSharedCache(t *testing.T) {
+	var calls atomic.Int64
+	h := NewCache(Config{}).Middleware()(countingHandler(&calls, nil))
+
+	rr := get(h, "/me", "Authorization", "Bearer x")
+
+	if rr.Header().Get("X-Cache") != "BYPASS" {
+		t.Errorf("expected BYPASS, got %q", rr.Header().Get("X-Cache"))
+	}
+}
+
+func TestCache_CookieBypassesSharedCache(t *testing.T) {
+	var calls atomic.Int64
+	h := NewCache(Config{VaryByUser: true}).Middleware()(countingHandler(&calls, nil))
+
+	get(h, "/me", "Cookie", "session=a")
+	rr := get(h, "/me", "Cookie", "session=b")
+
+	if rr.Header().Get("X-Cache") != "BYPASS" || rr.Body.String() != "n=2" {
+		t.Errorf("expected BYPASS without a resolved user, got %q %q", rr.Header().Get("X-Cache"), rr.Body.String())
+	}
+}
+
+func TestCache_PrivateNeedsResolvedUser(t *testing.T) {
+	var calls atomic.Int64
+	h := NewCache(Config{VaryByUser: true}).Middleware()(countingHandler(&calls, func(w http.ResponseWriter) {
+		w.Header().Set("Cache-Control", "private")
+	}))
+
+	get(h, "/me")
+	get(h, "/me")
+
+	if calls.Load() != 2 {
+		t.Errorf("expected a private response without a user not to be shared, handler ran %d times", calls.Load())
+	}
+}
+
+func TestCache_VaryByUser(t *testing.T) {
+	var calls atomic.Int64
+	withUser := func(id int, next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := stdctx.WithValue(r.Context(), context.UserIDKey, id)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+	cached := NewCache(Config{VaryByUser: true}).Middleware()(countingHandler(&calls, func(w http.ResponseWriter) {
+		w.Header().Set("Cache-Control", "private")
+	}))
+
+	get(withUser(1, cached), "/me", "Authorization", "Bearer a")
+	get(withUser(2, cached), "/me", "Authorization", "Bearer b")
+	rr := get(withUser(1, cached), "/me", "Cookie", "session=a")
+
+	if rr.Header().Get("X-Cache") != "HIT" || rr.Body.String() != "n=1" {
+		t.Errorf("expected user 1 to hit own entry, got %q %q", rr.Header().Get("X-Cache"), rr.Body.String())
+	}
+	if calls.Load() != 2 {
+		t.Errorf("expected one handler run per user, got %d", calls.Load())
+	}
+}
+
+func TestCache_VaryHeaders(t *testing.T) {
+	var calls atomic.Int64
+	h := NewCache(Config{VaryHeaders: []string{"accept-language"}}).Middleware()(countingHandler(&calls, func(w http.ResponseWriter) {
+		w.Header().Set("Vary", "Accept-Language")
+	}))
+
+	get(h, "/items", "Accept-Language", "en")
+	get(h, "/items", "Accept-Language", "vi")
+	rr := get(h, "/items", "Accept-Language", "en")
+
+	if rr.Header().Get("X-Cache") != "HIT" || rr.Body.String() != "n=1" {
+		t.Errorf("expected en to hit its own entry, got %q %q", rr.Header().Get("X-Cache"), rr.Body.String())
+	}
+}
+
+func TestCache_KeyFuncSkip(t *testing.T) {
+	var calls atomic.Int64
+	h := NewCache(Config{KeyFunc: func(r *http.Request) string { return "" }}).Middleware()(countingHandler(&calls, nil))
+
+	if rr := get(h, "/items"); rr.Header().Get("X-Cache") != "BYPASS" {
+		t.Errorf("expected BYPASS, got %q", rr.Header().Get("X-Cache"))
+	}
+}
+
+// ===========================================================================
+// Tags
+// ===========================================================================
+
+func TestCache_TagInvalidationFromHandler(t *testing.T) {
+	var calls atomic.Int64
+	c := NewCache(Config{})
+
+	r := router.NewRouter()
+	r.Use(c.Middleware())
+	r.GET("/users/{id}", func(ctx *context.Context) error {
+		n := calls.Add(1)
+		Tag(ctx.Context(), "users", "user:"+ctx.Param("id"))
+		ctx.W.Header().Set("Cache-Control", "public")
+		ctx.JSONResponse(http.StatusOK, n, nil)
+		return nil
+	})
+	r.PUT("/users/{id}", func(ctx *context.Context) error {
+		if err := Invalidate(ctx.Context(), "user:"+ctx.Param("id")); err != nil {
+			return err
+		}
+		ctx.Status(http.StatusNoContent)
+		return nil
+	})
+
+	get(r, "/users/1")
+	get(r, "/users/2")
+
+	put := httptest.NewRequest(http.MethodPut, "/users/1", nil)
+	r.ServeHTTP(httptest.NewRecorder(), put)
+
+	if rr := get(r, "/users/1"); rr.Header().Get("X-Cache") != "MISS" {
+		t.Errorf("expected invalidated entry to miss, got %q", rr.Header().Get("X-Cache"))
+	}
+	if rr := get(r, "/users/2"); rr.Header().Get("X-Cache") != "HIT" {
+		t.Errorf("expected other entry to remain cached, got %q", rr.Header().Get("X-Cache"))
+	}
+
+	if err := c.Invalidate(stdctx.Background(), "users"); err != nil {
+		t.Fatalf("Invalidate: %v", err)
+	}
+	if rr := get(r, "/users/2"); rr.Header().Get("X-Cache") != "MISS" {
+		t.Errorf("expected shared tag to invalidate, got %q", rr.Header().Get("X-Cache"))
+	}
+}
+
+func TestCache_InvalidateDuringFillNotStored(t *testing.T) {
+	var calls atomic.Int64
+	rendered := make(chan struct{})
+	release := make(chan struct{})
+	c := NewCache(Config{})
+	h := c.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		Tag(r.Context(), "users")
+		w.Header().Set("Cache-Control", "public")
+		if n == 1 {
+			close(rendered)
+			<-release
+		}
+		w.Write([]byte("n=" + strconv.FormatInt(n, 10)))
+	}))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		get(h, "/users")
+	}()
+	<-rendered
+	if err := c.Invalidate(stdctx.Background(), "users"); err != nil {
+		t.Fatalf("Invalidate: %v", err)
+	}
+	close(release)
+	<-done
+
+	if rr := get(h, "/users"); rr.Header().Get("X-Cache") != "MISS" || rr.Body.String() != "n=2" {
+		t.Errorf("expected the stale response not to be stored, got %q %q", rr.Header().Get("X-Cache"), rr.Body.String())
+	}
+	if rr := get(h, "/users"); rr.Header().Get("X-Cache") != "HIT" {
+		t.Errorf("expected later fills to be stored, got %q", rr.Header().Get("X-Cache"))
+	}
+}
+
+func TestInvalidate_NoCache(t *testing.T) {
+	if err := Invalidate(stdctx.Background(), "x"); err != ErrNoCache {
+		t.Errorf("expected ErrNoCache, got %v", err)
+	}
+}
+
+// ===========================================================================
+// Coalescing
+// ===========================================================================
+
+func TestCache_CoalescesConcurrentMisses(t *testing.T) {
+	var calls atomic.Int64
+	release := make(chan struct{})
+	c := NewCache(Config{})
+	h := c.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		<-release
+		w.Header().Set("Cache-Control", "public")
+		w.Write([]byte("slow"))
+	}))
+
+	const n = 10
+	var wg sync.WaitGroup
+	results := make([]*httptest.ResponseRecorder, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = get(h, "/slow")
+		}(i)
+	}
+
+	waitFor(t, func() bool { return calls.Load() == 1 })
+	time.Sleep(20 * time.Millisecond) // let the others queue on the flight
+	close(release)
+	wg.Wait()
+
+	if calls.Load() != 1 {
+		t.Errorf("expected handler to run once, ran %d times", calls.Load())
+	}
+	for i, rr := range results {
+		if rr.Body.String() != "slow" {
+			t.Errorf("request %d: expected body slow, got %q", i, rr.Body.String())
+		}
+	}
+	if s := c.Stats(); s.Misses != 1 || s.Coalesced+s.Hits != n-1 {
+		t.Errorf("expected 1 miss and %d coalesced or hits, got %+v", n-1, s)
+	}
+}
+
+func TestCache_UnshareableResponseNotCoalesced(t *testing.T) {
+	var calls atomic.Int64
+	release := make(chan struct{})
+	h := NewCache(Config{}).Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			<-release
+		}
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.Write([]byte("personal"))
+	}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			get(h, "/me")
+		}()
+	}
+	waitFor(t, func() bool { return calls.Load() >= 1 })
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls.Load() != 3 {
+		t.Errorf("expected every request to run the handler, ran %d times", calls.Load())
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// ===========================================================================
+// Redis
+// ===========================================================================
+
+func TestCache_RedisStoreSharedAcrossInstances(t *testing.T) {
+	store, _ := newTestRedisStore(t)
+
+	var calls atomic.Int64
+	a := NewCache(Config{Store: store}).Middleware()(countingHandler(&calls, nil))
+	b := NewCache(Config{Store: store}).Middleware()(countingHandler(&calls, nil))
+
+	get(a, "/items")
+	rr := get(b, "/items")
+
+	if rr.Header().Get("X-Cache") != "HIT" || rr.Body.String() != "n=1" {
+		t.Errorf("expected second instance to hit shared entry, got %q %q", rr.Header().Get("X-Cache"), rr.Body.String())
+	}
+}
@@ -0,0 +1,133 @@
+package httpcache
+
+import (
+	"container/list"
+	stdctx "context"
+	"sync"
+	"time"
+)
+
+// DefaultMaxEntries is the MemoryStore capacity used by default.
+const DefaultMaxEntries = 1000
+
+// MemoryStore is an in-process, size-bounded LRU Store. Entries only live in
+// the current process; use RedisStore to share a cache across replicas.
+type MemoryStore struct {
+	mu         sync.Mutex
+	maxEntries int
+	ll         *list.List
+	items      map[string]*list.Element
+	tags       map[string]map[string]struct{}
+	now        func() time.Time
+}
+
+type memoryItem struct {
+	key       string
+	value     []byte
+	tags      []string
+	expiresAt time.Time
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore creates an LRU store holding at most maxEntries responses.
+// A maxEntries of zero or less uses DefaultMaxEntries.
+func NewMemoryStore(maxEntries int) *MemoryStore {
+	if maxEntries <= 0 {
+		maxEntries = DefaultMaxEntries
+	}
+	return &MemoryStore{
+		maxEntries: maxEntries,
+		ll:         list.New(),
+		items:      make(map[string]*list.Element),
+		tags:       make(map[string]map[string]struct{}),
+		now:        time.Now,
+	}
+}
+
+// Len returns the number of entries currently held, including expired ones
+// that have not been evicted yet.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ll.Len()
+}
+
+// Get implements Store.
+func (s *MemoryStore) Get(_ stdctx.Context, key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	el, ok := s.items[key]
+	if !ok {
+		return nil, false, nil
+	}
+	item := el.Value.(*memoryItem)
+	if !s.now().Before(item.expiresAt) {
+		s.remove(el)
+		return nil, false, nil
+	}
+	s.ll.MoveToFront(el)
+	return item.value, true, nil
+}
+
+// Set implements Store.
+func (s *MemoryStore) Set(_ stdctx.Context, key string, value []byte, ttl time.Duration, tags []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if el, ok := s.items[key]; ok {
+		s.remove(el)
+	}
+
+	item := &memoryItem{
+		key:       key,
+		value:     value,
+		tags:      tags,
+		expiresAt: s.now().Add(ttl),
+	}
+	s.items[key] = s.ll.PushFront(item)
+	for _, tag := range tags {
+		keys, ok := s.tags[tag]
+		if !ok {
+			keys = make(map[string]struct{})
+			s.tags[tag] = keys
+		}
+		keys[key] = struct{}{}
+	}
+
+	for s.ll.Len() > s.maxEntries {
+		s.remove(s.ll.Back())
+	}
+	return nil
+}
+
+// Invalidate implements Store.
+func (s *MemoryStore) Invalidate(_ stdctx.Context, tags ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, tag := range tags {
+		for key := range s.tags[tag] {
+			if el, ok := s.items[key]; ok {
+				s.remove(el)
+			}
+		}
+		delete(s.tags, tag)
+	}
+	return nil
+}
+
+// remove drops el and its tag references. Callers must hold mu.
+func (s *MemoryStore) remove(el *list.Element) {
+	item := s.ll.Remove(el).(*memoryItem)
+	delete(s.items, item.key)
+	for _, tag := range item.tags {
+		if keys, ok := s.tags[tag]; ok {
+			delete(keys, item.key)
+			if len(keys) == 0 {
+				delete(s.tags, tag)
+			}
+		}
+	}
+}
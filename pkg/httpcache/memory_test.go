@@ -0,0 +1,89 @@
+package httpcache
+
+import (
+	stdctx "context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore_GetSet(t *testing.T) {
+	s := NewMemoryStore(10)
+	ctx := stdctx.Background()
+
+	if _, ok, _ := s.Get(ctx, "k"); ok {
+		t.Fatal("expected miss on empty store")
+	}
+
+	s.Set(ctx, "k", []byte("v"), time.Minute, nil)
+	v, ok, err := s.Get(ctx, "k")
+	if err != nil || !ok {
+		t.Fatalf("expected hit, got ok=%v err=%v", ok, err)
+	}
+	if string(v) != "v" {
+		t.Errorf("expected v, got %q", v)
+	}
+}
+
+func TestMemoryStore_Expiry(t *testing.T) {
+	s := NewMemoryStore(10)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	ctx := stdctx.Background()
+
+	s.Set(ctx, "k", []byte("v"), time.Second, []string{"t"})
+	now = now.Add(time.Second)
+
+	if _, ok, _ := s.Get(ctx, "k"); ok {
+		t.Error("expected expired entry to miss")
+	}
+	if s.Len() != 0 {
+		t.Errorf("expected expired entry to be removed, got %d entries", s.Len())
+	}
+	if len(s.tags) != 0 {
+		t.Errorf("expected tag index to be cleaned up, got %v", s.tags)
+	}
+}
+
+func TestMemoryStore_LRUEviction(t *testing.T) {
+	s := NewMemoryStore(2)
+	ctx := stdctx.Background()
+
+	s.Set(ctx, "a", []byte("1"), time.Minute, nil)
+	s.Set(ctx, "b", []byte("2"), time.Minute, nil)
+	s.Get(ctx, "a") // a becomes most recently used
+	s.Set(ctx, "c", []byte("3"), time.Minute, nil)
+
+	if _, ok, _ := s.Get(ctx, "b"); ok {
+		t.Error("expected least recently used entry b to be evicted")
+	}
+	for _, k := range []string{"a", "c"} {
+		if _, ok, _ := s.Get(ctx, k); !ok {
+			t.Errorf("expected %s to remain", k)
+		}
+	}
+}
+
+func TestMemoryStore_Invalidate(t *testing.T) {
+	s := NewMemoryStore(10)
+	ctx := stdctx.Background()
+
+	s.Set(ctx, "user:1", []byte("1"), time.Minute, []string{"users", "user:1"})
+	s.Set(ctx, "user:2", []byte("2"), time.Minute, []string{"users", "user:2"})
+	s.Set(ctx, "post:1", []byte("p"), time.Minute, []string{"posts"})
+
+	s.Invalidate(ctx, "user:1")
+	if _, ok, _ := s.Get(ctx, "user:1"); ok {
+		t.Error("expected user:1 to be invalidated")
+	}
+	if _, ok, _ := s.Get(ctx, "user:2"); !ok {
+		t.Error("expected user:2 to remain")
+	}
+
+	s.Invalidate(ctx, "users")
+	if _, ok, _ := s.Get(ctx, "user:2"); ok {
+		t.Error("expected user:2 to be invalidated by shared tag")
+	}
+	if _, ok, _ := s.Get(ctx, "post:1"); !ok {
+		t.Error("expected untagged entry to remain")
+	}
+}
@@ -0,0 +1,106 @@
+package httpcache
+
+import (
+	"bytes"
+	"net/http"
+)
+
+// recorder buffers a handler's response in its own header map so it can be
+// inspected, hashed or stored before anything reaches the client. If the
+// handler flushes, or the body outgrows limit, the recorder switches to
+// streaming and everything from then on goes straight to w.
+type recorder struct {
+	w      http.ResponseWriter
+	header http.Header
+	limit  int
+
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+	streaming   bool
+}
+
+func newRecorder(w http.ResponseWriter, limit int) *recorder {
+	return &recorder{w: w, header: make(http.Header), limit: limit}
+}
+
+func (rec *recorder) Header() http.Header {
+	if rec.streaming {
+		return rec.w.Header()
+	}
+	return rec.header
+}
+
+func (rec *recorder) WriteHeader(code int) {
+	if rec.wroteHeader {
+		return
+	}
+	rec.wroteHeader = true
+	rec.status = code
+}
+
+func (rec *recorder) Write(b []byte) (int, error) {
+	if !rec.wroteHeader {
+		rec.WriteHeader(http.StatusOK)
+	}
+	if rec.streaming {
+		return rec.w.Write(b)
+	}
+	if rec.limit > 0 && rec.body.Len()+len(b) > rec.limit {
+		if err := rec.stream(); err != nil {
+			return 0, err
+		}
+		return rec.w.Write(b)
+	}
+	return rec.body.Write(b)
+}
+
+// HeaderWritten reports whether the handler has written the status line.
+func (rec *recorder) HeaderWritten() bool {
+	return rec.wroteHeader
+}
+
+// Flush gives up buffering and streams the response from here on.
+func (rec *recorder) Flush() {
+	if !rec.wroteHeader {
+		rec.WriteHeader(http.StatusOK)
+	}
+	rec.stream()
+	if f, ok := rec.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (rec *recorder) Unwrap() http.ResponseWriter {
+	return rec.w
+}
+
+// stream writes the buffered status, headers and body to w and switches the
+// recorder to pass-through mode.
+func (rec *recorder) stream() error {
+	if rec.streaming {
+		return nil
+	}
+	rec.streaming = true
+	copyHeader(rec.w.Header(), rec.header)
+	rec.w.WriteHeader(rec.statusCode())
+	_, err := rec.w.Write(rec.body.Bytes())
+	rec.body.Reset()
+	return err
+}
+
+// statusCode returns the recorded status, defaulting to 200 like net/http.
+func (rec *recorder) statusCode() int {
+	if rec.status == 0 {
+		return http.StatusOK
+	}
+	return rec.status
+}
+
+// copyHeader adds every value in src to dst, replacing existing keys.
+func copyHeader(dst, src http.Header) {
+	for k, v := range src {
+		dst[k] = append([]string(nil), v...)
+	}
+}
@@ -0,0 +1,90 @@
+package httpcache
+
+import (
+	stdctx "context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// setScript stores a value and adds its key to every tag set, extending a
+// tag set's TTL so it never expires before one of its members.
+//
+// KEYS[1]   entry key
+// KEYS[2..] tag set keys
+// ARGV[1]   value
+// ARGV[2]   ttl in milliseconds
+var setScript = redis.NewScript(`
+local ttl = tonumber(ARGV[2])
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ttl)
+for i = 2, #KEYS do
+  redis.call('SADD', KEYS[i], KEYS[1])
+  if redis.call('PTTL', KEYS[i]) < ttl then
+    redis.call('PEXPIRE', KEYS[i], ttl)
+  end
+end
+return 1
+`)
+
+// invalidateScript deletes every entry listed in the given tag sets, then the
+// sets themselves. Entry keys come from set members rather than KEYS, so the
+// store must not be used with Redis Cluster.
+//
+// KEYS[1..] tag set keys
+var invalidateScript = redis.NewScript(`
+for i = 1, #KEYS do
+  local members = redis.call('SMEMBERS', KEYS[i])
+  for _, key in ipairs(members) do
+    redis.call('DEL', key)
+  end
+  redis.call('DEL', KEYS[i])
+end
+return 1
+`)
+
+// RedisStore is a Store backed by Redis, so every replica sharing the same
+// instance serves from one cache and sees the same invalidations.
+type RedisStore struct {
+	client *redis.Client
+}
+
+var _ Store = (*RedisStore)(nil)
+
+// NewRedisStore creates a store on client, typically from cache.NewRedisClient.
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+// Get implements Store.
+func (s *RedisStore) Get(ctx stdctx.Context, key string) ([]byte, bool, error) {
+	data, err := s.client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("httpcache: get: %w", err)
+	}
+	return data, true, nil
+}
+
+// Set implements Store.
+func (s *RedisStore) Set(ctx stdctx.Context, key string, value []byte, ttl time.Duration, tags []string) error {
+	keys := append([]string{key}, tags...)
+	if err := setScript.Run(ctx, s.client, keys, value, ttl.Milliseconds()).Err(); err != nil {
+		return fmt.Errorf("httpcache: set: %w", err)
+	}
+	return nil
+}
+
+// Invalidate implements Store.
+func (s *RedisStore) Invalidate(ctx stdctx.Context, tags ...string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	if err := invalidateScript.Run(ctx, s.client, tags).Err(); err != nil {
+		return fmt.Errorf("httpcache: invalidate: %w", err)
+	}
+	return nil
+}
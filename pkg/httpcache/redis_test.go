@@ -0,0 +1,65 @@
+package httpcache
+
+import (
+	stdctx "context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestRedisStore(t *testing.T) (*RedisStore, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewRedisStore(client), mr
+}
+
+func TestRedisStore_GetSetExpiry(t *testing.T) {
+	s, mr := newTestRedisStore(t)
+	ctx := stdctx.Background()
+
+	if _, ok, err := s.Get(ctx, "k"); ok || err != nil {
+		t.Fatalf("expected clean miss, got ok=%v err=%v", ok, err)
+	}
+
+	if err := s.Set(ctx, "k", []byte("v"), time.Second, nil); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	v, ok, err := s.Get(ctx, "k")
+	if err != nil || !ok || string(v) != "v" {
+		t.Fatalf("expected hit with v, got %q ok=%v err=%v", v, ok, err)
+	}
+
+	mr.FastForward(time.Second)
+	if _, ok, _ := s.Get(ctx, "k"); ok {
+		t.Error("expected entry to expire")
+	}
+}
+
+func TestRedisStore_Invalidate(t *testing.T) {
+	s, mr := newTestRedisStore(t)
+	ctx := stdctx.Background()
+
+	s.Set(ctx, "a", []byte("1"), time.Minute, []string{"tag:users"})
+	s.Set(ctx, "b", []byte("2"), 2*time.Minute, []string{"tag:users", "tag:b"})
+	s.Set(ctx, "c", []byte("3"), time.Minute, []string{"tag:posts"})
+
+	if ttl := mr.TTL("tag:users"); ttl != 2*time.Minute {
+		t.Errorf("expected tag set TTL to follow longest member, got %v", ttl)
+	}
+
+	if err := s.Invalidate(ctx, "tag:users"); err != nil {
+		t.Fatalf("Invalidate: %v", err)
+	}
+	for _, k := range []string{"a", "b", "tag:users"} {
+		if mr.Exists(k) {
+			t.Errorf("expected %s to be deleted", k)
+		}
+	}
+	if !mr.Exists("c") {
+		t.Error("expected c to remain")
+	}
+}
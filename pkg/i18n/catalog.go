@@ -0,0 +1,206 @@
+// Package i18n provides a locale-aware catalog of error message templates.
+// Templates are keyed by AppError.ErrorCode and may reference parameters
+// with {name} placeholders.
+package i18n
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Catalog maps error codes to message templates per locale.
+// It is safe for concurrent use.
+type Catalog struct {
+	defaultLocale string
+
+	mu       sync.RWMutex
+	messages map[string]map[string]string // locale → code → template
+}
+
+// NewCatalog creates an empty catalog. defaultLocale is used when a request
+// does not express a supported preference, and as the fallback when a code
+// has no template in the requested locale.
+func NewCatalog(defaultLocale string) *Catalog {
+	return &Catalog{
+		defaultLocale: normalizeLocale(defaultLocale),
+		messages:      make(map[string]map[string]string),
+	}
+}
+
+// DefaultLocale returns the catalog's fallback locale.
+func (c *Catalog) DefaultLocale() string {
+	return c.defaultLocale
+}
+
+// Add registers a template for code in locale, replacing any existing one.
+func (c *Catalog) Add(locale, code, template string) {
+	c.AddMessages(locale, map[string]string{code: template})
+}
+
+// AddMessages registers several templates for a single locale.
+func (c *Catalog) AddMessages(locale string, messages map[string]string) {
+	locale = normalizeLocale(locale)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	m, ok := c.messages[locale]
+	if !ok {
+		m = make(map[string]string, len(messages))
+		c.messages[locale] = m
+	}
+	for code, tmpl := range messages {
+		m[code] = tmpl
+	}
+}
+
+// LoadFS loads every <locale>.json, <locale>.yaml and <locale>.yml file in
+// dir of fsys. Each file holds a flat object mapping error codes to
+// templates. It is typically used with an embed.FS:
+//
+//	//go:embed locales
+//	var locales embed.FS
+//	err := catalog.LoadFS(locales, "locales")
+func (c *Catalog) LoadFS(fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return fmt.Errorf("i18n: read dir %q: %w", dir, err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+		ext := path.Ext(name)
+
+		var unmarshal func([]byte, any) error
+		switch ext {
+		case ".json":
+			unmarshal = json.Unmarshal
+		case ".yaml", ".yml":
+			unmarshal = yaml.Unmarshal
+		default:
+			continue
+		}
+
+		data, err := fs.ReadFile(fsys, path.Join(dir, name))
+		if err != nil {
+			return fmt.Errorf("i18n: read %q: %w", name, err)
+		}
+
+		var messages map[string]string
+		if err := unmarshal(data, &messages); err != nil {
+			return fmt.Errorf("i18n: parse %q: %w", name, err)
+		}
+		c.AddMessages(strings.TrimSuffix(name, ext), messages)
+	}
+	return nil
+}
+
+// Locales returns the locales that have at least one template.
+func (c *Catalog) Locales() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	locales := make([]string, 0, len(c.messages))
+	for l := range c.messages {
+		locales = append(locales, l)
+	}
+	return locales
+}
+
+// Translate renders the template for code in locale, falling back first to
+// the locale's base language (vi-VN → vi) and then to the default locale.
+// It returns false when no template exists for code.
+func (c *Catalog) Translate(locale, code string, params map[string]any) (string, bool) {
+	if code == "" {
+		return "", false
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	locale = normalizeLocale(locale)
+	for _, l := range []string{locale, baseLanguage(locale), c.defaultLocale} {
+		if tmpl, ok := c.messages[l][code]; ok {
+			return interpolate(tmpl, params), true
+		}
+	}
+	return "", false
+}
+
+// has reports whether locale has any templates. Callers must not hold mu.
+func (c *Catalog) has(locale string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	_, ok := c.messages[locale]
+	return ok
+}
+
+// regional returns a catalog locale whose base language is base, such as
+// "pt-br" for "pt". Of several, the alphabetically first is chosen so the
+// result is stable. Callers must not hold mu.
+func (c *Catalog) regional(base string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var found string
+	for l := range c.messages {
+		if l != base && baseLanguage(l) == base && (found == "" || l < found) {
+			found = l
+		}
+	}
+	return found, found != ""
+}
+
+// interpolate replaces {name} placeholders in tmpl with values from params.
+// Unknown placeholders are left untouched.
+func interpolate(tmpl string, params map[string]any) string {
+	if len(params) == 0 || !strings.Contains(tmpl, "{") {
+		return tmpl
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(tmpl))
+	for {
+		start := strings.IndexByte(tmpl, '{')
+		if start < 0 {
+			sb.WriteString(tmpl)
+			break
+		}
+		end := strings.IndexByte(tmpl[start:], '}')
+		if end < 0 {
+			sb.WriteString(tmpl)
+			break
+		}
+		end += start
+
+		sb.WriteString(tmpl[:start])
+		if v, ok := params[tmpl[start+1:end]]; ok {
+			fmt.Fprint(&sb, v)
+		} else {
+			sb.WriteString(tmpl[start : end+1])
+		}
+		tmpl = tmpl[end+1:]
+	}
+	return sb.String()
+}
+
+// normalizeLocale lowercases a language tag and uses '-' as the separator.
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
+
+// baseLanguage returns the primary subtag of a locale, e.g. "vi" for "vi-vn".
+func baseLanguage(locale string) string {
+	if i := strings.IndexByte(locale, '-'); i > 0 {
+		return locale[:i]
+	}
+	return locale
+}
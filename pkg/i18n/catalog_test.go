@@ -0,0 +1,206 @@
+package i18n
+
+import (
+	"context"
+	"embed"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	aerror "github.com/vietpham102301/lightway/pkg/errors"
+)
+
+//go:embed testdata/locales
+var testLocales embed.FS
+
+func newTestCatalog(t *testing.T) *Catalog {
+	t.Helper()
+	c := NewCatalog("en")
+	if err := c.LoadFS(testLocales, "testdata/locales"); err != nil {
+		t.Fatalf("LoadFS: %v", err)
+	}
+	return c
+}
+
+// ===========================================================================
+// LoadFS
+// ===========================================================================
+
+func TestLoadFS_JSONAndYAML(t *testing.T) {
+	c := newTestCatalog(t)
+
+	if msg, ok := c.Translate("en", "USER_NOT_FOUND", nil); !ok || msg != "User {id} not found" {
+		t.Errorf("expected English template from JSON, got %q (ok=%v)", msg, ok)
+	}
+	if msg, ok := c.Translate("vi", "USER_NOT_FOUND", nil); !ok || msg != "Không tìm thấy người dùng {id}" {
+		t.Errorf("expected Vietnamese template from YAML, got %q (ok=%v)", msg, ok)
+	}
+}
+
+func TestLoadFS_InvalidFile(t *testing.T) {
+	fsys := fstest.MapFS{
+		"locales/en.json": &fstest.MapFile{Data: []byte(`{not json`)},
+	}
+	if err := NewCatalog("en").LoadFS(fsys, "locales"); err == nil {
+		t.Error("expected error for malformed JSON")
+	}
+}
+
+func TestLoadFS_MissingDir(t *testing.T) {
+	if err := NewCatalog("en").LoadFS(fstest.MapFS{}, "nope"); err == nil {
+		t.Error("expected error for missing directory")
+	}
+}
+
+// ===========================================================================
+// Translate
+// ===========================================================================
+
+func TestTranslate_Interpolation(t *testing.T) {
+	c := newTestCatalog(t)
+
+	msg, ok := c.Translate("vi", "USER_NOT_FOUND", map[string]any{"id": 42})
+	if !ok {
+		t.Fatal("expected translation")
+	}
+	if msg != "Không tìm thấy người dùng 42" {
+		t.Errorf("unexpected message %q", msg)
+	}
+}
+
+func TestTranslate_UnknownPlaceholderKept(t *testing.T) {
+	c := NewCatalog("en")
+	c.Add("en", "X", "hello {name} and {other}")
+
+	msg, _ := c.Translate("en", "X", map[string]any{"name": "An"})
+	if msg != "hello An and {other}" {
+		t.Errorf("unexpected message %q", msg)
+	}
+}
+
+func TestTranslate_Fallbacks(t *testing.T) {
+	c := newTestCatalog(t)
+	c.Add("en", "ONLY_EN", "english only")
+
+	if msg, _ := c.Translate("vi-VN", "REQUIRED", map[string]any{"field": "email"}); msg != "email là bắt buộc" {
+		t.Errorf("expected base-language fallback, got %q", msg)
+	}
+	if msg, _ := c.Translate("vi", "ONLY_EN", nil); msg != "english only" {
+		t.Errorf("expected default-locale fallback, got %q", msg)
+	}
+	if _, ok := c.Translate("vi", "UNKNOWN", nil); ok {
+		t.Error("expected no translation for unknown code")
+	}
+	if _, ok := c.Translate("vi", "", nil); ok {
+		t.Error("expected no translation for empty code")
+	}
+}
+
+// ===========================================================================
+// Match / Middleware
+// ===========================================================================
+
+func TestMatch(t *testing.T) {
+	c := newTestCatalog(t)
+
+	tests := []struct {
+		header   string
+		expected string
+	}{
+		{"", "en"},
+		{"vi", "vi"},
+		{"vi-VN,vi;q=0.9,en;q=0.8", "vi"},
+		{"fr-FR, en;q=0.5", "en"},
+		{"en;q=0.3, vi;q=0.7", "vi"},
+		{"vi;q=0, en", "en"},
+		{"de", "en"},
+	}
+	for _, tt := range tests {
+		if got := c.Match(tt.header); got != tt.expected {
+			t.Errorf("Match(%q) = %q, want %q", tt.header, got, tt.expected)
+		}
+	}
+}
+
+func TestMatch_BaseToRegion(t *testing.T) {
+	c := NewCatalog("en")
+	c.Add("en", "greeting", "Hello")
+	c.Add("pt-BR", "greeting", "Olá")
+	c.Add("pt-PT", "greeting", "Olá")
+
+	tests := []struct {
+		header   string
+		expected string
+	}{
+		{"pt", "pt-br"},
+		{"pt-AO", "pt-br"},
+		{"pt-PT", "pt-pt"},
+		{"fr, pt;q=0.5", "pt-br"},
+		{"en, pt;q=0.5", "en"},
+	}
+	for _, tt := range tests {
+		if got := c.Match(tt.header); got != tt.expected {
+			t.Errorf("Match(%q) = %q, want %q", tt.header, got, tt.expected)
+		}
+	}
+}
+
+func TestMiddleware_StoresLocaleAndCatalog(t *testing.T) {
+	c := newTestCatalog(t)
+
+	var locale string
+	var catalog *Catalog
+	handler := c.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		locale = Locale(r.Context())
+		catalog = CatalogFrom(r.Context())
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Language", "vi-VN")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if locale != "vi" {
+		t.Errorf("expected locale 'vi', got %q", locale)
+	}
+	if catalog != c {
+		t.Error("expected catalog to be stored in context")
+	}
+	if w.Header().Get("Vary") != "Accept-Language" {
+		t.Errorf("expected Vary: Accept-Language, got %q", w.Header().Get("Vary"))
+	}
+}
+
+// ===========================================================================
+// Localize
+// ===========================================================================
+
+func TestLocalize(t *testing.T) {
+	c := newTestCatalog(t)
+	ctx := WithCatalog(WithLocale(context.Background(), "vi"), c)
+
+	original := aerror.NotFound("user not found").
+		WithCode("USER_NOT_FOUND").
+		WithParam("id", 7).
+		WithFields(aerror.FieldError{Field: "email", Message: "email is required", Code: "REQUIRED"})
+
+	got := Localize(ctx, original)
+
+	if got.Message != "Không tìm thấy người dùng 7" {
+		t.Errorf("unexpected translated message %q", got.Message)
+	}
+	if got.Fields[0].Message != "email là bắt buộc" {
+		t.Errorf("unexpected translated field message %q", got.Fields[0].Message)
+	}
+	if original.Message != "user not found" || original.Fields[0].Message != "email is required" {
+		t.Error("Localize must not modify the original AppError")
+	}
+}
+
+func TestLocalize_NoCatalog(t *testing.T) {
+	original := aerror.NotFound("x").WithCode("USER_NOT_FOUND")
+	if got := Localize(context.Background(), original); got != original {
+		t.Error("expected original AppError when ctx has no catalog")
+	}
+}
@@ -0,0 +1,164 @@
+package i18n
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	aerror "github.com/vietpham102301/lightway/pkg/errors"
+)
+
+type contextKey string
+
+const (
+	localeKey  contextKey = "i18n_locale"
+	catalogKey contextKey = "i18n_catalog"
+)
+
+// WithLocale returns a copy of ctx carrying locale.
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeKey, normalizeLocale(locale))
+}
+
+// Locale returns the locale stored in ctx, or "" if none was set.
+func Locale(ctx context.Context) string {
+	locale, _ := ctx.Value(localeKey).(string)
+	return locale
+}
+
+// WithCatalog returns a copy of ctx carrying catalog.
+func WithCatalog(ctx context.Context, catalog *Catalog) context.Context {
+	return context.WithValue(ctx, catalogKey, catalog)
+}
+
+// CatalogFrom returns the catalog stored in ctx, or nil if none was set.
+func CatalogFrom(ctx context.Context) *Catalog {
+	catalog, _ := ctx.Value(catalogKey).(*Catalog)
+	return catalog
+}
+
+// Translate renders code using the catalog and locale carried by ctx.
+// It returns false when ctx has no catalog or the code is unknown.
+func Translate(ctx context.Context, code string, params map[string]any) (string, bool) {
+	catalog := CatalogFrom(ctx)
+	if catalog == nil {
+		return "", false
+	}
+	return catalog.Translate(Locale(ctx), code, params)
+}
+
+// Middleware returns an HTTP middleware that negotiates the response locale
+// from the Accept-Language header and stores it, together with the catalog,
+// in the request context so context.JSONResponse can translate AppErrors.
+func (c *Catalog) Middleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			locale := c.Match(r.Header.Get("Accept-Language"))
+			ctx := WithCatalog(WithLocale(r.Context(), locale), c)
+			w.Header().Add("Vary", "Accept-Language")
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Match picks the best supported locale for an Accept-Language header value,
+// honoring q-values. A tag matches a catalog locale exactly, by base
+// language (pt-BR → pt), or else by a regional locale of the same language
+// (pt → pt-BR). The default locale is returned when nothing matches.
+func (c *Catalog) Match(acceptLanguage string) string {
+	for _, tag := range parseAcceptLanguage(acceptLanguage) {
+		if tag == "*" {
+			break
+		}
+		if c.has(tag) {
+			return tag
+		}
+		base := baseLanguage(tag)
+		if c.has(base) {
+			return base
+		}
+		if regional, ok := c.regional(base); ok {
+			return regional
+		}
+	}
+	return c.defaultLocale
+}
+
+// parseAcceptLanguage returns the language tags of header ordered by
+// descending q-value. Tags with q=0 are dropped.
+func parseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+
+	var tags []weighted
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		tag, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q <= 0 {
+			continue
+		}
+		tags = append(tags, weighted{tag: normalizeLocale(tag), q: q})
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+
+	out := make([]string, len(tags))
+	for i, t := range tags {
+		out[i] = t.tag
+	}
+	return out
+}
+
+// Localize returns a copy of appErr whose Message, and the Message of each
+// field error with a Code, are translated for the locale in ctx. Message
+// templates are looked up by ErrorCode and interpolated with Params; field
+// templates receive the field name as {field}. appErr itself is never
+// modified, so logs keep the canonical message. appErr is returned as-is
+// when ctx has no catalog or nothing translates.
+func Localize(ctx context.Context, appErr *aerror.AppError) *aerror.AppError {
+	if CatalogFrom(ctx) == nil {
+		return appErr
+	}
+
+	msg, msgOK := Translate(ctx, appErr.ErrorCode, appErr.Params)
+
+	var fields []aerror.FieldError
+	for i, f := range appErr.Fields {
+		fieldMsg, ok := Translate(ctx, f.Code, map[string]any{"field": f.Field})
+		if !ok {
+			continue
+		}
+		if fields == nil {
+			fields = append([]aerror.FieldError(nil), appErr.Fields...)
+		}
+		fields[i].Message = fieldMsg
+	}
+
+	if !msgOK && fields == nil {
+		return appErr
+	}
+
+	translated := *appErr
+	if msgOK {
+		translated.Message = msg
+	}
+	if fields != nil {
+		translated.Fields = fields
+	}
+	return &translated
+}
@@ -0,0 +1,350 @@
+// Package lock provides a Redis-backed distributed mutex for critical
+// sections shared by several replicas of a service. Locks are leases taken
+// with SET NX PX, renewed in the background while held and released with a
+// compare-and-delete script, so a replica can only ever release its own
+// lock. Every acquisition carries a fencing token that increases for each
+// new holder of a key.
+//
+// A Locker also implements scheduler.Locker, so that when several replicas
+// run the same schedule only one of them runs each tick. Ticks are told
+// apart by their scheduled time alone, so this holds only when replicas
+// compute the same times: cron schedules do, and the scheduler aligns
+// interval ticks to the interval when a Locker is set.
+package lock
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Sentinel errors returned by the lock package.
+var (
+	// ErrNotAcquired is returned by TryAcquire when another holder has the
+	// lock, and wrapped by Acquire when ctx ends before the lock is free.
+	ErrNotAcquired = errors.New("lock: not acquired")
+
+	// ErrLockLost is returned when a lock is no longer held by its owner —
+	// its lease expired or it was already released.
+	ErrLockLost = errors.New("lock: lock lost")
+)
+
+// acquireScript takes the lock and bumps the key's fencing counter
+// atomically, so tokens strictly increase across holders.
+//
+// KEYS[1] lock key
+// KEYS[2] fencing counter key
+// ARGV[1] owner value
+// ARGV[2] TTL in milliseconds
+// Returns the fencing token, or 0 if the lock is held.
+var acquireScript = redis.NewScript(`
+if redis.call('SET', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2]) then
+  return redis.call('INCR', KEYS[2])
+end
+return 0
+`)
+
+// refreshScript extends the lease if the caller still owns it.
+//
+// KEYS[1] lock key
+// ARGV[1] owner value
+// ARGV[2] TTL in milliseconds
+// Returns 1 if extended, 0 if the lock is not held by the caller.
+var refreshScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+  return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// releaseScript deletes the lock if the caller still owns it.
+//
+// KEYS[1] lock key
+// ARGV[1] owner value
+// Returns 1 if released, 0 if the lock is not held by the caller.
+var releaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+  return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// claimScript claims a one-off key for an owner. Claiming again with the
+// same owner succeeds, so a claim survives retries.
+//
+// KEYS[1] claim key
+// ARGV[1] owner value
+// ARGV[2] TTL in milliseconds
+// Returns 1 if the caller owns the claim, 0 otherwise.
+var claimScript = redis.NewScript(`
+if redis.call('SET', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2]) then
+  return 1
+end
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+  return 1
+end
+return 0
+`)
+
+// Config holds all tunables for a Locker. Zero values produce sensible
+// defaults.
+type Config struct {
+	// Prefix is prepended to every Redis key. Default: "lock:"
+	Prefix string
+
+	// TTL is the lease length. A holder that stops renewing — because it
+	// crashed or lost Redis — loses the lock after TTL. Default: 30s
+	TTL time.Duration
+
+	// RenewInterval is how often a held lock's lease is extended. Negative
+	// disables renewal; call Refresh yourself. Default: TTL / 3
+	RenewInterval time.Duration
+
+	// RetryInterval is how often Acquire retries a held lock.
+	// Default: 100ms
+	RetryInterval time.Duration
+
+	// ClaimTTL is how long a scheduler tick claim is kept. It must exceed
+	// the clock skew between replicas. Default: 10m
+	ClaimTTL time.Duration
+}
+
+func (c *Config) applyDefaults() {
+	if c.Prefix == "" {
+		c.Prefix = "lock:"
+	}
+	if c.TTL <= 0 {
+		c.TTL = 30 * time.Second
+	}
+	if c.RenewInterval == 0 {
+		c.RenewInterval = c.TTL / 3
+	}
+	if c.RetryInterval <= 0 {
+		c.RetryInterval = 100 * time.Millisecond
+	}
+	if c.ClaimTTL <= 0 {
+		c.ClaimTTL = 10 * time.Minute
+	}
+}
+
+// Locker hands out locks stored in one Redis instance.
+type Locker struct {
+	client *redis.Client
+	cfg    Config
+	owner  string // identifies this Locker's tick claims
+}
+
+// New creates a Locker on client, typically from cache.NewRedisClient,
+// with default settings.
+func New(client *redis.Client) *Locker {
+	return NewWithConfig(client, Config{})
+}
+
+// NewWithConfig creates a Locker on client with cfg.
+func NewWithConfig(client *redis.Client, cfg Config) *Locker {
+	cfg.applyDefaults()
+	return &Locker{client: client, cfg: cfg, owner: rand.Text()}
+}
+
+// TryAcquire takes the lock named key, returning ErrNotAcquired at once if
+// it is held.
+func (l *Locker) TryAcquire(ctx context.Context, key string) (*Lock, error) {
+	value := rand.Text()
+	token, err := acquireScript.Run(ctx, l.client, []string{l.key(key), l.key(key) + ":fence"},
+		value, l.cfg.TTL.Milliseconds()).Int64()
+	if err != nil {
+		return nil, fmt.Errorf("lock: acquire %q: %w", key, err)
+	}
+	if token == 0 {
+		return nil, ErrNotAcquired
+	}
+
+	lk := &Lock{
+		locker: l,
+		key:    key,
+		value:  value,
+		token:  token,
+		lost:   make(chan struct{}),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	if l.cfg.RenewInterval > 0 {
+		go lk.renew()
+	} else {
+		close(lk.done)
+	}
+	return lk, nil
+}
+
+// Acquire takes the lock named key, retrying every Config.RetryInterval
+// while it is held. If ctx ends first the error wraps both ErrNotAcquired
+// and ctx.Err().
+func (l *Locker) Acquire(ctx context.Context, key string) (*Lock, error) {
+	ticker := time.NewTicker(l.cfg.RetryInterval)
+	defer ticker.Stop()
+	for {
+		lk, err := l.TryAcquire(ctx, key)
+		switch {
+		case err == nil:
+			return lk, nil
+		case ctx.Err() != nil:
+			return nil, fmt.Errorf("%w: %w", ErrNotAcquired, ctx.Err())
+		case !errors.Is(err, ErrNotAcquired):
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%w: %w", ErrNotAcquired, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// WithLock runs fn while holding the lock named key, waiting for it as
+// Acquire does. fn's context is cancelled with cause ErrLockLost if the
+// lease is lost while fn runs. The lock is released when fn returns; fn's
+// error takes precedence over a release error.
+func (l *Locker) WithLock(ctx context.Context, key string, fn func(ctx context.Context, lk *Lock) error) error {
+	lk, err := l.Acquire(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	runCtx, cancel := context.WithCancelCause(ctx)
+	go func() {
+		select {
+		case <-lk.Lost():
+			cancel(ErrLockLost)
+		case <-runCtx.Done():
+		}
+	}()
+	err = fn(runCtx, lk)
+	cancel(nil)
+
+	if relErr := lk.Release(context.WithoutCancel(ctx)); err == nil {
+		err = relErr
+	}
+	return err
+}
+
+// Claim reports whether this Locker won the run of task scheduled at at.
+// The first Locker to claim a tick owns it for Config.ClaimTTL; claiming
+// the same tick again from the same Locker also succeeds. Replicas only
+// contend for a tick if they pass the same at, to the millisecond. It
+// implements scheduler.Locker.
+func (l *Locker) Claim(ctx context.Context, task string, at time.Time) (bool, error) {
+	key := fmt.Sprintf("%stick:{%s}:%d", l.cfg.Prefix, task, at.UnixMilli())
+	n, err := claimScript.Run(ctx, l.client, []string{key}, l.owner, l.cfg.ClaimTTL.Milliseconds()).Int64()
+	if err != nil {
+		return false, fmt.Errorf("lock: claim %q: %w", task, err)
+	}
+	return n == 1, nil
+}
+
+// key returns the Redis key for a lock. The braces keep a lock and its
+// fencing counter in one Redis Cluster slot.
+func (l *Locker) key(name string) string {
+	return l.cfg.Prefix + "{" + name + "}"
+}
+
+// Lock is a held lock. It is safe for concurrent use.
+type Lock struct {
+	locker *Locker
+	key    string
+	value  string // random owner value; only its holder can release
+	token  int64
+
+	lost     chan struct{}
+	lostOnce sync.Once
+	stop     chan struct{} // closed by Release to end renewal
+	stopOnce sync.Once
+	done     chan struct{} // closed when renewal has ended
+}
+
+// Key returns the lock's name.
+func (lk *Lock) Key() string { return lk.key }
+
+// Token returns the fencing token for this acquisition. Tokens strictly
+// increase for each new holder of a key: pass the token to the resource the
+// lock protects and have it reject writes carrying a lower token than one
+// it has already seen, which guards against a holder whose lease expired
+// while it was paused.
+func (lk *Lock) Token() int64 { return lk.token }
+
+// Lost returns a channel closed once the lock is no longer held — because
+// it was released or its lease could not be renewed.
+func (lk *Lock) Lost() <-chan struct{} { return lk.lost }
+
+// Refresh extends the lease by Config.TTL. It returns ErrLockLost if the
+// lease has already expired or the lock was released.
+func (lk *Lock) Refresh(ctx context.Context) error {
+	n, err := refreshScript.Run(ctx, lk.locker.client, []string{lk.locker.key(lk.key)},
+		lk.value, lk.locker.cfg.TTL.Milliseconds()).Int64()
+	if err != nil {
+		return fmt.Errorf("lock: refresh %q: %w", lk.key, err)
+	}
+	if n == 0 {
+		lk.markLost()
+		return ErrLockLost
+	}
+	return nil
+}
+
+// Release stops renewal and frees the lock. It returns ErrLockLost if the
+// lock was no longer held, meaning another holder may have run in the
+// meantime.
+func (lk *Lock) Release(ctx context.Context) error {
+	lk.stopOnce.Do(func() { close(lk.stop) })
+	<-lk.done
+	defer lk.markLost()
+
+	n, err := releaseScript.Run(ctx, lk.locker.client, []string{lk.locker.key(lk.key)}, lk.value).Int64()
+	if err != nil {
+		return fmt.Errorf("lock: release %q: %w", lk.key, err)
+	}
+	if n == 0 {
+		return ErrLockLost
+	}
+	return nil
+}
+
+// renew extends the lease every RenewInterval until Release. Transient
+// Redis errors are retried until the lease would have run out.
+func (lk *Lock) renew() {
+	defer close(lk.done)
+	cfg := lk.locker.cfg
+	ticker := time.NewTicker(cfg.RenewInterval)
+	defer ticker.Stop()
+
+	expires := time.Now().Add(cfg.TTL)
+	for {
+		select {
+		case <-lk.stop:
+			return
+		case <-lk.lost:
+			return
+		case <-ticker.C:
+		}
+
+		start := time.Now()
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.RenewInterval)
+		err := lk.Refresh(ctx)
+		cancel()
+		switch {
+		case err == nil:
+			expires = start.Add(cfg.TTL)
+		case errors.Is(err, ErrLockLost) || !time.Now().Before(expires):
+			lk.markLost()
+			return
+		}
+	}
+}
+
+func (lk *Lock) markLost() {
+	lk.lostOnce.Do(func() { close(lk.lost) })
+}
@@ -0,0 +1,314 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/vietpham102301/lightway/pkg/scheduler"
+)
+
+var _ scheduler.Locker = (*Locker)(nil)
+
+// ===========================================================================
+// Helpers
+// ===========================================================================
+
+func newTestLocker(t *testing.T, cfg Config) (*Locker, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewWithConfig(client, cfg), mr
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout waiting for condition")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func mustAcquire(t *testing.T, l *Locker, key string) *Lock {
+	t.Helper()
+	lk, err := l.TryAcquire(context.Background(), key)
+	if err != nil {
+		t.Fatalf("TryAcquire(%q): unexpected error: %v", key, err)
+	}
+	return lk
+}
+
+// ===========================================================================
+// Config
+// ===========================================================================
+
+func TestConfig_Defaults(t *testing.T) {
+	cfg := Config{}
+	cfg.applyDefaults()
+
+	if cfg.Prefix != "lock:" {
+		t.Errorf("expected Prefix %q, got %q", "lock:", cfg.Prefix)
+	}
+	if cfg.TTL != 30*time.Second {
+		t.Errorf("expected TTL 30s, got %v", cfg.TTL)
+	}
+	if cfg.RenewInterval != 10*time.Second {
+		t.Errorf("expected RenewInterval 10s, got %v", cfg.RenewInterval)
+	}
+	if cfg.RetryInterval != 100*time.Millisecond {
+		t.Errorf("expected RetryInterval 100ms, got %v", cfg.RetryInterval)
+	}
+	if cfg.ClaimTTL != 10*time.Minute {
+		t.Errorf("expected ClaimTTL 10m, got %v", cfg.ClaimTTL)
+	}
+}
+
+// ===========================================================================
+// Acquire / Release
+// ===========================================================================
+
+func TestLock_TryAcquireAndRelease(t *testing.T) {
+	l, mr := newTestLocker(t, Config{TTL: 5 * time.Second, RenewInterval: -1})
+	ctx := context.Background()
+
+	lk := mustAcquire(t, l, "job")
+	if lk.Key() != "job" {
+		t.Errorf("expected key %q, got %q", "job", lk.Key())
+	}
+	if ttl := mr.TTL("lock:{job}"); ttl != 5*time.Second {
+		t.Errorf("expected lease of 5s, got %v", ttl)
+	}
+
+	if _, err := l.TryAcquire(ctx, "job"); !errors.Is(err, ErrNotAcquired) {
+		t.Fatalf("expected ErrNotAcquired while held, got %v", err)
+	}
+	if _, err := l.TryAcquire(ctx, "other"); err != nil {
+		t.Fatalf("expected an unrelated key to be free, got %v", err)
+	}
+
+	if err := lk.Release(ctx); err != nil {
+		t.Fatalf("Release: unexpected error: %v", err)
+	}
+	select {
+	case <-lk.Lost():
+	default:
+		t.Error("expected Lost to be closed after Release")
+	}
+	if mr.Exists("lock:{job}") {
+		t.Error("expected lock key to be deleted")
+	}
+	mustAcquire(t, l, "job")
+}
+
+func TestLock_FencingTokensIncrease(t *testing.T) {
+	l, _ := newTestLocker(t, Config{RenewInterval: -1})
+	ctx := context.Background()
+
+	var last int64
+	for i := 0; i < 3; i++ {
+		lk := mustAcquire(t, l, "job")
+		if lk.Token() <= last {
+			t.Fatalf("acquisition %d: expected token above %d, got %d", i, last, lk.Token())
+		}
+		last = lk.Token()
+		if err := lk.Release(ctx); err != nil {
+			t.Fatalf("Release: unexpected error: %v", err)
+		}
+	}
+}
+
+func TestLock_ReleaseAfterExpiry(t *testing.T) {
+	l, mr := newTestLocker(t, Config{TTL: time.Second, RenewInterval: -1})
+	ctx := context.Background()
+
+	stale := mustAcquire(t, l, "job")
+	mr.FastForward(2 * time.Second)
+	fresh := mustAcquire(t, l, "job")
+	if fresh.Token() <= stale.Token() {
+		t.Fatalf("expected new holder's token above %d, got %d", stale.Token(), fresh.Token())
+	}
+
+	if err := stale.Release(ctx); !errors.Is(err, ErrLockLost) {
+		t.Fatalf("expected ErrLockLost releasing an expired lock, got %v", err)
+	}
+	if err := stale.Refresh(ctx); !errors.Is(err, ErrLockLost) {
+		t.Fatalf("expected ErrLockLost refreshing an expired lock, got %v", err)
+	}
+	if !mr.Exists("lock:{job}") {
+		t.Fatal("expected the new holder's lock to survive the stale release")
+	}
+	if err := fresh.Release(ctx); err != nil {
+		t.Fatalf("Release: unexpected error: %v", err)
+	}
+}
+
+func TestLock_AcquireWaits(t *testing.T) {
+	l, _ := newTestLocker(t, Config{RenewInterval: -1, RetryInterval: 5 * time.Millisecond})
+	ctx := context.Background()
+
+	held := mustAcquire(t, l, "job")
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		held.Release(ctx)
+	}()
+
+	lk, err := l.Acquire(ctx, "job")
+	if err != nil {
+		t.Fatalf("Acquire: unexpected error: %v", err)
+	}
+	if lk.Token() <= held.Token() {
+		t.Errorf("expected token above %d, got %d", held.Token(), lk.Token())
+	}
+}
+
+func TestLock_AcquireContextDone(t *testing.T) {
+	l, _ := newTestLocker(t, Config{RenewInterval: -1, RetryInterval: 5 * time.Millisecond})
+	mustAcquire(t, l, "job")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := l.Acquire(ctx, "job")
+	if !errors.Is(err, ErrNotAcquired) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected ErrNotAcquired wrapping DeadlineExceeded, got %v", err)
+	}
+}
+
+// ===========================================================================
+// Renewal
+// ===========================================================================
+
+func TestLock_Renewal(t *testing.T) {
+	l, mr := newTestLocker(t, Config{TTL: time.Second, RenewInterval: 10 * time.Millisecond})
+	lk := mustAcquire(t, l, "job")
+	defer lk.Release(context.Background())
+
+	mr.FastForward(900 * time.Millisecond)
+	waitFor(t, func() bool { return mr.TTL("lock:{job}") > 500*time.Millisecond })
+
+	select {
+	case <-lk.Lost():
+		t.Fatal("expected lock to still be held")
+	default:
+	}
+}
+
+func TestLock_LostOnTakeover(t *testing.T) {
+	l, mr := newTestLocker(t, Config{TTL: time.Second, RenewInterval: 10 * time.Millisecond})
+	lk := mustAcquire(t, l, "job")
+
+	// Simulate the lease expiring and another replica taking the lock.
+	mr.Set("lock:{job}", "someone-else")
+
+	select {
+	case <-lk.Lost():
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected Lost to be closed after a takeover")
+	}
+	if err := lk.Release(context.Background()); !errors.Is(err, ErrLockLost) {
+		t.Fatalf("expected ErrLockLost, got %v", err)
+	}
+	if got, _ := mr.Get("lock:{job}"); got != "someone-else" {
+		t.Errorf("expected the other holder's lock to be untouched, got %q", got)
+	}
+}
+
+// ===========================================================================
+// WithLock
+// ===========================================================================
+
+func TestLocker_WithLock(t *testing.T) {
+	l, mr := newTestLocker(t, Config{RenewInterval: -1})
+	ctx := context.Background()
+
+	errBoom := errors.New("boom")
+	err := l.WithLock(ctx, "job", func(ctx context.Context, lk *Lock) error {
+		if !mr.Exists("lock:{job}") {
+			t.Error("expected lock to be held inside fn")
+		}
+		if lk.Token() == 0 {
+			t.Error("expected a fencing token")
+		}
+		return errBoom
+	})
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("expected fn's error, got %v", err)
+	}
+	if mr.Exists("lock:{job}") {
+		t.Error("expected lock to be released after fn")
+	}
+}
+
+func TestLocker_WithLockCancelsOnLoss(t *testing.T) {
+	l, mr := newTestLocker(t, Config{TTL: time.Second, RenewInterval: 10 * time.Millisecond})
+
+	err := l.WithLock(context.Background(), "job", func(ctx context.Context, lk *Lock) error {
+		mr.Del("lock:{job}")
+		select {
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		case <-time.After(2 * time.Second):
+			return errors.New("context not cancelled")
+		}
+	})
+	if !errors.Is(err, ErrLockLost) {
+		t.Fatalf("expected ErrLockLost, got %v", err)
+	}
+}
+
+// ===========================================================================
+// Claim
+// ===========================================================================
+
+func TestLocker_Claim(t *testing.T) {
+	a, mr := newTestLocker(t, Config{ClaimTTL: time.Minute})
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	b := NewWithConfig(client, Config{ClaimTTL: time.Minute})
+
+	ctx := context.Background()
+	tick := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+
+	if won, err := a.Claim(ctx, "report", tick); err != nil || !won {
+		t.Fatalf("expected first claim to win, got %v (err=%v)", won, err)
+	}
+	if won, err := b.Claim(ctx, "report", tick); err != nil || won {
+		t.Fatalf("expected second replica to lose, got %v (err=%v)", won, err)
+	}
+	if won, _ := a.Claim(ctx, "report", tick); !won {
+		t.Fatal("expected the owner to win a repeated claim")
+	}
+	if won, _ := b.Claim(ctx, "report", tick.Add(time.Minute)); !won {
+		t.Fatal("expected the next tick to be claimable")
+	}
+
+	key := "lock:tick:{report}:" + "1767258000000"
+	if ttl := mr.TTL(key); ttl != time.Minute {
+		t.Errorf("expected claim to expire after 1m, got %v", ttl)
+	}
+}
+
+// ===========================================================================
+// Error handling
+// ===========================================================================
+
+func TestLocker_ConnectionError(t *testing.T) {
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+	defer client.Close()
+	l := New(client)
+	ctx := context.Background()
+
+	if _, err := l.TryAcquire(ctx, "job"); err == nil || errors.Is(err, ErrNotAcquired) {
+		t.Fatalf("expected a connection error, got %v", err)
+	}
+	if _, err := l.Claim(ctx, "job", time.Now()); err == nil {
+		t.Fatal("expected a connection error from Claim")
+	}
+}
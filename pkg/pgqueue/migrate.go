@@ -0,0 +1,94 @@
+package pgqueue
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Migrations holds the SQL files that create the queue tables, named
+// NNNN_description.sql. Migrate applies them; they are exported for teams
+// that run migrations with their own tooling.
+//
+//go:embed migrations/*.sql
+var Migrations embed.FS
+
+// migrationLockID is the advisory lock key serialising concurrent Migrate
+// calls from several replicas.
+const migrationLockID = 0x7067717565756500 // "pgqueue\0"
+
+type migration struct {
+	version int
+	name    string
+	sql     string
+}
+
+// Migrate creates or upgrades the queue tables, applying every migration
+// not yet recorded in pgqueue_migrations. It is safe to call on every
+// start-up and from several replicas at once.
+func Migrate(ctx context.Context, db *pgxpool.Pool) error {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+
+	return pgx.BeginFunc(ctx, db, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", int64(migrationLockID)); err != nil {
+			return fmt.Errorf("pgqueue: lock migrations: %w", err)
+		}
+		if _, err := tx.Exec(ctx, `CREATE TABLE IF NOT EXISTS pgqueue_migrations (
+			version    INTEGER     PRIMARY KEY,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)`); err != nil {
+			return fmt.Errorf("pgqueue: create migrations table: %w", err)
+		}
+
+		var current int
+		if err := tx.QueryRow(ctx, "SELECT COALESCE(MAX(version), 0) FROM pgqueue_migrations").Scan(&current); err != nil {
+			return fmt.Errorf("pgqueue: read migration version: %w", err)
+		}
+		for _, m := range migrations {
+			if m.version <= current {
+				continue
+			}
+			if _, err := tx.Exec(ctx, m.sql); err != nil {
+				return fmt.Errorf("pgqueue: apply migration %s: %w", m.name, err)
+			}
+			if _, err := tx.Exec(ctx, "INSERT INTO pgqueue_migrations (version) VALUES ($1)", m.version); err != nil {
+				return fmt.Errorf("pgqueue: record migration %s: %w", m.name, err)
+			}
+		}
+		return nil
+	})
+}
+
+// loadMigrations reads Migrations in version order.
+func loadMigrations() ([]migration, error) {
+	entries, err := fs.ReadDir(Migrations, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("pgqueue: read migrations: %w", err)
+	}
+
+	var migrations []migration
+	for _, e := range entries {
+		prefix, _, ok := strings.Cut(e.Name(), "_")
+		version, err := strconv.Atoi(prefix)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("pgqueue: migration %q: name must start with a positive version", e.Name())
+		}
+		data, err := Migrations.ReadFile("migrations/" + e.Name())
+		if err != nil {
+			return nil, fmt.Errorf("pgqueue: read migration %q: %w", e.Name(), err)
+		}
+		migrations = append(migrations, migration{version: version, name: e.Name(), sql: string(data)})
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].version < migrations[j].version })
+	return migrations, nil
+}
@@ -0,0 +1,54 @@
+CREATE TABLE IF NOT EXISTS pgqueue_jobs (
+    id           BIGSERIAL   PRIMARY KEY,
+    queue        TEXT        NOT NULL,
+    kind         TEXT        NOT NULL,
+    payload      JSONB       NOT NULL DEFAULT '{}'::jsonb,
+    unique_key   TEXT,
+    attempts     INTEGER     NOT NULL DEFAULT 0,
+    max_attempts INTEGER     NOT NULL,
+    run_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    locked_until TIMESTAMPTZ,
+    last_error   TEXT,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Workers claim ready jobs of one queue in run_at order.
+CREATE INDEX IF NOT EXISTS pgqueue_jobs_ready_idx
+    ON pgqueue_jobs (queue, run_at, id);
+
+-- At most one queued or running job per unique key.
+CREATE UNIQUE INDEX IF NOT EXISTS pgqueue_jobs_unique_idx
+    ON pgqueue_jobs (queue, unique_key)
+    WHERE unique_key IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS pgqueue_dead_jobs (
+    id           BIGINT      PRIMARY KEY,
+    queue        TEXT        NOT NULL,
+    kind         TEXT        NOT NULL,
+    payload      JSONB       NOT NULL,
+    unique_key   TEXT,
+    attempts     INTEGER     NOT NULL,
+    max_attempts INTEGER     NOT NULL,
+    last_error   TEXT,
+    created_at   TIMESTAMPTZ NOT NULL,
+    failed_at    TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS pgqueue_dead_jobs_queue_idx
+    ON pgqueue_dead_jobs (queue, failed_at);
+
+-- Wake listening workers when a job is ready now. Jobs scheduled for later
+-- are picked up by polling.
+CREATE OR REPLACE FUNCTION pgqueue_notify() RETURNS trigger AS $$
+BEGIN
+    IF NEW.run_at <= now() THEN
+        PERFORM pg_notify('pgqueue_jobs', NEW.queue);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS pgqueue_jobs_notify ON pgqueue_jobs;
+CREATE TRIGGER pgqueue_jobs_notify
+    AFTER INSERT ON pgqueue_jobs
+    FOR EACH ROW EXECUTE FUNCTION pgqueue_notify();
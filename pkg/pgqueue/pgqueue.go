@@ -0,0 +1,383 @@
+// Package pgqueue is a durable background job queue stored in PostgreSQL.
+// Jobs survive restarts: workers claim them with SELECT ... FOR UPDATE SKIP
+// LOCKED, hold them for a visibility timeout, and retry failures with
+// exponential backoff until they are moved to a dead-letter table. Jobs can
+// carry a unique key and a run_at time, and LISTEN/NOTIFY wakes workers as
+// soon as a job is enqueued. Handlers execute on a pool.Pool.
+//
+// Delivery is at-least-once: a job whose worker dies, or whose result
+// cannot be recorded, runs again after its visibility timeout, so handlers
+// should be idempotent.
+package pgqueue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/vietpham102301/lightway/pkg/logger"
+	"github.com/vietpham102301/lightway/pkg/pool"
+)
+
+// Sentinel errors returned by the queue.
+var (
+	// ErrDuplicate is returned when enqueueing a job whose unique key is
+	// already held by a queued or running job.
+	ErrDuplicate = errors.New("pgqueue: duplicate unique key")
+
+	// ErrNoHandler is recorded for jobs of a kind with no registered
+	// handler. Such jobs are retried, in case another replica knows them.
+	ErrNoHandler = errors.New("pgqueue: no handler for job kind")
+
+	// ErrJobNotFound is returned by Requeue when no dead job has the ID.
+	ErrJobNotFound = errors.New("pgqueue: job not found")
+)
+
+// Permanent marks err as not worth retrying: the job is dead-lettered
+// straight away.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err}
+}
+
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+
+func (e permanentError) Unwrap() error { return e.err }
+
+// Job is a claimed job, as passed to a Handler.
+type Job struct {
+	ID          int64
+	Queue       string
+	Kind        string
+	Payload     json.RawMessage
+	Attempt     int // 1 on the first run
+	MaxAttempts int
+	RunAt       time.Time
+	CreatedAt   time.Time
+}
+
+// Decode unmarshals the job's JSON payload into v.
+func (j *Job) Decode(v any) error {
+	if err := json.Unmarshal(j.Payload, v); err != nil {
+		return fmt.Errorf("pgqueue: decode %s payload: %w", j.Kind, err)
+	}
+	return nil
+}
+
+// Handler runs one job. Returning an error schedules a retry, or
+// dead-letters the job on its last attempt or if the error is Permanent.
+type Handler func(ctx context.Context, job *Job) error
+
+// EnqueueOptions tune a single job. The zero value runs the job as soon as
+// possible with Config.MaxAttempts.
+type EnqueueOptions struct {
+	// RunAt delays the job until the given time.
+	RunAt time.Time
+
+	// UniqueKey, if set, rejects the job with ErrDuplicate while another
+	// job in the queue with the same key is waiting or running.
+	UniqueKey string
+
+	// MaxAttempts overrides Config.MaxAttempts for this job.
+	MaxAttempts int
+}
+
+// Config holds all tunables for a Queue. Zero values produce sensible
+// defaults.
+type Config struct {
+	// Queue names the queue; several queues share the same tables.
+	// Default: "default"
+	Queue string
+
+	// Concurrency is the maximum number of jobs this Queue has claimed at
+	// once. Each claim is also capped at the room left in the pool's queue.
+	// Default: 10
+	Concurrency int
+
+	// PollInterval is how often the table is polled for jobs that are due
+	// (scheduled jobs, retries) or were missed by LISTEN. Default: 1s
+	PollInterval time.Duration
+
+	// VisibilityTimeout is how long a claim lasts. A job still unfinished
+	// after it is assumed lost and becomes claimable again. Handlers get
+	// what is left of it once a pool worker picks the job up as their
+	// timeout, and jobs whose claim ran out while waiting for one are
+	// handed back without running. Default: 5m
+	VisibilityTimeout time.Duration
+
+	// MaxAttempts is the default number of attempts, including the first,
+	// before a job is dead-lettered. Default: 5
+	MaxAttempts int
+
+	// RetryBaseDelay is the wait before the first retry; it doubles with
+	// each further retry. Default: 1s
+	RetryBaseDelay time.Duration
+
+	// RetryMaxDelay caps the retry backoff. Default: 1h
+	RetryMaxDelay time.Duration
+
+	// OnError is called when an attempt fails and when the queue itself
+	// hits a database error, in which case job is nil.
+	// Default: logs a warning
+	OnError func(job *Job, err error)
+}
+
+func (c *Config) applyDefaults() {
+	if c.Queue == "" {
+		c.Queue = "default"
+	}
+	if c.Concurrency <= 0 {
+		c.Concurrency = 10
+	}
+	if c.PollInterval <= 0 {
+		c.PollInterval = time.Second
+	}
+	if c.VisibilityTimeout <= 0 {
+		c.VisibilityTimeout = 5 * time.Minute
+	}
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = 5
+	}
+	if c.RetryBaseDelay <= 0 {
+		c.RetryBaseDelay = time.Second
+	}
+	if c.RetryMaxDelay <= 0 {
+		c.RetryMaxDelay = time.Hour
+	}
+	if c.OnError == nil {
+		c.OnError = func(job *Job, err error) {
+			if job == nil {
+				logger.Warn("pgqueue: queue error", "err", err)
+				return
+			}
+			logger.Warn("pgqueue: job failed", "id", job.ID, "kind", job.Kind, "attempt", job.Attempt, "err", err)
+		}
+	}
+}
+
+// backoff returns the delay before retrying after the given failed
+// attempt, with 20% jitter.
+func (c *Config) backoff(attempt int) time.Duration {
+	d := time.Duration(float64(c.RetryBaseDelay) * math.Pow(2, float64(attempt-1)))
+	if d > c.RetryMaxDelay || d <= 0 {
+		d = c.RetryMaxDelay
+	}
+	return d - time.Duration(rand.Float64()*0.2*float64(d))
+}
+
+// Queue enqueues jobs into PostgreSQL and runs them on a pool.Pool. T is
+// the pool's result type; handlers do not produce results.
+type Queue[T any] struct {
+	db     *pgxpool.Pool
+	claims dbtx // db, or a fake in tests
+	pool   *pool.Pool[T]
+	cfg    Config
+
+	mu       sync.Mutex
+	handlers map[string]Handler
+	inflight int
+	started  bool
+	stopped  bool
+
+	// saturated is set when the pool turns a claimed job away, and holds
+	// off claiming until one of ours finishes or PollInterval passes.
+	saturated bool
+
+	ctx     context.Context // cancelled by Stop; bounds claims and LISTEN
+	cancel  context.CancelFunc
+	wake    chan struct{}
+	done    chan struct{} // closed when the fetch loop exits
+	workers sync.WaitGroup
+	jobs    sync.WaitGroup // claimed jobs not yet recorded
+}
+
+// New creates a Queue on db, typically from sql.NewPostgresDB, that runs
+// jobs on p. Call Migrate once before using it; p must be started
+// separately.
+func New[T any](db *pgxpool.Pool, p *pool.Pool[T], cfg Config) *Queue[T] {
+	cfg.applyDefaults()
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Queue[T]{
+		db:       db,
+		claims:   db,
+		pool:     p,
+		cfg:      cfg,
+		handlers: make(map[string]Handler),
+		ctx:      ctx,
+		cancel:   cancel,
+		wake:     make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+}
+
+// Register sets the handler for jobs of kind, replacing any previous one.
+func (q *Queue[T]) Register(kind string, h Handler) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.handlers[kind] = h
+}
+
+// dbtx is the part of *pgxpool.Pool the fetch loop uses to claim jobs and
+// record their outcome.
+type dbtx interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// querier is satisfied by *pgxpool.Pool and pgx.Tx.
+type querier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// Enqueue adds a job of kind with payload marshalled to JSON, returning its
+// ID.
+func (q *Queue[T]) Enqueue(ctx context.Context, kind string, payload any, opts EnqueueOptions) (int64, error) {
+	return q.enqueue(ctx, q.db, kind, payload, opts)
+}
+
+// EnqueueTx adds a job inside tx, so it is only queued if tx commits.
+func (q *Queue[T]) EnqueueTx(ctx context.Context, tx pgx.Tx, kind string, payload any, opts EnqueueOptions) (int64, error) {
+	return q.enqueue(ctx, tx, kind, payload, opts)
+}
+
+func (q *Queue[T]) enqueue(ctx context.Context, db querier, kind string, payload any, opts EnqueueOptions) (int64, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return 0, fmt.Errorf("pgqueue: marshal %s payload: %w", kind, err)
+	}
+	maxAttempts := opts.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = q.cfg.MaxAttempts
+	}
+	var runAt any
+	if !opts.RunAt.IsZero() {
+		runAt = opts.RunAt
+	}
+
+	var id int64
+	err = db.QueryRow(ctx, enqueueSQL, q.cfg.Queue, kind, data, opts.UniqueKey, maxAttempts, runAt).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrDuplicate
+	}
+	if err != nil {
+		return 0, fmt.Errorf("pgqueue: enqueue %s: %w", kind, err)
+	}
+	return id, nil
+}
+
+// Start launches the fetch loop and the LISTEN connection. Calling Start
+// more than once is a no-op.
+func (q *Queue[T]) Start() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.started || q.stopped {
+		return
+	}
+	q.started = true
+	q.workers.Add(1)
+	go q.listen()
+	go q.loop()
+}
+
+// Stop stops claiming jobs and waits until every claimed job has finished
+// and its outcome is recorded. Stop the queue before the pool: jobs the
+// pool drops on Stop are released for another worker. Stop is safe to call
+// multiple times; a stopped Queue cannot be restarted.
+func (q *Queue[T]) Stop() {
+	q.mu.Lock()
+	if q.stopped {
+		q.mu.Unlock()
+		return
+	}
+	q.stopped = true
+	started := q.started
+	q.mu.Unlock()
+
+	q.cancel()
+	if started {
+		<-q.done
+		q.workers.Wait()
+	}
+	q.jobs.Wait()
+}
+
+// DeadJob is a job that exhausted its attempts or failed permanently.
+type DeadJob struct {
+	Job
+	LastError string
+	FailedAt  time.Time
+}
+
+// Dead returns up to limit dead-lettered jobs of this queue, most recent
+// first.
+func (q *Queue[T]) Dead(ctx context.Context, limit int) ([]DeadJob, error) {
+	rows, err := q.db.Query(ctx, deadSQL, q.cfg.Queue, limit)
+	if err != nil {
+		return nil, fmt.Errorf("pgqueue: list dead jobs: %w", err)
+	}
+	jobs, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (DeadJob, error) {
+		var d DeadJob
+		var payload []byte
+		var lastError *string
+		err := row.Scan(&d.ID, &d.Kind, &payload, &d.Attempt, &d.MaxAttempts, &lastError, &d.CreatedAt, &d.FailedAt)
+		d.Queue = q.cfg.Queue
+		d.Payload = payload
+		if lastError != nil {
+			d.LastError = *lastError
+		}
+		return d, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("pgqueue: list dead jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+// Requeue moves a dead job back into the queue with a fresh set of
+// attempts. It returns ErrJobNotFound if the job is not dead-lettered in
+// this queue, and ErrDuplicate if its unique key has been reused since.
+func (q *Queue[T]) Requeue(ctx context.Context, id int64) error {
+	var requeued int64
+	err := q.db.QueryRow(ctx, requeueSQL, id, q.cfg.Queue).Scan(&requeued)
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return ErrJobNotFound
+	case errors.As(err, &pgErr) && pgErr.Code == "23505":
+		return ErrDuplicate
+	case err != nil:
+		return fmt.Errorf("pgqueue: requeue job %d: %w", id, err)
+	}
+	return nil
+}
+
+// Stats is a point-in-time count of this queue's jobs.
+type Stats struct {
+	Ready     int64 // due and waiting for a worker
+	Scheduled int64 // waiting for their run_at or a retry
+	Running   int64 // claimed by a worker
+	Dead      int64 // dead-lettered
+}
+
+// Stats counts the queue's jobs by state.
+func (q *Queue[T]) Stats(ctx context.Context) (Stats, error) {
+	var s Stats
+	err := q.db.QueryRow(ctx, statsSQL, q.cfg.Queue).Scan(&s.Ready, &s.Scheduled, &s.Running, &s.Dead)
+	if err != nil {
+		return Stats{}, fmt.Errorf("pgqueue: stats: %w", err)
+	}
+	return s, nil
+}
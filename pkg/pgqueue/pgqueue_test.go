@@ -0,0 +1,401 @@
+package pgqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/vietpham102301/lightway/pkg/pool"
+)
+
+// ===========================================================================
+// Config
+// ===========================================================================
+
+func TestConfig_Defaults(t *testing.T) {
+	cfg := Config{}
+	cfg.applyDefaults()
+
+	if cfg.Queue != "default" {
+		t.Errorf("expected Queue %q, got %q", "default", cfg.Queue)
+	}
+	if cfg.Concurrency != 10 {
+		t.Errorf("expected Concurrency 10, got %d", cfg.Concurrency)
+	}
+	if cfg.PollInterval != time.Second {
+		t.Errorf("expected PollInterval 1s, got %v", cfg.PollInterval)
+	}
+	if cfg.VisibilityTimeout != 5*time.Minute {
+		t.Errorf("expected VisibilityTimeout 5m, got %v", cfg.VisibilityTimeout)
+	}
+	if cfg.MaxAttempts != 5 {
+		t.Errorf("expected MaxAttempts 5, got %d", cfg.MaxAttempts)
+	}
+	if cfg.RetryBaseDelay != time.Second {
+		t.Errorf("expected RetryBaseDelay 1s, got %v", cfg.RetryBaseDelay)
+	}
+	if cfg.RetryMaxDelay != time.Hour {
+		t.Errorf("expected RetryMaxDelay 1h, got %v", cfg.RetryMaxDelay)
+	}
+	if cfg.OnError == nil {
+		t.Error("expected a default OnError")
+	}
+}
+
+func TestConfig_Backoff(t *testing.T) {
+	cfg := Config{RetryBaseDelay: time.Second, RetryMaxDelay: 10 * time.Second}
+	cfg.applyDefaults()
+
+	cases := []struct {
+		attempt int
+		max     time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 8 * time.Second},
+		{5, 10 * time.Second},
+		{100, 10 * time.Second},
+	}
+	for _, tc := range cases {
+		for range 20 {
+			d := cfg.backoff(tc.attempt)
+			if min := tc.max * 8 / 10; d < min || d > tc.max {
+				t.Fatalf("attempt %d: expected backoff in [%v, %v], got %v", tc.attempt, min, tc.max, d)
+			}
+		}
+	}
+}
+
+// ===========================================================================
+// Errors
+// ===========================================================================
+
+func TestPermanent(t *testing.T) {
+	if Permanent(nil) != nil {
+		t.Fatal("expected Permanent(nil) to be nil")
+	}
+
+	errBoom := errors.New("boom")
+	err := fmt.Errorf("send: %w", Permanent(errBoom))
+	if !errors.Is(err, errBoom) {
+		t.Error("expected Permanent to wrap the original error")
+	}
+	var permanent permanentError
+	if !errors.As(err, &permanent) {
+		t.Error("expected a wrapped Permanent error to be detected")
+	}
+	if err.Error() != "send: boom" {
+		t.Errorf("expected message %q, got %q", "send: boom", err.Error())
+	}
+}
+
+// ===========================================================================
+// Job
+// ===========================================================================
+
+func TestJob_Decode(t *testing.T) {
+	job := &Job{Kind: "email", Payload: []byte(`{"to":"a@b.com"}`)}
+	var p struct {
+		To string `json:"to"`
+	}
+	if err := job.Decode(&p); err != nil {
+		t.Fatalf("Decode: unexpected error: %v", err)
+	}
+	if p.To != "a@b.com" {
+		t.Errorf("expected to %q, got %q", "a@b.com", p.To)
+	}
+
+	job.Payload = []byte(`not json`)
+	if err := job.Decode(&p); err == nil {
+		t.Error("expected an error for an invalid payload")
+	}
+}
+
+func TestQueueJob_Execute(t *testing.T) {
+	q := New[int](nil, nil, Config{VisibilityTimeout: time.Minute})
+	var got *Job
+	q.Register("email", func(_ context.Context, job *Job) error {
+		got = job
+		return nil
+	})
+
+	job := &Job{ID: 7, Kind: "email"}
+	qj := &queueJob[int]{q: q, job: job, deadline: time.Now().Add(time.Minute)}
+	if _, err := qj.Execute(context.Background()); err != nil {
+		t.Fatalf("Execute: unexpected error: %v", err)
+	}
+	if got != job {
+		t.Error("expected the handler to receive the claimed job")
+	}
+
+	unknown := &queueJob[int]{q: q, job: &Job{Kind: "sms"}, deadline: qj.deadline}
+	if _, err := unknown.Execute(context.Background()); !errors.Is(err, ErrNoHandler) {
+		t.Errorf("expected ErrNoHandler, got %v", err)
+	}
+
+	var _ pool.Timeouter = qj
+	var _ pool.Retrier = qj
+	if d := qj.Timeout(); d <= 0 || d > time.Minute {
+		t.Errorf("expected the rest of the claim as job timeout, got %v", d)
+	}
+	if qj.RetryPolicy() != nil {
+		t.Error("expected pool retries to be disabled")
+	}
+}
+
+func TestQueueJob_ClaimExpiredWhileQueued(t *testing.T) {
+	q := New[struct{}](nil, nil, Config{})
+	var ran atomic.Bool
+	q.Register("email", func(context.Context, *Job) error {
+		ran.Store(true)
+		return nil
+	})
+
+	// One busy worker: the claimed job waits in the pool's queue past the
+	// end of its claim.
+	p := pool.New[struct{}](pool.Config{MinWorkers: 1, MaxWorkers: 1, QueueSize: 10})
+	p.Start()
+	defer p.Stop()
+	release := make(chan struct{})
+	p.Submit(pool.JobFunc[struct{}](func(context.Context) (struct{}, error) { //nolint
+		<-release
+		return struct{}{}, nil
+	}))
+
+	qj := &queueJob[struct{}]{q: q, job: &Job{ID: 1, Kind: "email"}, deadline: time.Now().Add(50 * time.Millisecond)}
+	ch, err := p.Submit(qj)
+	if err != nil {
+		t.Fatalf("Submit: unexpected error: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+
+	res := <-ch
+	if !errors.Is(res.Err, errClaimExpired) {
+		t.Errorf("expected errClaimExpired, got %v", res.Err)
+	}
+	if ran.Load() {
+		t.Error("expected the handler not to run after its claim expired")
+	}
+}
+
+func TestQueueJob_TimeoutCountsQueuedTime(t *testing.T) {
+	q := New[struct{}](nil, nil, Config{})
+	deadline := make(chan time.Time, 1)
+	q.Register("email", func(ctx context.Context, _ *Job) error {
+		d, _ := ctx.Deadline()
+		deadline <- d
+		return nil
+	})
+
+	p := pool.New[struct{}](pool.Config{MinWorkers: 1, MaxWorkers: 1, QueueSize: 10})
+	p.Start()
+	defer p.Stop()
+	release := make(chan struct{})
+	p.Submit(pool.JobFunc[struct{}](func(context.Context) (struct{}, error) { //nolint
+		<-release
+		return struct{}{}, nil
+	}))
+
+	claimEnd := time.Now().Add(time.Second)
+	qj := &queueJob[struct{}]{q: q, job: &Job{ID: 1, Kind: "email"}, deadline: claimEnd}
+	ch, _ := p.Submit(qj)
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+
+	if res := <-ch; res.Err != nil {
+		t.Fatalf("unexpected error: %v", res.Err)
+	}
+	if d := <-deadline; d.After(claimEnd.Add(10 * time.Millisecond)) {
+		t.Errorf("expected the handler deadline by the end of the claim %v, got %v", claimEnd, d)
+	}
+}
+
+// ===========================================================================
+// Fetch
+// ===========================================================================
+
+// fakeDB stands in for PostgreSQL in the fetch loop. Every claim returns up
+// to the requested number of fresh jobs.
+type fakeDB struct {
+	mu       sync.Mutex
+	claims   int
+	released int
+	nextID   int64
+	onClaim  func()
+}
+
+func (db *fakeDB) Query(_ context.Context, sql string, args ...any) (pgx.Rows, error) {
+	if sql != claimSQL {
+		return nil, fmt.Errorf("unexpected query %q", sql)
+	}
+	db.mu.Lock()
+	db.claims++
+	var jobs []*Job
+	for range args[1].(int) {
+		db.nextID++
+		jobs = append(jobs, &Job{ID: db.nextID, Kind: "email", Attempt: 1, MaxAttempts: 5})
+	}
+	onClaim := db.onClaim
+	db.mu.Unlock()
+	if onClaim != nil {
+		onClaim()
+	}
+	return &fakeRows{jobs: jobs}, nil
+}
+
+func (db *fakeDB) Exec(_ context.Context, sql string, _ ...any) (pgconn.CommandTag, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if sql == releaseSQL {
+		db.released++
+	}
+	return pgconn.CommandTag{}, nil
+}
+
+func (db *fakeDB) counts() (claims, released int) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.claims, db.released
+}
+
+// fakeRows yields claimed jobs in the column order of claimSQL.
+type fakeRows struct {
+	jobs []*Job
+	i    int
+}
+
+func (r *fakeRows) Close()                                       {}
+func (r *fakeRows) Err() error                                   { return nil }
+func (r *fakeRows) CommandTag() pgconn.CommandTag                { return pgconn.CommandTag{} }
+func (r *fakeRows) FieldDescriptions() []pgconn.FieldDescription { return nil }
+func (r *fakeRows) Values() ([]any, error)                       { return nil, nil }
+func (r *fakeRows) RawValues() [][]byte                          { return nil }
+func (r *fakeRows) Conn() *pgx.Conn                              { return nil }
+
+func (r *fakeRows) Next() bool {
+	r.i++
+	return r.i <= len(r.jobs)
+}
+
+func (r *fakeRows) Scan(dest ...any) error {
+	j := r.jobs[r.i-1]
+	*dest[0].(*int64) = j.ID
+	*dest[1].(*string) = j.Kind
+	*dest[3].(*int) = j.Attempt
+	*dest[4].(*int) = j.MaxAttempts
+	return nil
+}
+
+// newFetchQueue returns a queue on a fake database and a pool with one
+// worker, blocked until the returned release is called.
+func newFetchQueue(t *testing.T, queueSize int) (*Queue[struct{}], *fakeDB, *pool.Pool[struct{}], func()) {
+	t.Helper()
+	p := pool.New[struct{}](pool.Config{MinWorkers: 1, MaxWorkers: 1, QueueSize: queueSize})
+	p.Start()
+	t.Cleanup(p.Stop)
+	gate := make(chan struct{})
+	p.Submit(pool.JobFunc[struct{}](func(context.Context) (struct{}, error) { //nolint
+		<-gate
+		return struct{}{}, nil
+	}))
+	for p.Stats().QueueDepth > 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	db := &fakeDB{}
+	q := New[struct{}](nil, p, Config{PollInterval: time.Hour, VisibilityTimeout: time.Minute})
+	q.claims = db
+	q.Register("email", func(context.Context, *Job) error { return nil })
+	t.Cleanup(q.Stop)
+	var once sync.Once
+	return q, db, p, func() { once.Do(func() { close(gate) }) }
+}
+
+func TestFetch_ClaimsNoMoreThanPoolRoom(t *testing.T) {
+	q, db, p, release := newFetchQueue(t, 2)
+	defer release()
+
+	q.fetch()
+	if claims, _ := db.counts(); claims != 1 {
+		t.Fatalf("expected 1 claim, got %d", claims)
+	}
+	if d := p.Stats().QueueDepth; d != 2 {
+		t.Fatalf("expected the claim sized to the 2 free queue slots, got %d queued", d)
+	}
+
+	q.fetch()
+	if claims, released := db.counts(); claims != 1 || released != 0 {
+		t.Fatalf("expected no claim while the pool is full, got %d claims, %d released", claims, released)
+	}
+}
+
+func TestFetch_NoClaimLoopWhilePoolFull(t *testing.T) {
+	q, db, p, release := newFetchQueue(t, 1)
+	defer release()
+
+	// Another submitter fills the pool's queue between our claim and submit.
+	var once sync.Once
+	db.onClaim = func() {
+		once.Do(func() {
+			p.Submit(pool.JobFunc[struct{}](func(context.Context) (struct{}, error) { //nolint
+				return struct{}{}, nil
+			}))
+		})
+	}
+
+	q.mu.Lock()
+	q.started = true
+	q.mu.Unlock()
+	go q.loop()
+	time.Sleep(50 * time.Millisecond)
+	for range 10 {
+		q.notify()
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	claims, released := db.counts()
+	if claims != 1 || released != 1 {
+		t.Fatalf("expected 1 claim handed back once, got %d claims, %d released", claims, released)
+	}
+
+	// One of our jobs finishing lifts the hold.
+	release()
+	waitFor(t, func() bool { return p.Stats().QueueDepth == 0 })
+	q.jobs.Add(1)
+	q.mu.Lock()
+	q.inflight++
+	q.mu.Unlock()
+	q.report(&Job{ID: 99}, nil)
+	waitFor(t, func() bool { c, _ := db.counts(); return c > 1 })
+}
+
+// ===========================================================================
+// Migrations
+// ===========================================================================
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		t.Fatalf("loadMigrations: unexpected error: %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("expected at least one migration")
+	}
+	for i, m := range migrations {
+		if m.version != i+1 {
+			t.Errorf("expected migration %d to have version %d, got %d (%s)", i, i+1, m.version, m.name)
+		}
+		if m.sql == "" {
+			t.Errorf("%s: expected SQL", m.name)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package pgqueue
+
+// notifyChannel is the LISTEN/NOTIFY channel the pgqueue_jobs insert
+// trigger notifies, with the queue name as payload.
+const notifyChannel = "pgqueue_jobs"
+
+// enqueueSQL inserts a job; with a unique key already in use it inserts
+// nothing and returns no row.
+//
+// $1 queue, $2 kind, $3 payload, $4 unique key (empty for none),
+// $5 max attempts, $6 run_at (NULL for now)
+const enqueueSQL = `
+INSERT INTO pgqueue_jobs (queue, kind, payload, unique_key, max_attempts, run_at)
+VALUES ($1, $2, $3, NULLIF($4::text, ''), $5, COALESCE($6::timestamptz, now()))
+ON CONFLICT (queue, unique_key) WHERE unique_key IS NOT NULL DO NOTHING
+RETURNING id`
+
+// claimSQL claims up to $2 due jobs of queue $1 for $3 milliseconds. Rows
+// locked by other workers' claims are skipped rather than waited on.
+// attempts doubles as a fencing token: a worker may only record the
+// outcome of the attempt it claimed.
+const claimSQL = `
+UPDATE pgqueue_jobs
+SET attempts = attempts + 1,
+    locked_until = now() + $3::bigint * interval '1 millisecond'
+WHERE id IN (
+    SELECT id FROM pgqueue_jobs
+    WHERE queue = $1
+      AND run_at <= now()
+      AND (locked_until IS NULL OR locked_until <= now())
+      AND attempts < max_attempts
+    ORDER BY run_at, id
+    LIMIT $2
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, kind, payload, attempts, max_attempts, run_at, created_at`
+
+// completeSQL deletes a finished job. $1 id, $2 claimed attempt.
+const completeSQL = `DELETE FROM pgqueue_jobs WHERE id = $1 AND attempts = $2`
+
+// retrySQL schedules a failed job's next attempt.
+//
+// $1 id, $2 claimed attempt, $3 delay in milliseconds, $4 error
+const retrySQL = `
+UPDATE pgqueue_jobs
+SET run_at = now() + $3::bigint * interval '1 millisecond',
+    locked_until = NULL,
+    last_error = $4::text
+WHERE id = $1 AND attempts = $2`
+
+// releaseSQL hands back a claim that never ran, without using up an
+// attempt. $1 id, $2 claimed attempt.
+const releaseSQL = `
+UPDATE pgqueue_jobs
+SET attempts = attempts - 1,
+    locked_until = NULL
+WHERE id = $1 AND attempts = $2`
+
+// buryColumns are copied from pgqueue_jobs into pgqueue_dead_jobs.
+const buryColumns = `id, queue, kind, payload, unique_key, attempts, max_attempts, created_at`
+
+// burySQL moves a failed job to the dead-letter table.
+//
+// $1 id, $2 claimed attempt, $3 error
+const burySQL = `
+WITH dead AS (
+    DELETE FROM pgqueue_jobs WHERE id = $1 AND attempts = $2
+    RETURNING ` + buryColumns + `
+)
+INSERT INTO pgqueue_dead_jobs (` + buryColumns + `, last_error)
+SELECT ` + buryColumns + `, $3::text FROM dead`
+
+// buryExpiredSQL dead-letters jobs of queue $1 whose last attempt's claim
+// expired, meaning the worker running it died.
+const buryExpiredSQL = `
+WITH dead AS (
+    DELETE FROM pgqueue_jobs
+    WHERE id IN (
+        SELECT id FROM pgqueue_jobs
+        WHERE queue = $1
+          AND attempts >= max_attempts
+          AND locked_until <= now()
+        FOR UPDATE SKIP LOCKED
+    )
+    RETURNING ` + buryColumns + `
+)
+INSERT INTO pgqueue_dead_jobs (` + buryColumns + `, last_error)
+SELECT ` + buryColumns + `, 'pgqueue: visibility timeout expired' FROM dead`
+
+// deadSQL lists dead jobs of queue $1, newest first, up to $2.
+const deadSQL = `
+SELECT id, kind, payload, attempts, max_attempts, last_error, created_at, failed_at
+FROM pgqueue_dead_jobs
+WHERE queue = $1
+ORDER BY failed_at DESC, id DESC
+LIMIT $2`
+
+// requeueSQL moves dead job $1 of queue $2 back into the queue.
+const requeueSQL = `
+WITH dead AS (
+    DELETE FROM pgqueue_dead_jobs WHERE id = $1 AND queue = $2
+    RETURNING id, queue, kind, payload, unique_key, max_attempts
+)
+INSERT INTO pgqueue_jobs (id, queue, kind, payload, unique_key, max_attempts)
+SELECT id, queue, kind, payload, unique_key, max_attempts FROM dead
+RETURNING id`
+
+// statsSQL counts jobs of queue $1 by state.
+const statsSQL = `
+SELECT
+    count(*) FILTER (WHERE run_at <= now() AND (locked_until IS NULL OR locked_until <= now())),
+    count(*) FILTER (WHERE run_at > now() AND (locked_until IS NULL OR locked_until <= now())),
+    count(*) FILTER (WHERE locked_until > now()),
+    (SELECT count(*) FROM pgqueue_dead_jobs WHERE queue = $1)
+FROM pgqueue_jobs
+WHERE queue = $1`
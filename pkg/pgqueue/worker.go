@@ -0,0 +1,245 @@
+package pgqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/vietpham102301/lightway/pkg/pool"
+)
+
+// reportTimeout bounds recording a job's outcome.
+const reportTimeout = 10 * time.Second
+
+// loop claims jobs whenever LISTEN wakes it or PollInterval passes, until
+// Stop.
+func (q *Queue[T]) loop() {
+	defer close(q.done)
+	ticker := time.NewTicker(q.cfg.PollInterval)
+	defer ticker.Stop()
+
+	q.buryExpired()
+	q.fetch()
+	for {
+		select {
+		case <-q.ctx.Done():
+			return
+		case <-q.wake:
+		case <-ticker.C:
+			q.mu.Lock()
+			q.saturated = false
+			q.mu.Unlock()
+			q.buryExpired()
+		}
+		q.fetch()
+	}
+}
+
+// notify wakes the fetch loop.
+func (q *Queue[T]) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// fetch claims as many due jobs as there is room for, both under
+// Concurrency and in the pool's queue, and submits them to the pool.
+func (q *Queue[T]) fetch() {
+	q.mu.Lock()
+	room := q.cfg.Concurrency - q.inflight
+	saturated := q.saturated
+	q.mu.Unlock()
+	if saturated {
+		return
+	}
+	if s := q.pool.Stats(); s.QueueCapacity-s.QueueDepth < room {
+		room = s.QueueCapacity - s.QueueDepth
+	}
+	if room <= 0 {
+		return
+	}
+
+	// Taken before the claim, so the claim lasts at least until deadline
+	// whatever the clock difference with the database.
+	deadline := time.Now().Add(q.cfg.VisibilityTimeout)
+	rows, err := q.claims.Query(q.ctx, claimSQL, q.cfg.Queue, room, q.cfg.VisibilityTimeout.Milliseconds())
+	if err != nil {
+		q.queueError(fmt.Errorf("pgqueue: claim jobs: %w", err))
+		return
+	}
+	jobs, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*Job, error) {
+		j := &Job{Queue: q.cfg.Queue}
+		var payload []byte
+		err := row.Scan(&j.ID, &j.Kind, &payload, &j.Attempt, &j.MaxAttempts, &j.RunAt, &j.CreatedAt)
+		j.Payload = payload
+		return j, err
+	})
+	if err != nil {
+		q.queueError(fmt.Errorf("pgqueue: claim jobs: %w", err))
+		return
+	}
+
+	for _, job := range jobs {
+		q.mu.Lock()
+		q.inflight++
+		q.mu.Unlock()
+		q.jobs.Add(1)
+
+		err := q.pool.SubmitOnComplete(context.Background(), &queueJob[T]{q: q, job: job, deadline: deadline}, func(res pool.Result[T]) {
+			q.report(job, res.Err)
+		})
+		if err != nil {
+			// The pool is full or stopped: report hands the claim back.
+			q.report(job, err)
+		}
+	}
+}
+
+// report records a finished attempt and frees its slot. A job the pool
+// turned away holds off further claims rather than waking the fetch loop,
+// which would only claim and release it again.
+func (q *Queue[T]) report(job *Job, err error) {
+	defer q.jobs.Done()
+	rejected := errors.Is(err, pool.ErrQueueFull) || errors.Is(err, pool.ErrPoolStopped) ||
+		errors.Is(err, pool.ErrJobEvicted)
+	defer func() {
+		q.mu.Lock()
+		q.inflight--
+		q.saturated = rejected
+		q.mu.Unlock()
+		if !rejected {
+			q.notify()
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), reportTimeout)
+	defer cancel()
+
+	var dbErr error
+	var permanent permanentError
+	switch {
+	case err == nil:
+		_, dbErr = q.claims.Exec(ctx, completeSQL, job.ID, job.Attempt)
+	case rejected || errors.Is(err, errClaimExpired):
+		_, dbErr = q.claims.Exec(ctx, releaseSQL, job.ID, job.Attempt)
+	case errors.As(err, &permanent) || job.Attempt >= job.MaxAttempts:
+		q.cfg.OnError(job, err)
+		_, dbErr = q.claims.Exec(ctx, burySQL, job.ID, job.Attempt, err.Error())
+	default:
+		q.cfg.OnError(job, err)
+		delay := q.cfg.backoff(job.Attempt)
+		_, dbErr = q.claims.Exec(ctx, retrySQL, job.ID, job.Attempt, delay.Milliseconds(), err.Error())
+	}
+	if dbErr != nil {
+		// The claim expires on its own and the job runs again.
+		q.cfg.OnError(nil, fmt.Errorf("pgqueue: record job %d: %w", job.ID, dbErr))
+	}
+}
+
+// buryExpired dead-letters jobs whose final attempt was lost with its
+// worker.
+func (q *Queue[T]) buryExpired() {
+	if _, err := q.claims.Exec(q.ctx, buryExpiredSQL, q.cfg.Queue); err != nil {
+		q.queueError(fmt.Errorf("pgqueue: bury expired jobs: %w", err))
+	}
+}
+
+// listen holds a connection LISTENing for new jobs, reconnecting after
+// PollInterval on failure. Polling keeps the queue working meanwhile.
+func (q *Queue[T]) listen() {
+	defer q.workers.Done()
+	for {
+		err := q.listenOnce()
+		if q.ctx.Err() != nil {
+			return
+		}
+		q.queueError(fmt.Errorf("pgqueue: listen: %w", err))
+
+		select {
+		case <-q.ctx.Done():
+			return
+		case <-time.After(q.cfg.PollInterval):
+		}
+	}
+}
+
+func (q *Queue[T]) listenOnce() error {
+	conn, err := q.db.Acquire(q.ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+	defer func() {
+		if !conn.Conn().IsClosed() {
+			// Don't hand a LISTENing connection back to the pool.
+			ctx, cancel := context.WithTimeout(context.Background(), reportTimeout)
+			defer cancel()
+			conn.Exec(ctx, "UNLISTEN *")
+		}
+	}()
+
+	if _, err := conn.Exec(q.ctx, "LISTEN "+notifyChannel); err != nil {
+		return err
+	}
+	// Catch up on anything enqueued before LISTEN took effect.
+	q.notify()
+	for {
+		n, err := conn.Conn().WaitForNotification(q.ctx)
+		if err != nil {
+			return err
+		}
+		if n.Payload == q.cfg.Queue {
+			q.notify()
+		}
+	}
+}
+
+func (q *Queue[T]) queueError(err error) {
+	if q.ctx.Err() != nil {
+		// Stopping cancels in-flight queries; those errors are expected.
+		return
+	}
+	q.cfg.OnError(nil, err)
+}
+
+// errClaimExpired fails a job whose claim ran out before a pool worker
+// picked it up; another worker may already be running it.
+var errClaimExpired = errors.New("pgqueue: claim expired before the job started")
+
+// queueJob adapts a claimed Job to pool.Job.
+type queueJob[T any] struct {
+	q   *Queue[T]
+	job *Job
+
+	// deadline is when the claim runs out.
+	deadline time.Time
+}
+
+// Execute runs the job's handler, unless its claim already ran out while it
+// waited in the pool's queue.
+func (j *queueJob[T]) Execute(ctx context.Context) (T, error) {
+	var zero T
+	if !time.Now().Before(j.deadline) {
+		return zero, errClaimExpired
+	}
+	j.q.mu.Lock()
+	h, ok := j.q.handlers[j.job.Kind]
+	j.q.mu.Unlock()
+	if !ok {
+		return zero, fmt.Errorf("%w: %q", ErrNoHandler, j.job.Kind)
+	}
+	return zero, h(ctx, j.job)
+}
+
+// Timeout ends the handler's context when the claim runs out, since another
+// worker may pick the job up after that. The pool asks for it when the job
+// starts, so time spent queued in the pool counts against the claim.
+func (j *queueJob[T]) Timeout() time.Duration { return time.Until(j.deadline) }
+
+// RetryPolicy disables the pool's own retries; failed jobs are retried
+// through the table with backoff instead.
+func (j *queueJob[T]) RetryPolicy() *pool.RetryPolicy { return nil }
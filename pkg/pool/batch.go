@@ -0,0 +1,183 @@
+package pool
+
+import (
+	"context"
+	"sync"
+)
+
+// JobFunc adapts an ordinary function to the Job interface.
+type JobFunc[T any] func(ctx context.Context) (T, error)
+
+// Execute calls f(ctx).
+func (f JobFunc[T]) Execute(ctx context.Context) (T, error) { return f(ctx) }
+
+// SubmitAll submits every job, waiting for queue space as SubmitWait does,
+// and waits for all of them to finish. results[i] is the outcome of jobs[i].
+// The error is the first non-nil Result.Err in input order; jobs that could
+// not be submitted carry the submission error. SubmitAll never cancels
+// siblings — use Group or Map for fail-fast behaviour.
+func (p *Pool[T]) SubmitAll(ctx context.Context, jobs []Job[T]) ([]Result[T], error) {
+	chans := make([]<-chan Result[T], len(jobs))
+	for i, job := range jobs {
+		ch, err := p.SubmitWait(ctx, job)
+		if err != nil {
+			ch = failedResult[T](err)
+		}
+		chans[i] = ch
+	}
+
+	results := make([]Result[T], len(jobs))
+	var firstErr error
+	for i, ch := range chans {
+		results[i] = <-ch
+		if firstErr == nil && results[i].Err != nil {
+			firstErr = results[i].Err
+		}
+	}
+	return results, firstErr
+}
+
+// Stream submits jobs as they arrive and emits their results in input
+// order, keeping at most Config.QueueSize jobs in flight. The returned
+// channel is closed once jobs is closed and every result has been emitted,
+// or as soon as ctx ends. Jobs that cannot be submitted produce a Result
+// carrying the submission error.
+func (p *Pool[T]) Stream(ctx context.Context, jobs <-chan Job[T]) <-chan Result[T] {
+	pending := make(chan (<-chan Result[T]), p.cfg.QueueSize)
+	out := make(chan Result[T])
+
+	go func() {
+		defer close(pending)
+		for {
+			var job Job[T]
+			select {
+			case <-ctx.Done():
+				return
+			case j, ok := <-jobs:
+				if !ok {
+					return
+				}
+				job = j
+			}
+
+			ch, err := p.SubmitWait(ctx, job)
+			if err != nil {
+				ch = failedResult[T](err)
+			}
+			select {
+			case pending <- ch:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	go func() {
+		defer close(out)
+		for ch := range pending {
+			var res Result[T]
+			select {
+			case res = <-ch:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case out <- res:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// failedResult returns a closed channel holding a single failed Result.
+func failedResult[T any](err error) <-chan Result[T] {
+	ch := make(chan Result[T], 1)
+	ch <- Result[T]{Err: err}
+	close(ch)
+	return ch
+}
+
+// Group runs related jobs on a pool with errgroup-style semantics: the
+// first job to fail cancels the group's context, which cancels its running
+// siblings and skips its queued ones.
+//
+// Go blocks while the pool's queue is full, so do not call it from a job
+// running on the same pool.
+type Group[T any] struct {
+	pool   *Pool[T]
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	results []Result[T]
+	err     error
+}
+
+// NewGroup returns a Group submitting to p and the derived context its jobs
+// run under, which is cancelled when a job fails or Wait returns.
+func NewGroup[T any](ctx context.Context, p *Pool[T]) (*Group[T], context.Context) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	return &Group[T]{pool: p, ctx: ctx, cancel: cancel}, ctx
+}
+
+// Go submits job to the group. Submission failures count as job failures.
+func (g *Group[T]) Go(job Job[T]) {
+	g.mu.Lock()
+	i := len(g.results)
+	g.results = append(g.results, Result[T]{})
+	g.mu.Unlock()
+
+	g.wg.Add(1)
+	done := func(res Result[T]) {
+		g.mu.Lock()
+		g.results[i] = res
+		if res.Err != nil && g.err == nil {
+			g.err = res.Err
+			g.cancel(res.Err)
+		}
+		g.mu.Unlock()
+		g.wg.Done()
+	}
+
+	env := g.pool.newEnvelope(g.ctx, job, done, nil)
+	err := g.pool.checkSubmit(env)
+	if err == nil {
+		err = g.pool.submitWait(env, g.ctx)
+	}
+	if err != nil {
+		done(Result[T]{Err: err})
+	}
+}
+
+// Wait blocks until every job submitted with Go has finished and returns
+// their values in submission order, along with the first error any of them
+// returned.
+func (g *Group[T]) Wait() ([]T, error) {
+	g.wg.Wait()
+	g.cancel(context.Canceled)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	values := make([]T, len(g.results))
+	for i, res := range g.results {
+		values[i] = res.Value
+	}
+	return values, g.err
+}
+
+// Map runs fn over items on p and returns the outputs in input order. It
+// fails fast like Group: the first error cancels the remaining calls and is
+// returned.
+func Map[T, R any](ctx context.Context, p *Pool[R], items []T, fn func(ctx context.Context, item T) (R, error)) ([]R, error) {
+	g, _ := NewGroup(ctx, p)
+	for _, item := range items {
+		g.Go(JobFunc[R](func(ctx context.Context) (R, error) {
+			return fn(ctx, item)
+		}))
+	}
+	return g.Wait()
+}
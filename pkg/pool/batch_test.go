@@ -0,0 +1,229 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// ===========================================================================
+// SubmitAll
+// ===========================================================================
+
+func TestSubmitAll_ResultsInInputOrder(t *testing.T) {
+	p := New[int](fastCfg())
+	p.Start()
+	defer p.Stop()
+
+	jobs := make([]Job[int], 20)
+	for i := range jobs {
+		// Later jobs finish first.
+		delay := time.Duration(len(jobs)-i) * time.Millisecond
+		jobs[i] = JobFunc[int](func(context.Context) (int, error) {
+			time.Sleep(delay)
+			return i, nil
+		})
+	}
+
+	results, err := p.SubmitAll(context.Background(), jobs)
+	if err != nil {
+		t.Fatalf("SubmitAll: unexpected error: %v", err)
+	}
+	for i, res := range results {
+		if res.Value != i {
+			t.Fatalf("expected results[%d].Value = %d, got %d", i, i, res.Value)
+		}
+	}
+}
+
+func TestSubmitAll_FirstErrorInInputOrder(t *testing.T) {
+	p := New[int](fastCfg())
+	p.Start()
+	defer p.Stop()
+
+	errA, errB := errors.New("a"), errors.New("b")
+	jobs := []Job[int]{
+		successJob{val: 1},
+		JobFunc[int](func(context.Context) (int, error) {
+			time.Sleep(20 * time.Millisecond)
+			return 0, errA
+		}),
+		errorJob{err: errB},
+	}
+
+	results, err := p.SubmitAll(context.Background(), jobs)
+	if !errors.Is(err, errA) {
+		t.Fatalf("expected first error in input order (a), got %v", err)
+	}
+	if results[0].Value != 1 || !errors.Is(results[2].Err, errB) {
+		t.Fatalf("expected all results to be collected, got %+v", results)
+	}
+}
+
+func TestSubmitAll_StoppedPool(t *testing.T) {
+	p := New[int](fastCfg())
+	results, err := p.SubmitAll(context.Background(), []Job[int]{successJob{}, successJob{}})
+	if !errors.Is(err, ErrPoolStopped) {
+		t.Fatalf("expected ErrPoolStopped, got %v", err)
+	}
+	if len(results) != 2 || !errors.Is(results[1].Err, ErrPoolStopped) {
+		t.Fatalf("expected every result to carry ErrPoolStopped, got %+v", results)
+	}
+}
+
+// ===========================================================================
+// Group / Map
+// ===========================================================================
+
+func TestGroup_WaitReturnsValuesInOrder(t *testing.T) {
+	p := New[int](fastCfg())
+	p.Start()
+	defer p.Stop()
+
+	g, _ := NewGroup(context.Background(), p)
+	for i := range 5 {
+		g.Go(successJob{val: i * 10})
+	}
+	values, err := g.Wait()
+	if err != nil {
+		t.Fatalf("Wait: unexpected error: %v", err)
+	}
+	for i, v := range values {
+		if v != i*10 {
+			t.Fatalf("expected values[%d] = %d, got %d", i, i*10, v)
+		}
+	}
+}
+
+func TestGroup_FailFastCancelsSiblings(t *testing.T) {
+	p := New[int](fastCfg())
+	p.Start()
+	defer p.Stop()
+
+	boom := errors.New("boom")
+	g, ctx := NewGroup(context.Background(), p)
+	g.Go(slowJob{})
+	g.Go(slowJob{})
+	g.Go(JobFunc[int](func(context.Context) (int, error) {
+		time.Sleep(10 * time.Millisecond)
+		return 0, boom
+	}))
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := g.Wait()
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, boom) {
+			t.Fatalf("expected boom, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the failure to cancel the blocked siblings")
+	}
+	if !errors.Is(context.Cause(ctx), boom) {
+		t.Fatalf("expected group context cause boom, got %v", context.Cause(ctx))
+	}
+}
+
+func TestMap_OrderedOutputs(t *testing.T) {
+	p := New[string](fastCfg())
+	p.Start()
+	defer p.Stop()
+
+	out, err := Map(context.Background(), p, []int{3, 1, 2}, func(_ context.Context, n int) (string, error) {
+		time.Sleep(time.Duration(n) * time.Millisecond)
+		return string(rune('a' + n)), nil
+	})
+	if err != nil {
+		t.Fatalf("Map: unexpected error: %v", err)
+	}
+	if len(out) != 3 || out[0] != "d" || out[1] != "b" || out[2] != "c" {
+		t.Fatalf("expected [d b c], got %v", out)
+	}
+}
+
+func TestMap_FirstErrorStopsRemaining(t *testing.T) {
+	p := New[int](Config{MinWorkers: 1, MaxWorkers: 1, QueueSize: 100, IdleTimeout: time.Second, ScaleInterval: time.Second})
+	p.Start()
+	defer p.Stop()
+
+	bad := errors.New("bad item")
+	var calls atomic.Int32
+	items := make([]int, 50)
+	for i := range items {
+		items[i] = i
+	}
+
+	_, err := Map(context.Background(), p, items, func(_ context.Context, n int) (int, error) {
+		calls.Add(1)
+		if n == 0 {
+			return 0, bad
+		}
+		time.Sleep(time.Millisecond)
+		return n, nil
+	})
+	if !errors.Is(err, bad) {
+		t.Fatalf("expected bad item error, got %v", err)
+	}
+	if n := calls.Load(); n >= int32(len(items)) {
+		t.Fatalf("expected remaining items to be skipped, got %d calls", n)
+	}
+}
+
+// ===========================================================================
+// Stream
+// ===========================================================================
+
+func TestStream_PreservesOrder(t *testing.T) {
+	p := New[int](fastCfg())
+	p.Start()
+	defer p.Stop()
+
+	jobs := make(chan Job[int])
+	go func() {
+		defer close(jobs)
+		for i := range 30 {
+			delay := time.Duration(i%5) * time.Millisecond
+			jobs <- JobFunc[int](func(context.Context) (int, error) {
+				time.Sleep(delay)
+				return i, nil
+			})
+		}
+	}()
+
+	next := 0
+	for res := range p.Stream(context.Background(), jobs) {
+		if res.Err != nil || res.Value != next {
+			t.Fatalf("expected result %d, got %d (%v)", next, res.Value, res.Err)
+		}
+		next++
+	}
+	if next != 30 {
+		t.Fatalf("expected 30 results, got %d", next)
+	}
+}
+
+func TestStream_ClosesOnCtxCancel(t *testing.T) {
+	p := New[int](fastCfg())
+	p.Start()
+	defer p.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	jobs := make(chan Job[int]) // never closed
+	out := p.Stream(ctx, jobs)
+	cancel()
+
+	select {
+	case _, ok := <-out:
+		if ok {
+			t.Fatal("expected no results")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the stream to close when ctx is cancelled")
+	}
+}
@@ -1,23 +1,53 @@
 package pool
 
-import "sync/atomic"
+import (
+	"math"
+	"slices"
+	"sort"
+	"sync/atomic"
+	"time"
+)
 
 // stats holds live counters updated atomically by worker goroutines.
 type stats struct {
 	processed atomic.Int64
 	failed    atomic.Int64
 	panics    atomic.Int64
+	cancelled atomic.Int64
+	timedOut  atomic.Int64
+	evicted   atomic.Int64
+	callerRan atomic.Int64
+
+	retried          atomic.Int64
+	retriesExhausted atomic.Int64
+
+	scaleUps   atomic.Int64
+	scaleDowns atomic.Int64
+
+	durations histogram
+	queueWait histogram
 }
 
 // Snapshot is a point-in-time read of pool metrics.
 // It is safe to call concurrently at any time, including during shutdown.
 type Snapshot struct {
-	ActiveWorkers int   // current live worker goroutines
-	QueueDepth    int   // jobs currently waiting in the channel
-	QueueCapacity int   // total queue capacity (static)
-	Processed     int64 // jobs completed (success or error, excluding panics)
-	Failed        int64 // jobs that returned a non-nil error
-	Panics        int64 // jobs recovered from panic
+	ActiveWorkers        int                // current live worker goroutines
+	QueueDepth           int                // jobs currently waiting in the queue
+	QueueDepthByPriority [NumPriorities]int // QueueDepth per level, indexed by Priority
+	QueueCapacity        int                // total queue capacity (static)
+	Processed            int64              // jobs completed (success or error, excluding panics)
+	Failed               int64              // jobs that returned a non-nil error
+	Panics               int64              // jobs recovered from panic
+	Cancelled            int64              // jobs skipped because their context ended while queued
+	TimedOut             int64              // attempts that exceeded their timeout
+	Evicted              int64              // queued jobs dropped under BackpressureDropOldest
+	CallerRan            int64              // jobs run on the submitter's goroutine under BackpressureCallerRuns
+	Retried              int64              // retry attempts made (not counting first attempts)
+	RetriesExhausted     int64              // jobs that failed on their final allowed retry
+	ScaleUps             int64              // times workers were added by the scaler or Resize
+	ScaleDowns           int64              // times workers were retired, including idle exits
+	Durations            Histogram          // time jobs took to run, including retries
+	QueueWait            Histogram          // time jobs spent queued before a worker picked them up
 }
 
 // Stats returns a point-in-time snapshot of pool metrics.
@@ -27,11 +57,114 @@ func (p *Pool[T]) Stats() Snapshot {
 	p.mu.Unlock()
 
 	return Snapshot{
-		ActiveWorkers: active,
-		QueueDepth:    len(p.jobs),
-		QueueCapacity: cap(p.jobs),
-		Processed:     p.stats.processed.Load(),
-		Failed:        p.stats.failed.Load(),
-		Panics:        p.stats.panics.Load(),
+		ActiveWorkers:        active,
+		QueueDepth:           p.queue.Len(),
+		QueueDepthByPriority: p.queue.depths(),
+		QueueCapacity:        p.queue.capacity,
+		Processed:            p.stats.processed.Load(),
+		Failed:               p.stats.failed.Load(),
+		Panics:               p.stats.panics.Load(),
+		Cancelled:            p.stats.cancelled.Load(),
+		TimedOut:             p.stats.timedOut.Load(),
+		Evicted:              p.stats.evicted.Load(),
+		CallerRan:            p.stats.callerRan.Load(),
+		Retried:              p.stats.retried.Load(),
+		RetriesExhausted:     p.stats.retriesExhausted.Load(),
+		ScaleUps:             p.stats.scaleUps.Load(),
+		ScaleDowns:           p.stats.scaleDowns.Load(),
+		Durations:            p.stats.durations.snapshot(),
+		QueueWait:            p.stats.queueWait.snapshot(),
+	}
+}
+
+// histogramBounds are the upper bounds of the histogram buckets.
+var histogramBounds = [...]time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+	30 * time.Second,
+	time.Minute,
+}
+
+// Histogram is a distribution of durations over fixed buckets.
+type Histogram struct {
+	// Bounds are the buckets' inclusive upper bounds, ascending.
+	Bounds []time.Duration
+
+	// Counts[i] is the number of durations in (Bounds[i-1], Bounds[i]];
+	// the extra last entry counts those above every bound.
+	Counts []int64
+
+	Count int64         // total number of durations
+	Sum   time.Duration // total of all durations
+}
+
+// Mean returns the average duration, or zero if there are none.
+func (h Histogram) Mean() time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	return h.Sum / time.Duration(h.Count)
+}
+
+// Quantile returns the upper bound of the bucket holding the q-th quantile,
+// 0 <= q <= 1: Quantile(0.99) is a duration at least 99% of durations did
+// not exceed. Durations above every bound report the largest bound. It
+// returns zero if there are no durations.
+func (h Histogram) Quantile(q float64) time.Duration {
+	if h.Count == 0 || len(h.Bounds) == 0 {
+		return 0
+	}
+	rank := max(int64(math.Ceil(min(max(q, 0), 1)*float64(h.Count))), 1)
+	var seen int64
+	for i, n := range h.Counts {
+		seen += n
+		if seen >= rank && i < len(h.Bounds) {
+			return h.Bounds[i]
+		}
+	}
+	return h.Bounds[len(h.Bounds)-1]
+}
+
+// histogram is the live, atomically updated form of Histogram.
+type histogram struct {
+	counts [len(histogramBounds) + 1]atomic.Int64
+	sum    atomic.Int64
+}
+
+func (h *histogram) observe(d time.Duration) {
+	i := sort.Search(len(histogramBounds), func(i int) bool { return d <= histogramBounds[i] })
+	h.counts[i].Add(1)
+	h.sum.Add(int64(d))
+}
+
+// totals returns the number and sum of observed durations.
+func (h *histogram) totals() (count, sum int64) {
+	sum = h.sum.Load()
+	for i := range h.counts {
+		count += h.counts[i].Load()
+	}
+	return count, sum
+}
+
+func (h *histogram) snapshot() Histogram {
+	s := Histogram{
+		Bounds: slices.Clone(histogramBounds[:]),
+		Counts: make([]int64, len(h.counts)),
+		Sum:    time.Duration(h.sum.Load()),
+	}
+	for i := range h.counts {
+		s.Counts[i] = h.counts[i].Load()
+		s.Count += s.Counts[i]
 	}
+	return s
 }
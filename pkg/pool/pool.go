@@ -9,26 +9,109 @@ import (
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/vietpham102301/lightway/pkg/logger"
 )
 
 // Sentinel errors returned by Submit and delivered via Result.Err.
 var (
-	// ErrPoolStopped is returned when submitting to a stopped pool,
-	// or delivered to jobs that were queued but never executed before Stop().
+	// ErrPoolStopped is returned when submitting to a stopped or draining
+	// pool, or delivered to jobs that were queued but never executed before
+	// Stop() or the end of Drain().
 	ErrPoolStopped = errors.New("pool: pool has been stopped")
 
 	// ErrQueueFull is returned by Submit when the job queue is at capacity.
 	ErrQueueFull = errors.New("pool: job queue is full")
+
+	// ErrJobTimeout is delivered via Result.Err when a job runs longer than
+	// its timeout (Config.JobTimeout or Timeouter). It wraps the job's own
+	// error, if any.
+	ErrJobTimeout = errors.New("pool: job timed out")
+
+	// ErrJobEvicted is delivered via Result.Err to a queued job dropped to
+	// make room for a newer one under BackpressureDropOldest.
+	ErrJobEvicted = errors.New("pool: job evicted from full queue")
+)
+
+// Backpressure selects what Submit and SubmitCtx do when the queue is full.
+type Backpressure int
+
+const (
+	// BackpressureReject fails the submission with ErrQueueFull.
+	BackpressureReject Backpressure = iota
+
+	// BackpressureBlock waits for queue space like SubmitWait.
+	BackpressureBlock
+
+	// BackpressureCallerRuns executes the job on the submitting goroutine;
+	// its result is ready by the time Submit returns.
+	BackpressureCallerRuns
+
+	// BackpressureDropOldest evicts the oldest queued job of the lowest
+	// queued priority, failing it with ErrJobEvicted, and queues the new one.
+	// Only jobs of lower priority than the new one are evicted; if there
+	// are none, the submission fails with ErrQueueFull.
+	BackpressureDropOldest
 )
 
 // Job is the interface that all submitted work must implement.
 // T is the result type produced by the job; use struct{} if no result is needed.
 type Job[T any] interface {
-	// Execute performs the job. ctx is the pool's root context — check it
-	// for cancellation in long-running jobs to support graceful shutdown.
+	// Execute performs the job. ctx is cancelled when the submitter's
+	// context ends, the job's timeout elapses or the pool stops — check it
+	// in long-running jobs to support cancellation and graceful shutdown.
 	Execute(ctx context.Context) (T, error)
 }
 
+// Timeouter is implemented by jobs that need a timeout other than
+// Config.JobTimeout. A zero or negative Timeout disables the timeout.
+type Timeouter interface {
+	Timeout() time.Duration
+}
+
+// Priority orders queued jobs; higher priorities are dispatched first or more
+// often, depending on Config.Dispatch.
+type Priority int
+
+// Priority levels. Jobs that do not implement Prioritized run at
+// PriorityNormal.
+const (
+	PriorityLow Priority = iota
+	PriorityNormal
+	PriorityHigh
+
+	// NumPriorities is the number of priority levels.
+	NumPriorities = 3
+)
+
+// Prioritized is implemented by jobs that run at a priority other than
+// PriorityNormal. Out-of-range values are clamped to the nearest level.
+type Prioritized interface {
+	Priority() Priority
+}
+
+// priorityOf returns the queue level for job.
+func priorityOf(job any) Priority {
+	p, ok := job.(Prioritized)
+	if !ok {
+		return PriorityNormal
+	}
+	return min(max(p.Priority(), PriorityLow), PriorityHigh)
+}
+
+// Dispatch selects how workers choose between priority levels.
+type Dispatch int
+
+const (
+	// DispatchWeighted serves every non-empty level in proportion to
+	// Config.Weights, so low priorities slow down but never stall.
+	DispatchWeighted Dispatch = iota
+
+	// DispatchStrict always serves the highest non-empty level. Combine it
+	// with Config.AgingInterval to keep low priorities from starving.
+	DispatchStrict
+)
+
 // Result carries the outcome of a single job execution back to the caller.
 type Result[T any] struct {
 	Value T
@@ -45,17 +128,48 @@ type Config struct {
 	// Default: runtime.NumCPU() * 2
 	MaxWorkers int
 
-	// QueueSize is the capacity of the internal job channel.
+	// QueueSize is the capacity of the job queue.
 	// Default: MaxWorkers * 10
 	QueueSize int
 
+	// Backpressure is the policy applied by Submit and SubmitCtx when the
+	// queue is full. Default: BackpressureReject
+	Backpressure Backpressure
+
+	// Dispatch is how workers choose between priority levels.
+	// Default: DispatchWeighted
+	Dispatch Dispatch
+
+	// Weights are the relative dispatch shares of each priority level under
+	// DispatchWeighted, indexed by Priority. Missing or non-positive
+	// entries take the default. Default: [1, 4, 16]
+	Weights []int
+
+	// AgingInterval promotes a queued job one priority level for every
+	// interval it waits at its current level. Default: none
+	AgingInterval time.Duration
+
 	// IdleTimeout is how long a worker above MinWorkers may sit idle before
 	// it exits, enabling scale-down. Default: 30s
 	IdleTimeout time.Duration
 
-	// ScaleInterval is how often the scaler goroutine checks whether new
-	// workers should be spawned. Default: 100ms
+	// ScaleInterval is how often the scaler goroutine asks Scaler how many
+	// workers to run. Default: 100ms
 	ScaleInterval time.Duration
+
+	// Scaler decides how many workers to run between MinWorkers and
+	// MaxWorkers. Default: BacklogScaler (a worker per queued job)
+	Scaler Scaler
+
+	// JobTimeout bounds how long each job attempt may run. Attempts
+	// exceeding it have their context cancelled and fail with
+	// ErrJobTimeout. Default: none
+	JobTimeout time.Duration
+
+	// Retry re-runs failed jobs; jobs implementing Retrier, and submissions
+	// with WithRetry, override it.
+	// Default: nil (no retries)
+	Retry *RetryPolicy
 }
 
 func (c *Config) applyDefaults() {
@@ -77,6 +191,21 @@ func (c *Config) applyDefaults() {
 	if c.ScaleInterval <= 0 {
 		c.ScaleInterval = 100 * time.Millisecond
 	}
+	if c.Scaler == nil {
+		c.Scaler = BacklogScaler{}
+	}
+	weights := []int{1, 4, 16}
+	for i := range min(len(c.Weights), NumPriorities) {
+		if c.Weights[i] > 0 {
+			weights[i] = c.Weights[i]
+		}
+	}
+	c.Weights = weights
+	if c.Retry != nil {
+		retry := *c.Retry
+		retry.applyDefaults()
+		c.Retry = &retry
+	}
 }
 
 // Pool is a generic, dynamically-scaling worker pool.
@@ -84,8 +213,8 @@ func (c *Config) applyDefaults() {
 type Pool[T any] struct {
 	cfg Config
 
-	// jobs is the buffered channel workers pull work from.
-	jobs chan jobEnvelope[T]
+	// queue is the bounded priority queue workers pull work from.
+	queue *jobQueue[T]
 
 	// ctx/cancel broadcast shutdown to all goroutines.
 	ctx    context.Context
@@ -94,19 +223,95 @@ type Pool[T any] struct {
 	// wg tracks all worker goroutines and the scaler goroutine.
 	wg sync.WaitGroup
 
-	// mu guards activeWorkers for scaling decisions.
+	// mu guards activeWorkers, the worker bounds in cfg and retirement for
+	// scaling decisions.
 	mu            sync.Mutex
 	activeWorkers int
 
-	stats   stats
-	started atomic.Bool
+	// retiring is how many workers should exit as soon as they are between
+	// jobs; retireCh is closed and replaced to wake idle ones.
+	retiring int
+	retireCh chan struct{}
+
+	stats    stats
+	started  atomic.Bool
+	stopOnce sync.Once
 }
 
-// jobEnvelope pairs a Job with its result channel so a worker can deliver
-// the outcome directly to the original caller.
+// DrainReport summarises a Drain.
+type DrainReport struct {
+	// Completed is the number of jobs that finished running during the
+	// drain, successfully or not.
+	Completed int
+
+	// Abandoned is the number of jobs still queued or running when the
+	// drain deadline passed. Queued jobs receive ErrPoolStopped; running
+	// jobs see their context cancelled.
+	Abandoned int
+}
+
+// Envelope states. An envelope is pending until it enters the queue, and is
+// claimed exactly once: by a worker about to run it, by its context being
+// cancelled while queued, by eviction, by Stop, or by the submitter when it
+// is never queued.
+const (
+	envPending int32 = iota
+	envQueued
+	envClaimed
+)
+
+// jobEnvelope pairs a Job with its submitter's context and result channel so
+// a worker can deliver the outcome directly to the original caller.
 type jobEnvelope[T any] struct {
-	job    Job[T]
-	result chan<- Result[T]
+	job   Job[T]
+	ctx   context.Context
+	opts  submitOptions
+	state atomic.Int32
+
+	// The outcome goes to onComplete if set, and to result otherwise.
+	result     chan Result[T]
+	onComplete func(Result[T])
+
+	// levelSince is when the envelope reached its current priority level,
+	// for aging.
+	levelSince time.Time
+
+	// queuedAt is when the envelope entered the queue.
+	queuedAt time.Time
+
+	// stopWatch stops the callback that fails the job when ctx ends while
+	// it is still queued.
+	stopWatch func() bool
+}
+
+// claim reports whether the caller won the right to deliver the result.
+func (e *jobEnvelope[T]) claim() bool {
+	if !e.state.CompareAndSwap(envQueued, envClaimed) &&
+		!e.state.CompareAndSwap(envPending, envClaimed) {
+		return false
+	}
+	if e.stopWatch != nil {
+		e.stopWatch()
+	}
+	return true
+}
+
+// deliver hands over the single result: it sends it and closes the channel,
+// or calls onComplete, recovering from a panicking callback.
+func (e *jobEnvelope[T]) deliver(value T, err error) {
+	res := Result[T]{Value: value, Err: err}
+	if e.onComplete == nil {
+		e.result <- res
+		close(e.result)
+		return
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("pool: OnComplete callback panicked", "panic", r)
+		}
+	}()
+	e.onComplete(res)
 }
 
 // New creates a Pool configured by cfg.
@@ -115,10 +320,11 @@ func New[T any](cfg Config) *Pool[T] {
 	cfg.applyDefaults()
 	ctx, cancel := context.WithCancel(context.Background())
 	return &Pool[T]{
-		cfg:    cfg,
-		jobs:   make(chan jobEnvelope[T], cfg.QueueSize),
-		ctx:    ctx,
-		cancel: cancel,
+		cfg:      cfg,
+		queue:    newJobQueue[T](cfg),
+		ctx:      ctx,
+		cancel:   cancel,
+		retireCh: make(chan struct{}),
 	}
 }
 
@@ -128,32 +334,75 @@ func (p *Pool[T]) Start() {
 	if !p.started.CompareAndSwap(false, true) {
 		return
 	}
-	for i := 0; i < p.cfg.MinWorkers; i++ {
-		p.spawnWorker()
-	}
+	p.rescale(func(int) int { return 0 }) // up to MinWorkers
 	p.wg.Add(1)
 	go p.scaler()
 }
 
 // Stop signals all workers to finish their current job, waits for them to
 // exit, then drains any remaining queued jobs with ErrPoolStopped.
-// Stop is safe to call multiple times; only the first call has effect.
+// Stop is safe to call multiple times and concurrently; only the first call
+// has effect, and every call returns once the pool has stopped.
 func (p *Pool[T]) Stop() {
+	p.stop()
+}
+
+// Drain shuts the pool down gracefully: it stops accepting new jobs (blocked
+// submitters get ErrPoolStopped), lets workers finish everything already
+// queued, and then stops the pool. If ctx ends first, the pool is stopped as
+// by Stop — the remaining queued jobs are abandoned and running jobs have
+// their context cancelled — and Drain returns ctx.Err().
+//
+// Drain on a pool that was never started, or already stopped, does nothing.
+func (p *Pool[T]) Drain(ctx context.Context) (DrainReport, error) {
 	if !p.started.Load() {
-		return
+		return DrainReport{}, nil
 	}
-	p.cancel()   // unblocks idle workers and the scaler
-	p.wg.Wait()  // waits for all goroutines to exit
 
-	// Safe to close the channel now — no concurrent senders remain.
-	close(p.jobs)
+	before := p.finished()
+	var err error
+	select {
+	case <-p.queue.closeIntake():
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
 
-	// Drain jobs that were queued but never picked up.
-	for env := range p.jobs {
-		var zero T
-		env.result <- Result[T]{Value: zero, Err: ErrPoolStopped}
-		close(env.result)
+	report := DrainReport{Completed: int(p.finished() - before)}
+	running := p.queue.runningCount()
+	abandoned := p.stop()
+	if err != nil {
+		report.Abandoned = running + abandoned
+	}
+	return report, err
+}
+
+// finished returns how many jobs have run to completion or panicked.
+func (p *Pool[T]) finished() int64 {
+	return p.stats.processed.Load() + p.stats.panics.Load()
+}
+
+// stop stops the pool once and returns the number of queued jobs failed
+// with ErrPoolStopped by this call.
+func (p *Pool[T]) stop() int {
+	if !p.started.Load() {
+		return 0
 	}
+	abandoned := 0
+	p.stopOnce.Do(func() {
+		p.cancel()  // unblocks idle workers and the scaler
+		p.wg.Wait() // waits for all goroutines to exit
+
+		// Drain jobs that were queued but never picked up; blocked
+		// submitters are turned away with ErrPoolStopped.
+		for _, env := range p.queue.close() {
+			if env.claim() {
+				abandoned++
+				var zero T
+				env.deliver(zero, ErrPoolStopped)
+			}
+		}
+	})
+	return abandoned
 }
 
 // Submit enqueues job for execution and returns a channel that will receive
@@ -162,19 +411,172 @@ func (p *Pool[T]) Stop() {
 // use select.
 //
 // Submit returns ErrPoolStopped immediately if the pool is not running.
-// Submit returns ErrQueueFull if the queue is at capacity (never blocks).
-func (p *Pool[T]) Submit(job Job[T]) (<-chan Result[T], error) {
+// When the queue is full, Submit follows Config.Backpressure; under the
+// default policy it returns ErrQueueFull without blocking. opts tune this
+// submission only; see WithRetry.
+func (p *Pool[T]) Submit(job Job[T], opts ...SubmitOption) (<-chan Result[T], error) {
+	return p.SubmitCtx(context.Background(), job, opts...)
+}
+
+// SubmitCtx is like Submit, but ties the job to ctx: the context passed to
+// Execute is cancelled when either ctx or the pool ends, and a job whose ctx
+// ends while it is still queued is skipped, delivering ctx.Err() at once.
+//
+// SubmitCtx returns ctx.Err() without enqueueing if ctx has already ended.
+func (p *Pool[T]) SubmitCtx(ctx context.Context, job Job[T], opts ...SubmitOption) (<-chan Result[T], error) {
+	env := p.newEnvelope(ctx, job, nil, opts)
+	if err := p.submit(env); err != nil {
+		return nil, err
+	}
+	return env.result, nil
+}
+
+// SubmitOnComplete is like SubmitCtx, but delivers the Result by calling
+// onComplete instead of through a channel. onComplete is called exactly
+// once if SubmitOnComplete returns nil, and never otherwise. It runs on the
+// goroutine that finishes the job — usually a worker — so it should return
+// quickly; a panicking callback is recovered and logged.
+func (p *Pool[T]) SubmitOnComplete(ctx context.Context, job Job[T], onComplete func(Result[T]), opts ...SubmitOption) error {
+	return p.submit(p.newEnvelope(ctx, job, onComplete, opts))
+}
+
+// SubmitWait is like SubmitCtx, but blocks until there is room in the queue,
+// regardless of Config.Backpressure. Blocked submitters are admitted in the
+// order they arrived. It returns ctx.Err() if ctx ends first, and
+// ErrPoolStopped if the pool stops first.
+func (p *Pool[T]) SubmitWait(ctx context.Context, job Job[T], opts ...SubmitOption) (<-chan Result[T], error) {
+	env := p.newEnvelope(ctx, job, nil, opts)
+	if err := p.checkSubmit(env); err != nil {
+		return nil, err
+	}
+	if err := p.submitWait(env, ctx); err != nil {
+		return nil, err
+	}
+	return env.result, nil
+}
+
+// TrySubmitFor is like SubmitWait, but gives up with ErrQueueFull if no
+// queue space frees up within timeout.
+func (p *Pool[T]) TrySubmitFor(job Job[T], timeout time.Duration, opts ...SubmitOption) (<-chan Result[T], error) {
+	env := p.newEnvelope(context.Background(), job, nil, opts)
+	if err := p.checkSubmit(env); err != nil {
+		return nil, err
+	}
+	waitCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := p.submitWait(env, waitCtx); err != nil {
+		return nil, err
+	}
+	return env.result, nil
+}
+
+func (p *Pool[T]) newEnvelope(ctx context.Context, job Job[T], onComplete func(Result[T]), opts []SubmitOption) *jobEnvelope[T] {
+	env := &jobEnvelope[T]{job: job, ctx: ctx, onComplete: onComplete}
+	for _, opt := range opts {
+		opt(&env.opts)
+	}
+	if onComplete == nil {
+		env.result = make(chan Result[T], 1)
+	}
+	return env
+}
+
+// checkSubmit validates a submission and arranges for the job to fail with
+// ctx.Err() as soon as its ctx ends while it is queued. The watch is
+// registered before the envelope is queued so workers always observe
+// stopWatch.
+func (p *Pool[T]) checkSubmit(env *jobEnvelope[T]) error {
 	if !p.started.Load() || p.ctx.Err() != nil {
-		return nil, ErrPoolStopped
+		return ErrPoolStopped
+	}
+	if err := env.ctx.Err(); err != nil {
+		return err
+	}
+	if env.ctx.Done() != nil {
+		env.stopWatch = context.AfterFunc(env.ctx, func() {
+			if env.state.CompareAndSwap(envQueued, envClaimed) {
+				p.stats.cancelled.Add(1)
+				var zero T
+				env.deliver(zero, env.ctx.Err())
+			}
+		})
+	}
+	return nil
+}
+
+// submit queues env according to Config.Backpressure.
+func (p *Pool[T]) submit(env *jobEnvelope[T]) error {
+	if err := p.checkSubmit(env); err != nil {
+		return err
+	}
+
+	switch p.cfg.Backpressure {
+	case BackpressureBlock:
+		return p.submitWait(env, env.ctx)
+	case BackpressureDropOldest:
+		return p.submitDropOldest(env)
 	}
 
-	result := make(chan Result[T], 1)
-	env := jobEnvelope[T]{job: job, result: result}
+	queued, err := p.queue.tryPush(env)
+	switch {
+	case err != nil:
+		return p.discard(env, err)
+	case queued:
+		return nil
+	case p.cfg.Backpressure == BackpressureCallerRuns:
+		env.claim()
+		p.stats.callerRan.Add(1)
+		p.executeJob(env)
+		return nil
+	default:
+		return p.discard(env, ErrQueueFull)
+	}
+}
+
+// submitWait queues env, waiting for space until waitCtx ends.
+func (p *Pool[T]) submitWait(env *jobEnvelope[T], waitCtx context.Context) error {
+	w, err := p.queue.wait(env)
+	if err != nil {
+		return p.discard(env, err)
+	}
+	if w == nil {
+		return nil
+	}
 
 	select {
-	case p.jobs <- env:
-		return result, nil
+	case <-w.done:
+	case <-waitCtx.Done():
+	case <-p.ctx.Done():
+	}
+	if p.queue.leave(w) {
+		return nil
+	}
+
+	switch {
+	case p.ctx.Err() != nil:
+		return p.discard(env, ErrPoolStopped)
+	case env.ctx.Err() != nil:
+		return p.discard(env, env.ctx.Err())
 	default:
-		return nil, ErrQueueFull
+		return p.discard(env, ErrQueueFull)
 	}
 }
+
+func (p *Pool[T]) submitDropOldest(env *jobEnvelope[T]) error {
+	evicted, err := p.queue.pushOrEvict(env)
+	if err != nil {
+		return p.discard(env, err)
+	}
+	if evicted != nil && evicted.claim() {
+		p.stats.evicted.Add(1)
+		var zero T
+		evicted.deliver(zero, ErrJobEvicted)
+	}
+	return nil
+}
+
+// discard abandons an envelope that was never queued and returns err.
+func (p *Pool[T]) discard(env *jobEnvelope[T], err error) error {
+	env.claim()
+	return err
+}
@@ -32,6 +32,11 @@ func (j slowJob) Execute(ctx context.Context) (int, error) {
 	return 0, ctx.Err()
 }
 
+// funcJob adapts a function to Job[int].
+type funcJob func(context.Context) (int, error)
+
+func (f funcJob) Execute(ctx context.Context) (int, error) { return f(ctx) }
+
 // fastCfg returns a Config suitable for tests — short timeouts so tests finish quickly.
 func fastCfg() Config {
 	return Config{
@@ -66,6 +71,9 @@ func TestConfig_Defaults(t *testing.T) {
 	if cfg.ScaleInterval <= 0 {
 		t.Errorf("ScaleInterval: want > 0, got %v", cfg.ScaleInterval)
 	}
+	if _, ok := cfg.Scaler.(BacklogScaler); !ok {
+		t.Errorf("Scaler: want BacklogScaler, got %T", cfg.Scaler)
+	}
 }
 
 func TestConfig_MinWorkersClampedToMax(t *testing.T) {
@@ -167,6 +175,122 @@ func TestStop_DrainsQueuedJobs(t *testing.T) {
 	}
 }
 
+func TestStop_IsIdempotent(t *testing.T) {
+	p := New[int](fastCfg())
+	p.Start()
+	p.Stop()
+	p.Stop() // must not panic
+}
+
+func TestStop_Concurrent(t *testing.T) {
+	p := New[int](fastCfg())
+	p.Start()
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.Stop()
+		}()
+	}
+	wg.Wait()
+}
+
+func TestDrain_BeforeStart_IsNoop(t *testing.T) {
+	p := New[int](fastCfg())
+	report, err := p.Drain(context.Background())
+	if err != nil || report != (DrainReport{}) {
+		t.Fatalf("expected empty report and nil error, got %+v, %v", report, err)
+	}
+}
+
+func TestDrain_FinishesQueuedJobs(t *testing.T) {
+	gate := make(chan struct{})
+	p := New[int](Config{
+		MinWorkers:    1,
+		MaxWorkers:    1,
+		QueueSize:     10,
+		IdleTimeout:   time.Second,
+		ScaleInterval: time.Second,
+	})
+	p.Start()
+
+	p.Submit(gateJob{gate: gate}) //nolint
+	chans := make([]<-chan Result[int], 0, 3)
+	for i := range 3 {
+		ch, _ := p.Submit(successJob{val: i})
+		chans = append(chans, ch)
+	}
+
+	time.AfterFunc(20*time.Millisecond, func() { close(gate) })
+	report, err := p.Drain(context.Background())
+	if err != nil {
+		t.Fatalf("Drain: unexpected error: %v", err)
+	}
+	if report.Completed != 4 || report.Abandoned != 0 {
+		t.Fatalf("expected Completed=4 Abandoned=0, got %+v", report)
+	}
+	for i, ch := range chans {
+		if res := waitResult(t, ch); res.Err != nil || res.Value != i {
+			t.Fatalf("expected value %d, got %d (%v)", i, res.Value, res.Err)
+		}
+	}
+}
+
+func TestDrain_RejectsNewJobs(t *testing.T) {
+	gate := make(chan struct{})
+	p := New[int](fastCfg())
+	p.Start()
+
+	p.Submit(gateJob{gate: gate}) //nolint
+	waitFor(t, func() bool { return p.Stats().QueueDepth == 0 })
+
+	done := make(chan struct{})
+	go func() {
+		p.Drain(context.Background()) //nolint
+		close(done)
+	}()
+
+	waitFor(t, func() bool {
+		_, err := p.Submit(successJob{})
+		return errors.Is(err, ErrPoolStopped)
+	})
+	close(gate)
+	<-done
+}
+
+func TestDrain_DeadlineAbandonsRemaining(t *testing.T) {
+	p := New[int](Config{
+		MinWorkers:    1,
+		MaxWorkers:    1,
+		QueueSize:     10,
+		IdleTimeout:   time.Second,
+		ScaleInterval: time.Second,
+	})
+	p.Start()
+
+	running, _ := p.Submit(slowJob{})
+	waitFor(t, func() bool { return p.Stats().QueueDepth == 0 })
+	queued, _ := p.Submit(successJob{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	report, err := p.Drain(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if report.Completed != 0 || report.Abandoned != 2 {
+		t.Fatalf("expected Completed=0 Abandoned=2, got %+v", report)
+	}
+	if res := waitResult(t, queued); !errors.Is(res.Err, ErrPoolStopped) {
+		t.Fatalf("expected ErrPoolStopped for queued job, got %v", res.Err)
+	}
+	if res := waitResult(t, running); !errors.Is(res.Err, context.Canceled) {
+		t.Fatalf("expected context.Canceled for running job, got %v", res.Err)
+	}
+}
+
 // ===========================================================================
 // Submit
 // ===========================================================================
@@ -261,207 +385,1341 @@ func TestSubmit_Concurrent(t *testing.T) {
 }
 
 // ===========================================================================
-// Error handling
+// SubmitCtx / timeouts
 // ===========================================================================
 
-func TestJob_ReturnsError_CountedAsFailed(t *testing.T) {
+// timeoutJob blocks until its context ends and overrides Config.JobTimeout.
+type timeoutJob struct{ timeout time.Duration }
+
+func (j timeoutJob) Execute(ctx context.Context) (int, error) {
+	<-ctx.Done()
+	return 0, ctx.Err()
+}
+
+func (j timeoutJob) Timeout() time.Duration { return j.timeout }
+
+// stubbornJob ignores its context and fails with its own error after d.
+type stubbornJob struct {
+	d   time.Duration
+	err error
+}
+
+func (j stubbornJob) Execute(_ context.Context) (int, error) {
+	time.Sleep(j.d)
+	return 0, j.err
+}
+
+func waitResult(t *testing.T, ch <-chan Result[int]) Result[int] {
+	t.Helper()
+	select {
+	case res := <-ch:
+		return res
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for result")
+		return Result[int]{}
+	}
+}
+
+func TestSubmitCtx_AlreadyCancelled_ReturnsCtxErr(t *testing.T) {
 	p := New[int](fastCfg())
 	p.Start()
 	defer p.Stop()
 
-	sentinel := errors.New("job error")
-	ch, err := p.Submit(errorJob{err: sentinel})
-	if err != nil {
-		t.Fatalf("Submit: %v", err)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := p.SubmitCtx(ctx, successJob{}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
 	}
+}
 
-	res := <-ch
-	if !errors.Is(res.Err, sentinel) {
-		t.Fatalf("expected sentinel error, got %v", res.Err)
+func TestSubmitCtx_CancelRunningJob(t *testing.T) {
+	p := New[int](fastCfg())
+	p.Start()
+	defer p.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := p.SubmitCtx(ctx, slowJob{})
+	if err != nil {
+		t.Fatalf("SubmitCtx: unexpected error: %v", err)
 	}
+	time.Sleep(20 * time.Millisecond)
+	cancel()
 
-	snap := p.Stats()
-	if snap.Failed < 1 {
-		t.Errorf("expected Failed >= 1, got %d", snap.Failed)
+	if res := waitResult(t, ch); !errors.Is(res.Err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", res.Err)
 	}
 }
 
-func TestJob_Panic_RecoveredAndCountedAsPanic(t *testing.T) {
-	p := New[int](fastCfg())
+func TestSubmitCtx_CancelledWhileQueued_IsSkipped(t *testing.T) {
+	p := New[int](Config{
+		MinWorkers:    1,
+		MaxWorkers:    1,
+		QueueSize:     4,
+		IdleTimeout:   time.Second,
+		ScaleInterval: time.Second,
+	})
 	p.Start()
 	defer p.Stop()
 
-	ch, err := p.Submit(panicJob{})
+	// Occupy the only worker.
+	blockCtx, unblock := context.WithCancel(context.Background())
+	defer unblock()
+	p.SubmitCtx(blockCtx, slowJob{}) //nolint
+	time.Sleep(20 * time.Millisecond)
+
+	var ran atomic.Bool
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := p.SubmitCtx(ctx, funcJob(func(context.Context) (int, error) {
+		ran.Store(true)
+		return 1, nil
+	}))
 	if err != nil {
-		t.Fatalf("Submit: %v", err)
+		t.Fatalf("SubmitCtx: unexpected error: %v", err)
 	}
+	cancel()
 
-	res := <-ch
-	if res.Err == nil {
-		t.Fatal("expected error from panicking job")
+	// The result is delivered while the worker is still busy.
+	if res := waitResult(t, ch); !errors.Is(res.Err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", res.Err)
 	}
 
-	snap := p.Stats()
-	if snap.Panics < 1 {
-		t.Errorf("expected Panics >= 1, got %d", snap.Panics)
+	unblock()
+	time.Sleep(20 * time.Millisecond)
+	if ran.Load() {
+		t.Fatal("expected cancelled job to be skipped, but it ran")
+	}
+	if s := p.Stats(); s.Cancelled != 1 {
+		t.Fatalf("expected Cancelled=1, got %d", s.Cancelled)
 	}
 }
 
-func TestJob_Panic_WorkerSurvives(t *testing.T) {
+func TestSubmitCtx_PoolStopCancelsJob(t *testing.T) {
 	p := New[int](fastCfg())
 	p.Start()
-	defer p.Stop()
-
-	// Submit a panic job.
-	ch, _ := p.Submit(panicJob{})
-	<-ch
 
-	// The pool must still process subsequent jobs.
-	ch2, err := p.Submit(successJob{val: 7})
+	ch, err := p.SubmitCtx(context.Background(), slowJob{})
 	if err != nil {
-		t.Fatalf("Submit after panic: %v", err)
+		t.Fatalf("SubmitCtx: unexpected error: %v", err)
 	}
-	res := <-ch2
-	if res.Err != nil || res.Value != 7 {
-		t.Fatalf("expected (7, nil) after panic recovery, got (%d, %v)", res.Value, res.Err)
+	time.Sleep(20 * time.Millisecond)
+	p.Stop()
+
+	if res := waitResult(t, ch); !errors.Is(res.Err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", res.Err)
 	}
 }
 
-// ===========================================================================
-// Dynamic scaling
-// ===========================================================================
-
-func TestScaling_SpawnsWorkersUnderLoad(t *testing.T) {
-	cfg := Config{
-		MinWorkers:    1,
-		MaxWorkers:    8,
-		QueueSize:     200,
-		IdleTimeout:   500 * time.Millisecond,
-		ScaleInterval: 10 * time.Millisecond,
-	}
+func TestJobTimeout_FromConfig(t *testing.T) {
+	cfg := fastCfg()
+	cfg.JobTimeout = 20 * time.Millisecond
 	p := New[int](cfg)
 	p.Start()
 	defer p.Stop()
 
-	initialSnap := p.Stats()
-	if initialSnap.ActiveWorkers != 1 {
-		t.Fatalf("expected 1 initial worker, got %d", initialSnap.ActiveWorkers)
+	ch, _ := p.Submit(slowJob{})
+	res := waitResult(t, ch)
+	if !errors.Is(res.Err, ErrJobTimeout) {
+		t.Fatalf("expected ErrJobTimeout, got %v", res.Err)
 	}
-
-	// Submit a burst of slow jobs to trigger scale-up.
-	for i := 0; i < 50; i++ {
-		p.Submit(slowJob{}) //nolint
+	if errors.Is(res.Err, context.DeadlineExceeded) {
+		t.Fatalf("expected ErrJobTimeout to replace the deadline error, got %v", res.Err)
 	}
 
-	// Allow scaler to react.
-	deadline := time.Now().Add(500 * time.Millisecond)
-	for time.Now().Before(deadline) {
-		snap := p.Stats()
-		if snap.ActiveWorkers > 1 {
-			return // scaled up
-		}
-		time.Sleep(20 * time.Millisecond)
+	s := p.Stats()
+	if s.TimedOut != 1 || s.Failed != 1 {
+		t.Fatalf("expected TimedOut=1 Failed=1, got TimedOut=%d Failed=%d", s.TimedOut, s.Failed)
 	}
-	t.Errorf("expected ActiveWorkers to grow above 1, got %d", p.Stats().ActiveWorkers)
 }
 
-func TestScaling_ShrinksWorkersWhenIdle(t *testing.T) {
-	cfg := Config{
-		MinWorkers:    1,
-		MaxWorkers:    4,
-		QueueSize:     100,
-		IdleTimeout:   50 * time.Millisecond,
-		ScaleInterval: 10 * time.Millisecond,
-	}
+func TestJobTimeout_PerJobOverride(t *testing.T) {
+	cfg := fastCfg()
+	cfg.JobTimeout = time.Hour
 	p := New[int](cfg)
 	p.Start()
 	defer p.Stop()
 
-	// Submit burst of quick jobs to scale up.
-	var chans []<-chan Result[int]
-	for i := 0; i < 20; i++ {
-		ch, err := p.Submit(successJob{val: i})
-		if err == nil {
-			chans = append(chans, ch)
-		}
-	}
-	// Drain results.
-	for _, ch := range chans {
-		<-ch
-	}
-
-	// After IdleTimeout, workers above MinWorkers should exit.
-	deadline := time.Now().Add(500 * time.Millisecond)
-	for time.Now().Before(deadline) {
-		snap := p.Stats()
-		if snap.ActiveWorkers <= cfg.MinWorkers {
-			return // scaled down
-		}
-		time.Sleep(20 * time.Millisecond)
+	ch, _ := p.Submit(timeoutJob{timeout: 20 * time.Millisecond})
+	if res := waitResult(t, ch); !errors.Is(res.Err, ErrJobTimeout) {
+		t.Fatalf("expected ErrJobTimeout, got %v", res.Err)
 	}
-	t.Errorf("expected ActiveWorkers to shrink to %d, got %d", cfg.MinWorkers, p.Stats().ActiveWorkers)
 }
 
-// ===========================================================================
-// Stats / Snapshot
-// ===========================================================================
+func TestJobTimeout_WrapsJobError(t *testing.T) {
+	cfg := fastCfg()
+	cfg.JobTimeout = 10 * time.Millisecond
+	p := New[int](cfg)
+	p.Start()
+	defer p.Stop()
 
-func TestStats_ZeroedBeforeStart(t *testing.T) {
-	p := New[int](fastCfg())
-	snap := p.Stats()
-	if snap.Processed != 0 || snap.Failed != 0 || snap.Panics != 0 {
-		t.Errorf("expected all counters zero before Start, got %+v", snap)
+	jobErr := errors.New("partial write")
+	ch, _ := p.Submit(stubbornJob{d: 40 * time.Millisecond, err: jobErr})
+	res := waitResult(t, ch)
+	if !errors.Is(res.Err, ErrJobTimeout) || !errors.Is(res.Err, jobErr) {
+		t.Fatalf("expected error wrapping ErrJobTimeout and job error, got %v", res.Err)
 	}
 }
 
-func TestStats_ProcessedAndFailed_Updated(t *testing.T) {
+func TestJobTimeout_CallerDeadlineIsNotATimeout(t *testing.T) {
 	p := New[int](fastCfg())
 	p.Start()
 	defer p.Stop()
 
-	ch1, _ := p.Submit(successJob{val: 1})
-	ch2, _ := p.Submit(errorJob{err: errors.New("e")})
-	<-ch1
-	<-ch2
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
 
-	snap := p.Stats()
-	if snap.Processed < 2 {
-		t.Errorf("expected Processed >= 2, got %d", snap.Processed)
+	ch, _ := p.SubmitCtx(ctx, slowJob{})
+	res := waitResult(t, ch)
+	if !errors.Is(res.Err, context.DeadlineExceeded) || errors.Is(res.Err, ErrJobTimeout) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", res.Err)
 	}
-	if snap.Failed < 1 {
-		t.Errorf("expected Failed >= 1, got %d", snap.Failed)
+	if s := p.Stats(); s.TimedOut != 0 {
+		t.Fatalf("expected TimedOut=0, got %d", s.TimedOut)
 	}
 }
 
-func TestStats_QueueDepth_ReflectsPending(t *testing.T) {
+// ===========================================================================
+// Blocking submit / backpressure
+// ===========================================================================
+
+// gateJob blocks until gate is closed, then records val in order.
+type gateJob struct {
+	gate  <-chan struct{}
+	val   int
+	order *orderLog
+}
+
+func (j gateJob) Execute(_ context.Context) (int, error) {
+	if j.gate != nil {
+		<-j.gate
+	}
+	if j.order != nil {
+		j.order.add(j.val)
+	}
+	return j.val, nil
+}
+
+type orderLog struct {
+	mu   sync.Mutex
+	vals []int
+}
+
+func (o *orderLog) add(v int) {
+	o.mu.Lock()
+	o.vals = append(o.vals, v)
+	o.mu.Unlock()
+}
+
+// singleSlotPool returns a started pool with one worker and a queue of one,
+// with the worker blocked on gate and the queue slot filled.
+func singleSlotPool(t *testing.T, bp Backpressure, gate <-chan struct{}) *Pool[int] {
+	t.Helper()
 	p := New[int](Config{
 		MinWorkers:    1,
 		MaxWorkers:    1,
-		QueueSize:     10,
+		QueueSize:     1,
 		IdleTimeout:   time.Second,
 		ScaleInterval: time.Second,
+		Backpressure:  bp,
 	})
 	p.Start()
-	defer p.Stop()
-
-	// Block the worker.
-	p.Submit(slowJob{}) //nolint
-	time.Sleep(20 * time.Millisecond)
-
-	// Queue some jobs.
-	p.Submit(successJob{}) //nolint
-	p.Submit(successJob{}) //nolint
+	if _, err := p.SubmitWait(context.Background(), gateJob{gate: gate, val: -1}); err != nil {
+		t.Fatalf("SubmitWait: unexpected error: %v", err)
+	}
+	waitFor(t, func() bool { return p.Stats().QueueDepth == 0 })
+	if _, err := p.SubmitWait(context.Background(), gateJob{gate: gate, val: 0}); err != nil {
+		t.Fatalf("SubmitWait: unexpected error: %v", err)
+	}
+	return p
+}
 
-	snap := p.Stats()
-	if snap.QueueDepth < 1 {
-		t.Errorf("expected QueueDepth >= 1, got %d", snap.QueueDepth)
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout waiting for condition")
+		}
+		time.Sleep(time.Millisecond)
 	}
 }
 
-func TestStats_ActiveWorkers_Accurate(t *testing.T) {
-	cfg := fastCfg()
-	p := New[int](cfg)
-	p.Start()
+func waiting[T any](p *Pool[T]) int {
+	p.queue.mu.Lock()
+	defer p.queue.mu.Unlock()
+	return p.queue.waiters.Len()
+}
+
+func TestSubmitWait_BlocksUntilSpace(t *testing.T) {
+	gate := make(chan struct{})
+	p := singleSlotPool(t, BackpressureReject, gate)
+	defer p.Stop()
+
+	type submitted struct {
+		ch  <-chan Result[int]
+		err error
+	}
+	done := make(chan submitted, 1)
+	go func() {
+		ch, err := p.SubmitWait(context.Background(), successJob{val: 7})
+		done <- submitted{ch, err}
+	}()
+
+	waitFor(t, func() bool { return waiting(p) == 1 })
+	select {
+	case <-done:
+		t.Fatal("expected SubmitWait to block while the queue is full")
+	default:
+	}
+
+	close(gate)
+	s := <-done
+	if s.err != nil {
+		t.Fatalf("SubmitWait: unexpected error: %v", s.err)
+	}
+	if res := waitResult(t, s.ch); res.Value != 7 {
+		t.Fatalf("expected value 7, got %d", res.Value)
+	}
+}
+
+func TestSubmitWait_CtxCancelled(t *testing.T) {
+	gate := make(chan struct{})
+	p := singleSlotPool(t, BackpressureReject, gate)
+	defer p.Stop()
+	defer close(gate)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := p.SubmitWait(ctx, successJob{})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if n := waiting(p); n != 0 {
+		t.Fatalf("expected no waiters left, got %d", n)
+	}
+}
+
+func TestSubmitWait_FIFO(t *testing.T) {
+	gate := make(chan struct{})
+	p := singleSlotPool(t, BackpressureReject, gate)
+	defer p.Stop()
+
+	var order orderLog
+	for i := 1; i <= 5; i++ {
+		go func(val int) {
+			p.SubmitWait(context.Background(), gateJob{val: val, order: &order}) //nolint
+		}(i)
+		// Make sure each submitter is in line before the next one arrives.
+		waitFor(t, func() bool { return waiting(p) == i })
+	}
+
+	close(gate)
+	waitFor(t, func() bool {
+		order.mu.Lock()
+		defer order.mu.Unlock()
+		return len(order.vals) == 5
+	})
+
+	for i, v := range order.vals {
+		if v != i+1 {
+			t.Fatalf("expected FIFO order [1 2 3 4 5], got %v", order.vals)
+		}
+	}
+}
+
+func TestSubmitWait_StopReleasesWaiters(t *testing.T) {
+	gate := make(chan struct{})
+	p := singleSlotPool(t, BackpressureReject, gate)
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := p.SubmitWait(context.Background(), successJob{})
+		errCh <- err
+	}()
+	waitFor(t, func() bool { return waiting(p) == 1 })
+
+	close(gate)
+	p.Stop()
+
+	// The waiter is either admitted before Stop or turned away by it.
+	select {
+	case err := <-errCh:
+		if err != nil && !errors.Is(err, ErrPoolStopped) {
+			t.Fatalf("expected nil or ErrPoolStopped, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected Stop to release blocked submitters")
+	}
+}
+
+func TestTrySubmitFor_TimesOut(t *testing.T) {
+	gate := make(chan struct{})
+	p := singleSlotPool(t, BackpressureReject, gate)
+	defer p.Stop()
+	defer close(gate)
+
+	start := time.Now()
+	_, err := p.TrySubmitFor(successJob{}, 30*time.Millisecond)
+	if !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("expected ErrQueueFull, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Fatalf("expected TrySubmitFor to wait for the timeout, returned after %v", elapsed)
+	}
+}
+
+func TestTrySubmitFor_SucceedsWhenSpaceFrees(t *testing.T) {
+	gate := make(chan struct{})
+	p := singleSlotPool(t, BackpressureReject, gate)
+	defer p.Stop()
+
+	time.AfterFunc(20*time.Millisecond, func() { close(gate) })
+	ch, err := p.TrySubmitFor(successJob{val: 3}, 2*time.Second)
+	if err != nil {
+		t.Fatalf("TrySubmitFor: unexpected error: %v", err)
+	}
+	if res := waitResult(t, ch); res.Value != 3 {
+		t.Fatalf("expected value 3, got %d", res.Value)
+	}
+}
+
+func TestBackpressure_Block(t *testing.T) {
+	gate := make(chan struct{})
+	p := singleSlotPool(t, BackpressureBlock, gate)
+	defer p.Stop()
+
+	time.AfterFunc(20*time.Millisecond, func() { close(gate) })
+	ch, err := p.Submit(successJob{val: 5})
+	if err != nil {
+		t.Fatalf("Submit: unexpected error: %v", err)
+	}
+	if res := waitResult(t, ch); res.Value != 5 {
+		t.Fatalf("expected value 5, got %d", res.Value)
+	}
+}
+
+func TestBackpressure_CallerRuns(t *testing.T) {
+	gate := make(chan struct{})
+	p := singleSlotPool(t, BackpressureCallerRuns, gate)
+	defer p.Stop()
+	defer close(gate)
+
+	ch, err := p.Submit(successJob{val: 9})
+	if err != nil {
+		t.Fatalf("Submit: unexpected error: %v", err)
+	}
+	select {
+	case res := <-ch:
+		if res.Value != 9 {
+			t.Fatalf("expected value 9, got %d", res.Value)
+		}
+	default:
+		t.Fatal("expected the result to be ready when Submit returns")
+	}
+	if s := p.Stats(); s.CallerRan != 1 {
+		t.Fatalf("expected CallerRan=1, got %d", s.CallerRan)
+	}
+}
+
+func TestBackpressure_DropOldest(t *testing.T) {
+	gate := make(chan struct{})
+	p := New[int](Config{
+		MinWorkers:    1,
+		MaxWorkers:    1,
+		QueueSize:     2,
+		IdleTimeout:   time.Second,
+		ScaleInterval: time.Second,
+		Backpressure:  BackpressureDropOldest,
+	})
+	p.Start()
+	defer p.Stop()
+
+	p.Submit(gateJob{gate: gate}) //nolint
+	waitFor(t, func() bool { return p.Stats().QueueDepth == 0 })
+
+	oldest, _ := p.Submit(prioJob{val: 1, prio: PriorityLow})
+	p.Submit(prioJob{val: 2, prio: PriorityLow}) //nolint
+	newest, err := p.Submit(successJob{val: 3})
+	if err != nil {
+		t.Fatalf("Submit: unexpected error: %v", err)
+	}
+
+	if res := waitResult(t, oldest); !errors.Is(res.Err, ErrJobEvicted) {
+		t.Fatalf("expected ErrJobEvicted, got %v", res.Err)
+	}
+
+	close(gate)
+	if res := waitResult(t, newest); res.Err != nil || res.Value != 3 {
+		t.Fatalf("expected value 3, got %d (%v)", res.Value, res.Err)
+	}
+	if s := p.Stats(); s.Evicted != 1 {
+		t.Fatalf("expected Evicted=1, got %d", s.Evicted)
+	}
+}
+
+// ===========================================================================
+// Priority
+// ===========================================================================
+
+// prioJob is a successJob with a priority.
+type prioJob struct {
+	val  int
+	prio Priority
+}
+
+func (j prioJob) Execute(_ context.Context) (int, error) { return j.val, nil }
+
+func (j prioJob) Priority() Priority { return j.prio }
+
+// newTestQueue returns a queue with cfg's defaults applied.
+func newTestQueue(cfg Config) *jobQueue[int] {
+	cfg.applyDefaults()
+	return newJobQueue[int](cfg)
+}
+
+func pushPrio(t *testing.T, q *jobQueue[int], val int, prio Priority) {
+	t.Helper()
+	env := &jobEnvelope[int]{job: prioJob{val: val, prio: prio}, ctx: context.Background()}
+	if ok, err := q.tryPush(env); !ok || err != nil {
+		t.Fatalf("tryPush: expected ok, got %v, %v", ok, err)
+	}
+}
+
+func popVals(q *jobQueue[int], n int) []int {
+	vals := make([]int, 0, n)
+	for range n {
+		env, ok := q.pop()
+		if !ok {
+			break
+		}
+		vals = append(vals, env.job.(prioJob).val)
+	}
+	return vals
+}
+
+func TestConfig_DefaultWeights(t *testing.T) {
+	cfg := Config{Weights: []int{2, 0}}
+	cfg.applyDefaults()
+	want := []int{2, 4, 16}
+	for i, w := range want {
+		if cfg.Weights[i] != w {
+			t.Fatalf("expected Weights %v, got %v", want, cfg.Weights)
+		}
+	}
+}
+
+func TestPriority_DefaultAndClamped(t *testing.T) {
+	if p := priorityOf(successJob{}); p != PriorityNormal {
+		t.Errorf("expected PriorityNormal, got %d", p)
+	}
+	if p := priorityOf(prioJob{prio: 99}); p != PriorityHigh {
+		t.Errorf("expected PriorityHigh, got %d", p)
+	}
+	if p := priorityOf(prioJob{prio: -5}); p != PriorityLow {
+		t.Errorf("expected PriorityLow, got %d", p)
+	}
+}
+
+func TestPriority_StrictDispatch(t *testing.T) {
+	q := newTestQueue(Config{QueueSize: 10, Dispatch: DispatchStrict})
+	pushPrio(t, q, 1, PriorityLow)
+	pushPrio(t, q, 2, PriorityNormal)
+	pushPrio(t, q, 3, PriorityHigh)
+	pushPrio(t, q, 4, PriorityLow)
+	pushPrio(t, q, 5, PriorityHigh)
+
+	got := popVals(q, 5)
+	want := []int{3, 5, 2, 1, 4}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected order %v, got %v", want, got)
+		}
+	}
+}
+
+func TestPriority_WeightedDispatch(t *testing.T) {
+	q := newTestQueue(Config{QueueSize: 100, Weights: []int{1, 1, 3}})
+	for range 20 {
+		pushPrio(t, q, 0, PriorityLow)
+		pushPrio(t, q, 2, PriorityHigh)
+	}
+
+	// Over 8 dispatches high should get 3 of every 4.
+	counts := map[int]int{}
+	for _, v := range popVals(q, 8) {
+		counts[v]++
+	}
+	if counts[2] != 6 || counts[0] != 2 {
+		t.Fatalf("expected 6 high and 2 low dispatches, got %d high and %d low", counts[2], counts[0])
+	}
+}
+
+func TestPriority_AgingPromotes(t *testing.T) {
+	q := newTestQueue(Config{QueueSize: 10, Dispatch: DispatchStrict, AgingInterval: time.Minute})
+	pushPrio(t, q, 2, PriorityNormal)
+	pushPrio(t, q, 1, PriorityLow)
+
+	// Pretend the low job has waited a full interval: the next pop moves it
+	// to the normal level, ahead of normal jobs submitted afterwards.
+	q.levels[PriorityLow].peek().levelSince = time.Now().Add(-time.Minute)
+
+	got := popVals(q, 1)
+	pushPrio(t, q, 5, PriorityNormal)
+	got = append(got, popVals(q, 2)...)
+
+	want := []int{2, 1, 5}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected order %v, got %v", want, got)
+		}
+	}
+}
+
+func TestPriority_DropOldestEvictsLowest(t *testing.T) {
+	q := newTestQueue(Config{QueueSize: 2})
+	pushPrio(t, q, 1, PriorityHigh)
+	pushPrio(t, q, 2, PriorityLow)
+
+	env := &jobEnvelope[int]{job: prioJob{val: 3, prio: PriorityHigh}, ctx: context.Background()}
+	evicted, err := q.pushOrEvict(env)
+	if err != nil {
+		t.Fatalf("pushOrEvict: unexpected error: %v", err)
+	}
+	if v := evicted.job.(prioJob).val; v != 2 {
+		t.Fatalf("expected low-priority job 2 to be evicted, got %d", v)
+	}
+}
+
+func TestPriority_DropOldestRejectsWithoutLowerPriority(t *testing.T) {
+	q := newTestQueue(Config{QueueSize: 2})
+	pushPrio(t, q, 1, PriorityHigh)
+	pushPrio(t, q, 2, PriorityNormal)
+
+	env := &jobEnvelope[int]{job: prioJob{val: 3, prio: PriorityNormal}, ctx: context.Background()}
+	evicted, err := q.pushOrEvict(env)
+	if !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("expected ErrQueueFull, got %v", err)
+	}
+	if evicted != nil {
+		t.Fatalf("expected no eviction, got job %d", evicted.job.(prioJob).val)
+	}
+	if got := popVals(q, 2); got[0] != 1 || got[1] != 2 {
+		t.Fatalf("expected queued jobs [1 2] to remain, got %v", got)
+	}
+}
+
+func TestStats_QueueDepthByPriority(t *testing.T) {
+	gate := make(chan struct{})
+	p := New[int](Config{
+		MinWorkers:    1,
+		MaxWorkers:    1,
+		QueueSize:     10,
+		IdleTimeout:   time.Second,
+		ScaleInterval: time.Second,
+	})
+	p.Start()
+	defer p.Stop()
+	defer close(gate)
+
+	p.Submit(gateJob{gate: gate}) //nolint
+	waitFor(t, func() bool { return p.Stats().QueueDepth == 0 })
+
+	p.Submit(prioJob{prio: PriorityHigh}) //nolint
+	p.Submit(prioJob{prio: PriorityHigh}) //nolint
+	p.Submit(successJob{})                //nolint
+
+	s := p.Stats()
+	want := [NumPriorities]int{0, 1, 2}
+	if s.QueueDepthByPriority != want {
+		t.Fatalf("expected QueueDepthByPriority %v, got %v", want, s.QueueDepthByPriority)
+	}
+}
+
+// ===========================================================================
+// Retry / OnComplete
+// ===========================================================================
+
+// flakyJob fails until it has been attempted `failures` times.
+type flakyJob struct {
+	failures int
+	attempts *atomic.Int32
+	err      error
+	policy   *RetryPolicy
+}
+
+func (j flakyJob) Execute(_ context.Context) (int, error) {
+	n := int(j.attempts.Add(1))
+	if n <= j.failures {
+		return 0, j.err
+	}
+	return n, nil
+}
+
+// retrierJob is a flakyJob with its own retry policy.
+type retrierJob struct{ flakyJob }
+
+func (j retrierJob) RetryPolicy() *RetryPolicy { return j.policy }
+
+func retryCfg(policy *RetryPolicy) Config {
+	cfg := fastCfg()
+	cfg.Retry = policy
+	return cfg
+}
+
+func TestRetryPolicy_Defaults(t *testing.T) {
+	var r RetryPolicy
+	r.applyDefaults()
+	if r.MaxAttempts != 3 {
+		t.Errorf("expected MaxAttempts=3, got %d", r.MaxAttempts)
+	}
+	if r.BaseDelay != 100*time.Millisecond {
+		t.Errorf("expected BaseDelay=100ms, got %v", r.BaseDelay)
+	}
+	if r.MaxDelay != 10*time.Second {
+		t.Errorf("expected MaxDelay=10s, got %v", r.MaxDelay)
+	}
+	if r.Jitter != 0.2 {
+		t.Errorf("expected Jitter=0.2, got %v", r.Jitter)
+	}
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	r := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second, Jitter: -1}
+	r.applyDefaults()
+
+	want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second}
+	for i, w := range want {
+		if got := r.backoff(i); got != w {
+			t.Errorf("retry %d: expected %v, got %v", i, w, got)
+		}
+	}
+}
+
+func TestRetryPolicy_BackoffJitter(t *testing.T) {
+	r := RetryPolicy{BaseDelay: 100 * time.Millisecond, Jitter: 0.5}
+	r.applyDefaults()
+
+	for range 100 {
+		if d := r.backoff(0); d < 50*time.Millisecond || d > 100*time.Millisecond {
+			t.Fatalf("expected delay in [50ms, 100ms], got %v", d)
+		}
+	}
+}
+
+func TestRetry_SucceedsAfterFailures(t *testing.T) {
+	p := New[int](retryCfg(&RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}))
+	p.Start()
+	defer p.Stop()
+
+	var attempts atomic.Int32
+	ch, _ := p.Submit(flakyJob{failures: 2, attempts: &attempts, err: errors.New("flaky")})
+	res := waitResult(t, ch)
+	if res.Err != nil || res.Value != 3 {
+		t.Fatalf("expected success on attempt 3, got %d (%v)", res.Value, res.Err)
+	}
+
+	s := p.Stats()
+	if s.Retried != 2 || s.RetriesExhausted != 0 || s.Processed != 1 || s.Failed != 0 {
+		t.Fatalf("expected Retried=2 RetriesExhausted=0 Processed=1 Failed=0, got %+v", s)
+	}
+}
+
+func TestRetry_Exhausted(t *testing.T) {
+	p := New[int](retryCfg(&RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond}))
+	p.Start()
+	defer p.Stop()
+
+	jobErr := errors.New("down")
+	var attempts atomic.Int32
+	ch, _ := p.Submit(flakyJob{failures: 5, attempts: &attempts, err: jobErr})
+	if res := waitResult(t, ch); !errors.Is(res.Err, jobErr) {
+		t.Fatalf("expected job error, got %v", res.Err)
+	}
+	if n := attempts.Load(); n != 2 {
+		t.Fatalf("expected 2 attempts, got %d", n)
+	}
+	if s := p.Stats(); s.Retried != 1 || s.RetriesExhausted != 1 {
+		t.Fatalf("expected Retried=1 RetriesExhausted=1, got %+v", s)
+	}
+}
+
+func TestRetry_SingleAttemptNotExhausted(t *testing.T) {
+	p := New[int](retryCfg(&RetryPolicy{MaxAttempts: 1, BaseDelay: time.Millisecond}))
+	p.Start()
+	defer p.Stop()
+
+	jobErr := errors.New("down")
+	var attempts atomic.Int32
+	ch, _ := p.Submit(flakyJob{failures: 5, attempts: &attempts, err: jobErr})
+	if res := waitResult(t, ch); !errors.Is(res.Err, jobErr) {
+		t.Fatalf("expected job error, got %v", res.Err)
+	}
+	if n := attempts.Load(); n != 1 {
+		t.Fatalf("expected 1 attempt, got %d", n)
+	}
+	if s := p.Stats(); s.Retried != 0 || s.RetriesExhausted != 0 || s.Failed != 1 {
+		t.Fatalf("expected Retried=0 RetriesExhausted=0 Failed=1, got %+v", s)
+	}
+}
+
+func TestRetry_NotRetryable(t *testing.T) {
+	permanent := errors.New("permanent")
+	p := New[int](retryCfg(&RetryPolicy{
+		MaxAttempts: 5,
+		BaseDelay:   time.Millisecond,
+		Retryable:   func(err error) bool { return !errors.Is(err, permanent) },
+	}))
+	p.Start()
+	defer p.Stop()
+
+	var attempts atomic.Int32
+	ch, _ := p.Submit(flakyJob{failures: 5, attempts: &attempts, err: permanent})
+	waitResult(t, ch)
+	if n := attempts.Load(); n != 1 {
+		t.Fatalf("expected 1 attempt, got %d", n)
+	}
+}
+
+func TestRetry_PerJobPolicy(t *testing.T) {
+	p := New[int](retryCfg(&RetryPolicy{MaxAttempts: 5, BaseDelay: time.Millisecond}))
+	p.Start()
+	defer p.Stop()
+
+	// A nil policy disables the pool-wide retries.
+	var attempts atomic.Int32
+	ch, _ := p.Submit(retrierJob{flakyJob{failures: 5, attempts: &attempts, err: errors.New("x")}})
+	waitResult(t, ch)
+	if n := attempts.Load(); n != 1 {
+		t.Fatalf("expected 1 attempt with retries disabled, got %d", n)
+	}
+
+	attempts.Store(0)
+	job := retrierJob{flakyJob{failures: 5, attempts: &attempts, err: errors.New("x"),
+		policy: &RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond}}}
+	ch, _ = p.Submit(job)
+	waitResult(t, ch)
+	if n := attempts.Load(); n != 2 {
+		t.Fatalf("expected 2 attempts from the job's policy, got %d", n)
+	}
+}
+
+func TestRetry_PerSubmissionPolicy(t *testing.T) {
+	p := New[int](retryCfg(&RetryPolicy{MaxAttempts: 5, BaseDelay: time.Millisecond}))
+	p.Start()
+	defer p.Stop()
+
+	// The submission's policy wins over the job's own.
+	var attempts atomic.Int32
+	job := retrierJob{flakyJob{failures: 5, attempts: &attempts, err: errors.New("x"),
+		policy: &RetryPolicy{MaxAttempts: 4, BaseDelay: time.Millisecond}}}
+	ch, _ := p.SubmitCtx(context.Background(), job, WithRetry(&RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond}))
+	waitResult(t, ch)
+	if n := attempts.Load(); n != 2 {
+		t.Fatalf("expected 2 attempts from the submission's policy, got %d", n)
+	}
+
+	// A nil policy disables the pool-wide retries.
+	attempts.Store(0)
+	ch, _ = p.SubmitCtx(context.Background(), flakyJob{failures: 5, attempts: &attempts, err: errors.New("x")}, WithRetry(nil))
+	waitResult(t, ch)
+	if n := attempts.Load(); n != 1 {
+		t.Fatalf("expected 1 attempt with retries disabled, got %d", n)
+	}
+
+	// Other submissions keep the pool-wide policy.
+	attempts.Store(0)
+	ch, _ = p.Submit(flakyJob{failures: 5, attempts: &attempts, err: errors.New("x")})
+	waitResult(t, ch)
+	if n := attempts.Load(); n != 5 {
+		t.Fatalf("expected 5 attempts from Config.Retry, got %d", n)
+	}
+}
+
+func TestRetry_StopsWhenCtxCancelled(t *testing.T) {
+	p := New[int](retryCfg(&RetryPolicy{MaxAttempts: 10, BaseDelay: time.Hour}))
+	p.Start()
+	defer p.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var attempts atomic.Int32
+	ch, _ := p.SubmitCtx(ctx, flakyJob{failures: 10, attempts: &attempts, err: errors.New("x")})
+	waitFor(t, func() bool { return attempts.Load() == 1 })
+	cancel()
+
+	if res := waitResult(t, ch); res.Err == nil {
+		t.Fatal("expected the last attempt's error, got nil")
+	}
+	if n := attempts.Load(); n != 1 {
+		t.Fatalf("expected no retry after cancellation, got %d attempts", n)
+	}
+}
+
+func TestSubmitOnComplete_CalledOnce(t *testing.T) {
+	p := New[int](fastCfg())
+	p.Start()
+	defer p.Stop()
+
+	results := make(chan Result[int], 2)
+	err := p.SubmitOnComplete(context.Background(), successJob{val: 11}, func(r Result[int]) {
+		results <- r
+	})
+	if err != nil {
+		t.Fatalf("SubmitOnComplete: unexpected error: %v", err)
+	}
+
+	if res := waitResult(t, results); res.Value != 11 {
+		t.Fatalf("expected value 11, got %d", res.Value)
+	}
+	select {
+	case <-results:
+		t.Fatal("expected callback to be called only once")
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestSubmitOnComplete_NotCalledWhenRejected(t *testing.T) {
+	p := New[int](fastCfg())
+	// Don't start — the submission is rejected.
+	called := false
+	err := p.SubmitOnComplete(context.Background(), successJob{}, func(Result[int]) { called = true })
+	if !errors.Is(err, ErrPoolStopped) {
+		t.Fatalf("expected ErrPoolStopped, got %v", err)
+	}
+	if called {
+		t.Fatal("expected callback not to be called for a rejected submission")
+	}
+}
+
+func TestSubmitOnComplete_CancelledWhileQueued(t *testing.T) {
+	gate := make(chan struct{})
+	p := singleSlotPool(t, BackpressureReject, gate)
+	defer p.Stop()
+	defer close(gate)
+
+	// Free the queue slot held by singleSlotPool's second job.
+	p.queue.pop()
+	p.queue.finish()
+
+	results := make(chan Result[int], 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := p.SubmitOnComplete(ctx, successJob{}, func(r Result[int]) { results <- r }); err != nil {
+		t.Fatalf("SubmitOnComplete: unexpected error: %v", err)
+	}
+	cancel()
+
+	if res := waitResult(t, results); !errors.Is(res.Err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", res.Err)
+	}
+}
+
+func TestSubmitOnComplete_PanickingCallbackRecovered(t *testing.T) {
+	p := New[int](Config{MinWorkers: 1, MaxWorkers: 1, IdleTimeout: time.Second, ScaleInterval: time.Second})
+	p.Start()
+	defer p.Stop()
+
+	p.SubmitOnComplete(context.Background(), successJob{}, func(Result[int]) { panic("callback") }) //nolint
+
+	// The worker survives and keeps processing.
+	ch, _ := p.Submit(successJob{val: 1})
+	if res := waitResult(t, ch); res.Value != 1 {
+		t.Fatalf("expected value 1, got %d", res.Value)
+	}
+	if s := p.Stats(); s.Panics != 0 {
+		t.Fatalf("expected callback panic not to count as a job panic, got Panics=%d", s.Panics)
+	}
+}
+
+// ===========================================================================
+// Error handling
+// ===========================================================================
+
+func TestJob_ReturnsError_CountedAsFailed(t *testing.T) {
+	p := New[int](fastCfg())
+	p.Start()
+	defer p.Stop()
+
+	sentinel := errors.New("job error")
+	ch, err := p.Submit(errorJob{err: sentinel})
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+
+	res := <-ch
+	if !errors.Is(res.Err, sentinel) {
+		t.Fatalf("expected sentinel error, got %v", res.Err)
+	}
+
+	snap := p.Stats()
+	if snap.Failed < 1 {
+		t.Errorf("expected Failed >= 1, got %d", snap.Failed)
+	}
+}
+
+func TestJob_Panic_RecoveredAndCountedAsPanic(t *testing.T) {
+	p := New[int](fastCfg())
+	p.Start()
+	defer p.Stop()
+
+	ch, err := p.Submit(panicJob{})
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+
+	res := <-ch
+	if res.Err == nil {
+		t.Fatal("expected error from panicking job")
+	}
+
+	snap := p.Stats()
+	if snap.Panics < 1 {
+		t.Errorf("expected Panics >= 1, got %d", snap.Panics)
+	}
+}
+
+func TestJob_Panic_WorkerSurvives(t *testing.T) {
+	p := New[int](fastCfg())
+	p.Start()
+	defer p.Stop()
+
+	// Submit a panic job.
+	ch, _ := p.Submit(panicJob{})
+	<-ch
+
+	// The pool must still process subsequent jobs.
+	ch2, err := p.Submit(successJob{val: 7})
+	if err != nil {
+		t.Fatalf("Submit after panic: %v", err)
+	}
+	res := <-ch2
+	if res.Err != nil || res.Value != 7 {
+		t.Fatalf("expected (7, nil) after panic recovery, got (%d, %v)", res.Value, res.Err)
+	}
+}
+
+// ===========================================================================
+// Dynamic scaling
+// ===========================================================================
+
+func TestScaling_SpawnsWorkersUnderLoad(t *testing.T) {
+	cfg := Config{
+		MinWorkers:    1,
+		MaxWorkers:    8,
+		QueueSize:     200,
+		IdleTimeout:   500 * time.Millisecond,
+		ScaleInterval: 10 * time.Millisecond,
+	}
+	p := New[int](cfg)
+	p.Start()
+	defer p.Stop()
+
+	initialSnap := p.Stats()
+	if initialSnap.ActiveWorkers != 1 {
+		t.Fatalf("expected 1 initial worker, got %d", initialSnap.ActiveWorkers)
+	}
+
+	// Submit a burst of slow jobs to trigger scale-up.
+	for i := 0; i < 50; i++ {
+		p.Submit(slowJob{}) //nolint
+	}
+
+	// Allow scaler to react.
+	deadline := time.Now().Add(500 * time.Millisecond)
+	for time.Now().Before(deadline) {
+		snap := p.Stats()
+		if snap.ActiveWorkers > 1 {
+			return // scaled up
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Errorf("expected ActiveWorkers to grow above 1, got %d", p.Stats().ActiveWorkers)
+}
+
+func TestScaling_ShrinksWorkersWhenIdle(t *testing.T) {
+	cfg := Config{
+		MinWorkers:    1,
+		MaxWorkers:    4,
+		QueueSize:     100,
+		IdleTimeout:   50 * time.Millisecond,
+		ScaleInterval: 10 * time.Millisecond,
+	}
+	p := New[int](cfg)
+	p.Start()
+	defer p.Stop()
+
+	// Submit burst of quick jobs to scale up.
+	var chans []<-chan Result[int]
+	for i := 0; i < 20; i++ {
+		ch, err := p.Submit(successJob{val: i})
+		if err == nil {
+			chans = append(chans, ch)
+		}
+	}
+	// Drain results.
+	for _, ch := range chans {
+		<-ch
+	}
+
+	// After IdleTimeout, workers above MinWorkers should exit.
+	deadline := time.Now().Add(500 * time.Millisecond)
+	for time.Now().Before(deadline) {
+		snap := p.Stats()
+		if snap.ActiveWorkers <= cfg.MinWorkers {
+			return // scaled down
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Errorf("expected ActiveWorkers to shrink to %d, got %d", cfg.MinWorkers, p.Stats().ActiveWorkers)
+}
+
+func TestScaling_CustomScaler(t *testing.T) {
+	var target atomic.Int32
+	target.Store(4)
+	cfg := fastCfg()
+	cfg.MinWorkers = 1
+	cfg.IdleTimeout = time.Minute
+	cfg.Scaler = ScalerFunc(func(m ScaleMetrics) int { return int(target.Load()) })
+	p := New[int](cfg)
+	p.Start()
+	defer p.Stop()
+
+	waitFor(t, func() bool { return p.Stats().ActiveWorkers == 4 })
+	if snap := p.Stats(); snap.ScaleUps == 0 {
+		t.Errorf("expected a scale-up event, got %+v", snap)
+	}
+
+	target.Store(100) // clamped to MaxWorkers
+	waitFor(t, func() bool { return p.Stats().ActiveWorkers == cfg.MaxWorkers })
+
+	target.Store(0) // clamped to MinWorkers
+	waitFor(t, func() bool { return p.Stats().ActiveWorkers == 1 })
+	if snap := p.Stats(); snap.ScaleDowns == 0 {
+		t.Errorf("expected a scale-down event, got %+v", snap)
+	}
+}
+
+func TestScaling_ThresholdScalerHysteresis(t *testing.T) {
+	s := &ThresholdScaler{High: 10, Low: 2, Step: 2, UpAfter: 2, DownAfter: 3}
+	m := ScaleMetrics{Workers: 4, Busy: 4}
+
+	steps := []struct {
+		depth, busy, want int
+	}{
+		{10, 4, 4}, // first check above High
+		{12, 4, 6}, // second: grow by Step
+		{10, 4, 4}, // counter restarts after acting
+		{5, 4, 4},  // between thresholds resets both counters
+		{10, 4, 4},
+		{0, 4, 4},
+		{1, 4, 4},
+		{2, 1, 2}, // third check at or below Low: shrink by Step
+		{0, 3, 4}, // counter restarts after acting
+		{0, 3, 4},
+		{0, 3, 3}, // only idle workers are retired
+	}
+	for i, st := range steps {
+		m.QueueDepth, m.Busy = st.depth, st.busy
+		if got := s.Target(m); got != st.want {
+			t.Fatalf("check %d (depth %d): expected target %d, got %d", i, st.depth, st.want, got)
+		}
+	}
+}
+
+func TestScaling_ThresholdScalerDefaults(t *testing.T) {
+	s := &ThresholdScaler{}
+	if got := s.Target(ScaleMetrics{Workers: 2, QueueDepth: 1}); got != 3 {
+		t.Errorf("expected one queued job to add a worker, got target %d", got)
+	}
+	for i := range 9 {
+		if got := s.Target(ScaleMetrics{Workers: 3}); got != 3 {
+			t.Fatalf("check %d: expected no scale-down yet, got target %d", i, got)
+		}
+	}
+	if got := s.Target(ScaleMetrics{Workers: 3}); got != 2 {
+		t.Errorf("expected a scale-down after 10 idle checks, got target %d", got)
+	}
+}
+
+func TestScaling_LatencyScalerTarget(t *testing.T) {
+	s := LatencyScaler{MaxWait: 100 * time.Millisecond}
+	cases := []struct {
+		name string
+		m    ScaleMetrics
+		want int
+	}{
+		{"empty queue", ScaleMetrics{Workers: 3}, 3},
+		{"no measurements", ScaleMetrics{Workers: 2, QueueDepth: 5}, 7},
+		{"within target", ScaleMetrics{Workers: 4, QueueDepth: 10, MeanDuration: 10 * time.Millisecond}, 4},
+		{"behind target", ScaleMetrics{Workers: 4, QueueDepth: 50, MeanDuration: 10 * time.Millisecond}, 5},
+		{"rounds up", ScaleMetrics{Workers: 1, QueueDepth: 21, MeanDuration: 50 * time.Millisecond}, 11},
+	}
+	for _, tc := range cases {
+		if got := s.Target(tc.m); got != tc.want {
+			t.Errorf("%s: expected target %d, got %d", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestScaling_MetricsPassedToScaler(t *testing.T) {
+	gate := make(chan struct{})
+	seen := make(chan ScaleMetrics, 100)
+	cfg := Config{
+		MinWorkers:    1,
+		MaxWorkers:    3,
+		QueueSize:     10,
+		IdleTimeout:   time.Minute,
+		ScaleInterval: 10 * time.Millisecond,
+		Scaler: ScalerFunc(func(m ScaleMetrics) int {
+			select {
+			case seen <- m:
+			default:
+			}
+			return m.Workers
+		}),
+	}
+	p := New[int](cfg)
+	p.Start()
+	defer p.Stop()
+
+	p.Submit(gateJob{gate: gate}) //nolint
+	p.Submit(gateJob{gate: gate}) //nolint
+	waitFor(t, func() bool {
+		m := <-seen
+		return m.Workers == 1 && m.Busy == 1 && m.QueueDepth == 1 &&
+			m.MinWorkers == 1 && m.MaxWorkers == 3
+	})
+	close(gate)
+	waitFor(t, func() bool {
+		m := <-seen
+		return m.MeanDuration > 0 && m.QueueDepth == 0
+	})
+}
+
+func TestResize_GrowsAndShrinks(t *testing.T) {
+	cfg := fastCfg()
+	cfg.MinWorkers, cfg.MaxWorkers = 1, 2
+	cfg.IdleTimeout = time.Minute
+	cfg.ScaleInterval = time.Hour // only Resize changes the size
+	p := New[int](cfg)
+	p.Start()
+	defer p.Stop()
+
+	if err := p.Resize(4, 6); err != nil {
+		t.Fatalf("Resize: unexpected error: %v", err)
+	}
+	if got := p.Stats().ActiveWorkers; got != 4 {
+		t.Fatalf("expected Resize to spawn up to the new minimum of 4, got %d", got)
+	}
+
+	// A busy worker finishes its job before retiring.
+	gate := make(chan struct{})
+	ch, _ := p.Submit(gateJob{gate: gate, val: 7})
+	waitFor(t, func() bool { return p.queue.runningCount() == 1 })
+	if err := p.Resize(1, 1); err != nil {
+		t.Fatalf("Resize: unexpected error: %v", err)
+	}
+	waitFor(t, func() bool { return p.Stats().ActiveWorkers == 1 })
+	close(gate)
+	if res := waitResult(t, ch); res.Err != nil || res.Value != 7 {
+		t.Fatalf("expected the running job to finish, got %+v", res)
+	}
+
+	snap := p.Stats()
+	if snap.ScaleUps != 1 || snap.ScaleDowns != 1 {
+		t.Errorf("expected 1 scale-up and 1 scale-down, got %d and %d", snap.ScaleUps, snap.ScaleDowns)
+	}
+
+	// The pool keeps working at its new size.
+	ch, _ = p.Submit(successJob{val: 1})
+	if res := waitResult(t, ch); res.Err != nil {
+		t.Fatalf("unexpected error: %v", res.Err)
+	}
+}
+
+func TestResize_InvalidBounds(t *testing.T) {
+	p := New[int](fastCfg())
+	for _, b := range [][2]int{{0, 1}, {3, 2}, {-1, -1}} {
+		if err := p.Resize(b[0], b[1]); !errors.Is(err, ErrInvalidSize) {
+			t.Errorf("Resize(%d, %d): expected ErrInvalidSize, got %v", b[0], b[1], err)
+		}
+	}
+}
+
+func TestResize_BeforeStart(t *testing.T) {
+	p := New[int](fastCfg())
+	if err := p.Resize(3, 3); err != nil {
+		t.Fatalf("Resize: unexpected error: %v", err)
+	}
+	if got := p.Stats().ActiveWorkers; got != 0 {
+		t.Fatalf("expected no workers before Start, got %d", got)
+	}
+	p.Start()
+	defer p.Stop()
+	if got := p.Stats().ActiveWorkers; got != 3 {
+		t.Errorf("expected Start to use the resized minimum of 3, got %d", got)
+	}
+}
+
+// ===========================================================================
+// Stats / Snapshot
+// ===========================================================================
+
+func TestStats_ZeroedBeforeStart(t *testing.T) {
+	p := New[int](fastCfg())
+	snap := p.Stats()
+	if snap.Processed != 0 || snap.Failed != 0 || snap.Panics != 0 {
+		t.Errorf("expected all counters zero before Start, got %+v", snap)
+	}
+}
+
+func TestStats_ProcessedAndFailed_Updated(t *testing.T) {
+	p := New[int](fastCfg())
+	p.Start()
+	defer p.Stop()
+
+	ch1, _ := p.Submit(successJob{val: 1})
+	ch2, _ := p.Submit(errorJob{err: errors.New("e")})
+	<-ch1
+	<-ch2
+
+	snap := p.Stats()
+	if snap.Processed < 2 {
+		t.Errorf("expected Processed >= 2, got %d", snap.Processed)
+	}
+	if snap.Failed < 1 {
+		t.Errorf("expected Failed >= 1, got %d", snap.Failed)
+	}
+}
+
+func TestStats_QueueDepth_ReflectsPending(t *testing.T) {
+	p := New[int](Config{
+		MinWorkers:    1,
+		MaxWorkers:    1,
+		QueueSize:     10,
+		IdleTimeout:   time.Second,
+		ScaleInterval: time.Second,
+	})
+	p.Start()
+	defer p.Stop()
+
+	// Block the worker.
+	p.Submit(slowJob{}) //nolint
+	time.Sleep(20 * time.Millisecond)
+
+	// Queue some jobs.
+	p.Submit(successJob{}) //nolint
+	p.Submit(successJob{}) //nolint
+
+	snap := p.Stats()
+	if snap.QueueDepth < 1 {
+		t.Errorf("expected QueueDepth >= 1, got %d", snap.QueueDepth)
+	}
+}
+
+func TestStats_ActiveWorkers_Accurate(t *testing.T) {
+	cfg := fastCfg()
+	p := New[int](cfg)
+	p.Start()
 	defer p.Stop()
 
 	snap := p.Stats()
@@ -469,3 +1727,69 @@ func TestStats_ActiveWorkers_Accurate(t *testing.T) {
 		t.Errorf("expected ActiveWorkers == MinWorkers (%d), got %d", cfg.MinWorkers, snap.ActiveWorkers)
 	}
 }
+
+func TestStats_DurationAndQueueWaitHistograms(t *testing.T) {
+	p := New[int](Config{
+		MinWorkers:    1,
+		MaxWorkers:    1,
+		QueueSize:     10,
+		IdleTimeout:   time.Second,
+		ScaleInterval: time.Second,
+	})
+	p.Start()
+	defer p.Stop()
+
+	var chans []<-chan Result[int]
+	for range 3 {
+		ch, _ := p.Submit(stubbornJob{d: 20 * time.Millisecond})
+		chans = append(chans, ch)
+	}
+	for _, ch := range chans {
+		waitResult(t, ch)
+	}
+
+	snap := p.Stats()
+	if snap.Durations.Count != 3 || snap.QueueWait.Count != 3 {
+		t.Fatalf("expected 3 durations and 3 queue waits, got %d and %d", snap.Durations.Count, snap.QueueWait.Count)
+	}
+	if mean := snap.Durations.Mean(); mean < 20*time.Millisecond {
+		t.Errorf("expected mean duration >= 20ms, got %v", mean)
+	}
+	if p50 := snap.Durations.Quantile(0.5); p50 < 25*time.Millisecond {
+		t.Errorf("expected p50 duration in the 25ms bucket or above, got %v", p50)
+	}
+	// The last job queued behind two 20ms jobs.
+	if max := snap.QueueWait.Quantile(1); max < 25*time.Millisecond {
+		t.Errorf("expected the longest queue wait >= 25ms, got %v", max)
+	}
+}
+
+func TestHistogram_Quantile(t *testing.T) {
+	h := Histogram{
+		Bounds: []time.Duration{time.Millisecond, 10 * time.Millisecond, 100 * time.Millisecond},
+		Counts: []int64{5, 3, 1, 1},
+		Count:  10,
+		Sum:    250 * time.Millisecond,
+	}
+	cases := []struct {
+		q    float64
+		want time.Duration
+	}{
+		{0, time.Millisecond},
+		{0.5, time.Millisecond},
+		{0.8, 10 * time.Millisecond},
+		{0.9, 100 * time.Millisecond},
+		{1, 100 * time.Millisecond}, // overflow reports the largest bound
+	}
+	for _, tc := range cases {
+		if got := h.Quantile(tc.q); got != tc.want {
+			t.Errorf("Quantile(%v): expected %v, got %v", tc.q, tc.want, got)
+		}
+	}
+	if got := h.Mean(); got != 25*time.Millisecond {
+		t.Errorf("expected mean 25ms, got %v", got)
+	}
+	if got := (Histogram{}).Quantile(0.5); got != 0 {
+		t.Errorf("expected 0 for an empty histogram, got %v", got)
+	}
+}
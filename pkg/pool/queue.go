@@ -0,0 +1,337 @@
+package pool
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// jobQueue is the bounded queue workers pull from. It keeps one FIFO per
+// priority level and picks the next level by Config.Dispatch, promoting jobs
+// that have waited Config.AgingInterval at their level. Submitters that block
+// for space wait in a FIFO line of their own: whenever a worker frees a
+// slot, the longest-waiting submitter is admitted before anyone else.
+type jobQueue[T any] struct {
+	mu       sync.Mutex
+	levels   [NumPriorities]fifo[T]
+	size     int
+	waiters  list.List // of *waiter[T], oldest first
+	closed   bool
+	capacity int
+
+	dispatch Dispatch
+	weights  [NumPriorities]int
+	aging    time.Duration
+
+	// credit holds the smooth weighted round-robin state per level.
+	credit [NumPriorities]int
+
+	// running counts envelopes popped by workers and not yet finished.
+	running int
+
+	// ready is signalled (without blocking) whenever an envelope is queued,
+	// waking one idle worker.
+	ready chan struct{}
+
+	// idle is closed once the queue is closed and no envelopes remain
+	// queued or running.
+	idle       chan struct{}
+	idleClosed bool
+}
+
+// waiter is a submitter blocked until there is room in the queue.
+type waiter[T any] struct {
+	env *jobEnvelope[T]
+
+	// done is closed once the waiter leaves the line; admitted reports
+	// whether env was queued. Both are set under jobQueue.mu.
+	done     chan struct{}
+	admitted bool
+	elem     *list.Element
+}
+
+func newJobQueue[T any](cfg Config) *jobQueue[T] {
+	q := &jobQueue[T]{
+		capacity: cfg.QueueSize,
+		dispatch: cfg.Dispatch,
+		aging:    cfg.AgingInterval,
+		ready:    make(chan struct{}, 1),
+		idle:     make(chan struct{}),
+	}
+	copy(q.weights[:], cfg.Weights)
+	return q
+}
+
+// Len returns the number of queued envelopes.
+func (q *jobQueue[T]) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.size
+}
+
+// depths returns the number of queued envelopes per priority level.
+func (q *jobQueue[T]) depths() [NumPriorities]int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var d [NumPriorities]int
+	for i := range q.levels {
+		d[i] = q.levels[i].len()
+	}
+	return d
+}
+
+// tryPush queues env if there is room and nobody is waiting ahead of it.
+func (q *jobQueue[T]) tryPush(env *jobEnvelope[T]) (bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return false, ErrPoolStopped
+	}
+	if q.size == q.capacity || q.waiters.Len() > 0 {
+		return false, nil
+	}
+	q.pushLocked(env)
+	return true, nil
+}
+
+// pushOrEvict queues env. If the queue is full it evicts the oldest
+// envelope of the lowest non-empty priority below env's, and returns it;
+// with nothing of lower priority queued it returns ErrQueueFull.
+func (q *jobQueue[T]) pushOrEvict(env *jobEnvelope[T]) (*jobEnvelope[T], error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return nil, ErrPoolStopped
+	}
+	var evicted *jobEnvelope[T]
+	if q.size == q.capacity {
+		for i := range priorityOf(env.job) {
+			if q.levels[i].len() > 0 {
+				evicted = q.levels[i].pop()
+				q.size--
+				break
+			}
+		}
+		if evicted == nil {
+			return nil, ErrQueueFull
+		}
+	}
+	q.pushLocked(env)
+	return evicted, nil
+}
+
+// wait queues env if possible, otherwise joins the line of waiters. The
+// returned waiter is nil if env was queued immediately.
+func (q *jobQueue[T]) wait(env *jobEnvelope[T]) (*waiter[T], error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return nil, ErrPoolStopped
+	}
+	if q.size < q.capacity && q.waiters.Len() == 0 {
+		q.pushLocked(env)
+		return nil, nil
+	}
+	w := &waiter[T]{env: env, done: make(chan struct{})}
+	w.elem = q.waiters.PushBack(w)
+	return w, nil
+}
+
+// leave removes w from the line if it is still waiting and reports whether
+// its envelope was admitted to the queue in the meantime.
+func (q *jobQueue[T]) leave(w *waiter[T]) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if w.elem != nil {
+		q.waiters.Remove(w.elem)
+		w.elem = nil
+		close(w.done)
+	}
+	return w.admitted
+}
+
+// pop removes the next envelope to run and admits the first waiter in its
+// place.
+func (q *jobQueue[T]) pop() (*jobEnvelope[T], bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.size == 0 {
+		return nil, false
+	}
+	env := q.popLocked()
+	q.running++
+	if front := q.waiters.Front(); front != nil && !q.closed {
+		w := q.waiters.Remove(front).(*waiter[T])
+		w.elem = nil
+		w.admitted = true
+		q.pushLocked(w.env)
+		close(w.done)
+	}
+	if q.size > 0 {
+		q.signal()
+	}
+	return env, true
+}
+
+// finish records that a worker is done with an envelope returned by pop.
+func (q *jobQueue[T]) finish() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.running--
+	q.checkIdleLocked()
+}
+
+// runningCount returns the number of envelopes popped but not finished.
+func (q *jobQueue[T]) runningCount() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.running
+}
+
+// closeIntake rejects further pushes and turns away all waiters, while
+// workers keep popping what is already queued. The returned channel is
+// closed once nothing is left queued or running.
+func (q *jobQueue[T]) closeIntake() <-chan struct{} {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closeIntakeLocked()
+	return q.idle
+}
+
+func (q *jobQueue[T]) closeIntakeLocked() {
+	q.closed = true
+	for e := q.waiters.Front(); e != nil; e = q.waiters.Front() {
+		w := q.waiters.Remove(e).(*waiter[T])
+		w.elem = nil
+		close(w.done)
+	}
+	q.checkIdleLocked()
+}
+
+func (q *jobQueue[T]) checkIdleLocked() {
+	if q.closed && q.size == 0 && q.running == 0 && !q.idleClosed {
+		q.idleClosed = true
+		close(q.idle)
+	}
+}
+
+// close rejects further pushes, turns away all waiters and returns the
+// envelopes still queued.
+func (q *jobQueue[T]) close() []*jobEnvelope[T] {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closeIntakeLocked()
+	remaining := make([]*jobEnvelope[T], 0, q.size)
+	for i := len(q.levels) - 1; i >= 0; i-- {
+		for q.levels[i].len() > 0 {
+			remaining = append(remaining, q.levels[i].pop())
+		}
+	}
+	q.size = 0
+	q.checkIdleLocked()
+	return remaining
+}
+
+func (q *jobQueue[T]) pushLocked(env *jobEnvelope[T]) {
+	env.state.Store(envQueued)
+	env.levelSince = time.Now()
+	env.queuedAt = env.levelSince
+	q.levels[priorityOf(env.job)].push(env)
+	q.size++
+	q.signal()
+}
+
+// popLocked removes the envelope chosen by the dispatch policy. The queue
+// must not be empty.
+func (q *jobQueue[T]) popLocked() *jobEnvelope[T] {
+	q.age()
+
+	var level int
+	if q.dispatch == DispatchStrict {
+		level = q.highestLocked()
+	} else {
+		level = q.weightedLocked()
+	}
+	q.size--
+	return q.levels[level].pop()
+}
+
+// highestLocked returns the highest non-empty level.
+func (q *jobQueue[T]) highestLocked() int {
+	for i := len(q.levels) - 1; i > 0; i-- {
+		if q.levels[i].len() > 0 {
+			return i
+		}
+	}
+	return 0
+}
+
+// weightedLocked picks a non-empty level by smooth weighted round-robin, so
+// each level gets a share of dispatches proportional to its weight.
+func (q *jobQueue[T]) weightedLocked() int {
+	best, total := -1, 0
+	for i := range q.levels {
+		if q.levels[i].len() == 0 {
+			q.credit[i] = 0
+			continue
+		}
+		q.credit[i] += q.weights[i]
+		total += q.weights[i]
+		if best < 0 || q.credit[i] > q.credit[best] {
+			best = i
+		}
+	}
+	q.credit[best] -= total
+	return best
+}
+
+// age promotes jobs that have waited AgingInterval at their level to the
+// tail of the next level up. Each level is ordered by arrival, so only its
+// head needs checking.
+func (q *jobQueue[T]) age() {
+	if q.aging <= 0 {
+		return
+	}
+	now := time.Now()
+	for i := len(q.levels) - 2; i >= 0; i-- {
+		for q.levels[i].len() > 0 && now.Sub(q.levels[i].peek().levelSince) >= q.aging {
+			env := q.levels[i].pop()
+			env.levelSince = now
+			q.levels[i+1].push(env)
+		}
+	}
+}
+
+// signal wakes one idle worker without blocking.
+func (q *jobQueue[T]) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// fifo is a growable FIFO of envelopes.
+type fifo[T any] struct {
+	items []*jobEnvelope[T]
+	head  int
+}
+
+func (f *fifo[T]) len() int { return len(f.items) - f.head }
+
+func (f *fifo[T]) push(env *jobEnvelope[T]) { f.items = append(f.items, env) }
+
+func (f *fifo[T]) peek() *jobEnvelope[T] { return f.items[f.head] }
+
+func (f *fifo[T]) pop() *jobEnvelope[T] {
+	env := f.items[f.head]
+	f.items[f.head] = nil
+	f.head++
+	// Reclaim the consumed prefix once it dominates the slice.
+	if f.head > len(f.items)/2 {
+		n := copy(f.items, f.items[f.head:])
+		clear(f.items[n:])
+		f.items = f.items[:n]
+		f.head = 0
+	}
+	return env
+}
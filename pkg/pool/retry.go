@@ -0,0 +1,130 @@
+package pool
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy re-runs failed jobs with exponential backoff. Set it pool-wide
+// with Config.Retry, per job by implementing Retrier, or per submission
+// with WithRetry. Zero values for fields use sensible defaults.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first.
+	// Default: 3
+	MaxAttempts int
+
+	// BaseDelay is the wait before the first retry; it doubles with each
+	// further retry. Default: 100ms
+	BaseDelay time.Duration
+
+	// MaxDelay caps the backoff. Default: 10s
+	MaxDelay time.Duration
+
+	// Jitter is the fraction of each delay that is randomised, spreading out
+	// retries of jobs that failed together. Negative disables it.
+	// Default: 0.2
+	Jitter float64
+
+	// Retryable reports whether a failed attempt should be retried.
+	// Default: every error is retried. Panics, and attempts whose job
+	// context has ended, are never retried.
+	Retryable func(err error) bool
+}
+
+// Retrier is implemented by jobs that need a retry policy other than
+// Config.Retry. Returning nil disables retries for the job.
+type Retrier interface {
+	RetryPolicy() *RetryPolicy
+}
+
+// SubmitOption tunes a single submission.
+type SubmitOption func(*submitOptions)
+
+type submitOptions struct {
+	retry    *RetryPolicy
+	retrySet bool
+}
+
+// WithRetry sets the retry policy for one submission, overriding both
+// Config.Retry and the job's Retrier. A nil policy disables retries.
+func WithRetry(policy *RetryPolicy) SubmitOption {
+	if policy != nil {
+		cp := *policy
+		cp.applyDefaults()
+		policy = &cp
+	}
+	return func(o *submitOptions) {
+		o.retry = policy
+		o.retrySet = true
+	}
+}
+
+func (r *RetryPolicy) applyDefaults() {
+	if r.MaxAttempts <= 0 {
+		r.MaxAttempts = 3
+	}
+	if r.BaseDelay <= 0 {
+		r.BaseDelay = 100 * time.Millisecond
+	}
+	if r.MaxDelay <= 0 {
+		r.MaxDelay = 10 * time.Second
+	}
+	if r.Jitter == 0 {
+		r.Jitter = 0.2
+	}
+	if r.Jitter > 1 {
+		r.Jitter = 1
+	}
+}
+
+// backoff returns the delay before the given retry.
+// retry is 0-indexed: retry 0 = BaseDelay, retry 1 = BaseDelay*2, etc.
+func (r *RetryPolicy) backoff(retry int) time.Duration {
+	d := time.Duration(float64(r.BaseDelay) * math.Pow(2, float64(retry)))
+	if d > r.MaxDelay || d <= 0 {
+		d = r.MaxDelay
+	}
+	if r.Jitter > 0 {
+		d -= time.Duration(rand.Float64() * r.Jitter * float64(d))
+	}
+	return d
+}
+
+func (r *RetryPolicy) retryable(err error) bool {
+	if r.Retryable != nil {
+		return r.Retryable(err)
+	}
+	return true
+}
+
+// retryPolicyFor returns the effective policy for env, or nil for none.
+func (p *Pool[T]) retryPolicyFor(env *jobEnvelope[T]) *RetryPolicy {
+	if env.opts.retrySet {
+		return env.opts.retry
+	}
+	rj, ok := env.job.(Retrier)
+	if !ok {
+		return p.cfg.Retry
+	}
+	policy := rj.RetryPolicy()
+	if policy == nil {
+		return nil
+	}
+	cp := *policy
+	cp.applyDefaults()
+	return &cp
+}
+
+// sleepCtx waits for d and reports false if ctx ends first.
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
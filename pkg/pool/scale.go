@@ -0,0 +1,264 @@
+package pool
+
+import (
+	"errors"
+	"time"
+
+	"github.com/vietpham102301/lightway/pkg/logger"
+)
+
+// ErrInvalidSize is returned by Resize when the bounds are not
+// 1 <= minWorkers <= maxWorkers.
+var ErrInvalidSize = errors.New("pool: invalid worker bounds")
+
+// ScaleMetrics is what a Scaler sees at each ScaleInterval.
+type ScaleMetrics struct {
+	Workers    int // live workers, not counting those asked to retire
+	Busy       int // workers running a job
+	QueueDepth int // jobs waiting in the queue
+	MinWorkers int
+	MaxWorkers int
+
+	// MeanDuration and MeanQueueWait average the jobs finished and started
+	// since the previous check. They keep their last value through checks
+	// with no such jobs, and are zero until the first one.
+	MeanDuration  time.Duration
+	MeanQueueWait time.Duration
+}
+
+// Scaler decides how many workers the pool should run. The pool calls
+// Target from a single goroutine every Config.ScaleInterval, clamps the
+// result to [MinWorkers, MaxWorkers], then spawns or retires workers to
+// match. Retired workers finish their current job first.
+//
+// Whatever the Scaler decides, workers above MinWorkers still exit after
+// Config.IdleTimeout without work.
+type Scaler interface {
+	Target(m ScaleMetrics) int
+}
+
+// ScalerFunc adapts a plain function to Scaler.
+type ScalerFunc func(m ScaleMetrics) int
+
+// Target calls f(m).
+func (f ScalerFunc) Target(m ScaleMetrics) int { return f(m) }
+
+// BacklogScaler spawns a worker for every queued job and leaves scale-down
+// to Config.IdleTimeout. It is the default Scaler.
+type BacklogScaler struct{}
+
+// Target returns Workers + QueueDepth.
+func (BacklogScaler) Target(m ScaleMetrics) int { return m.Workers + m.QueueDepth }
+
+// ThresholdScaler grows the pool by Step workers while the queue holds at
+// least High jobs, and shrinks it by Step idle workers while it holds at
+// most Low. Requiring UpAfter or DownAfter consecutive checks before acting,
+// and the gap between Low and High, keep a queue hovering around one
+// threshold from flapping the pool.
+//
+// A ThresholdScaler counts checks between calls, so give each pool its own.
+type ThresholdScaler struct {
+	// High is the queue depth at or above which the pool grows.
+	// Default: Low + 1
+	High int
+
+	// Low is the queue depth at or below which the pool shrinks.
+	// Default: 0
+	Low int
+
+	// Step is how many workers are added or removed at a time.
+	// Default: 1
+	Step int
+
+	// UpAfter is how many consecutive checks must see the queue at or
+	// above High before growing. Default: 1
+	UpAfter int
+
+	// DownAfter is how many consecutive checks must see the queue at or
+	// below Low before shrinking. Default: 10
+	DownAfter int
+
+	above, below int
+}
+
+// Target implements Scaler.
+func (s *ThresholdScaler) Target(m ScaleMetrics) int {
+	high := s.High
+	if high <= s.Low {
+		high = s.Low + 1
+	}
+	step := max(s.Step, 1)
+	upAfter := max(s.UpAfter, 1)
+	downAfter := s.DownAfter
+	if downAfter <= 0 {
+		downAfter = 10
+	}
+
+	switch {
+	case m.QueueDepth >= high:
+		s.above++
+		s.below = 0
+		if s.above >= upAfter {
+			s.above = 0
+			return m.Workers + step
+		}
+	case m.QueueDepth <= s.Low:
+		s.below++
+		s.above = 0
+		if s.below >= downAfter {
+			s.below = 0
+			// Only idle workers are let go.
+			return max(m.Workers-step, m.Busy)
+		}
+	default:
+		s.above, s.below = 0, 0
+	}
+	return m.Workers
+}
+
+// LatencyScaler sizes the pool so that queued jobs start within MaxWait,
+// estimating the time to clear the queue from the measured mean job
+// duration. Until a job has finished it behaves like BacklogScaler. It only
+// grows the pool; idle workers exit after Config.IdleTimeout.
+type LatencyScaler struct {
+	// MaxWait is the queue wait to stay under.
+	MaxWait time.Duration
+}
+
+// Target implements Scaler.
+func (s LatencyScaler) Target(m ScaleMetrics) int {
+	if m.QueueDepth == 0 {
+		return m.Workers
+	}
+	if m.MeanDuration <= 0 || s.MaxWait <= 0 {
+		return m.Workers + m.QueueDepth
+	}
+	backlog := time.Duration(m.QueueDepth) * m.MeanDuration
+	need := int((backlog + s.MaxWait - 1) / s.MaxWait)
+	return max(m.Workers, need)
+}
+
+// Resize changes MinWorkers and MaxWorkers at runtime, spawning or retiring
+// workers right away to fit the new bounds. Queue capacity is unchanged.
+// On a pool that is not running it only updates the bounds.
+func (p *Pool[T]) Resize(minWorkers, maxWorkers int) error {
+	if minWorkers < 1 || maxWorkers < minWorkers {
+		return ErrInvalidSize
+	}
+	p.mu.Lock()
+	p.cfg.MinWorkers, p.cfg.MaxWorkers = minWorkers, maxWorkers
+	p.mu.Unlock()
+
+	added, removed := p.rescale(func(current int) int { return current })
+	p.recordScale(added, removed, "resize")
+	return nil
+}
+
+// rescale spawns or retires workers to reach target(current), clamped to
+// [MinWorkers, MaxWorkers], where current excludes workers already asked to
+// retire. It does nothing unless the pool is running.
+func (p *Pool[T]) rescale(target func(current int) int) (added, removed int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.started.Load() || p.ctx.Err() != nil {
+		return 0, 0
+	}
+
+	current := p.activeWorkers - p.retiring
+	want := min(max(target(current), p.cfg.MinWorkers), p.cfg.MaxWorkers)
+	switch {
+	case want > current:
+		added = want - current
+		// Take back pending retirements before spawning anyone.
+		kept := min(added, p.retiring)
+		p.retiring -= kept
+		spawn := added - kept
+		p.activeWorkers += spawn
+		p.wg.Add(spawn)
+		for range spawn {
+			go p.worker()
+		}
+	case want < current:
+		removed = current - want
+		p.retiring += removed
+		close(p.retireCh)
+		p.retireCh = make(chan struct{})
+	}
+	return added, removed
+}
+
+// recordScale counts and logs a scaling event.
+func (p *Pool[T]) recordScale(added, removed int, reason string) {
+	if added > 0 {
+		p.stats.scaleUps.Add(1)
+		logger.Info("pool: scaled up workers", "added", added, "reason", reason)
+	}
+	if removed > 0 {
+		p.stats.scaleDowns.Add(1)
+		logger.Info("pool: scaled down workers", "removed", removed, "reason", reason)
+	}
+}
+
+// retireSignal consumes a pending retirement, reporting true if the calling
+// worker should exit. Otherwise it returns the channel closed when the next
+// retirement is requested.
+func (p *Pool[T]) retireSignal() (<-chan struct{}, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.retiring > 0 {
+		p.retiring--
+		return nil, true
+	}
+	return p.retireCh, false
+}
+
+// scaler asks Config.Scaler for a worker count every ScaleInterval and
+// resizes the pool to match.
+func (p *Pool[T]) scaler() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.cfg.ScaleInterval)
+	defer ticker.Stop()
+
+	var durations, waits meanTracker
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+
+		case <-ticker.C:
+			p.mu.Lock()
+			m := ScaleMetrics{
+				Workers:    p.activeWorkers - p.retiring,
+				MinWorkers: p.cfg.MinWorkers,
+				MaxWorkers: p.cfg.MaxWorkers,
+			}
+			p.mu.Unlock()
+			m.Busy = p.queue.runningCount()
+			m.QueueDepth = p.queue.Len()
+			m.MeanDuration = durations.update(&p.stats.durations)
+			m.MeanQueueWait = waits.update(&p.stats.queueWait)
+
+			want := p.cfg.Scaler.Target(m)
+			added, removed := p.rescale(func(int) int { return want })
+			p.recordScale(added, removed, "scaler")
+		}
+	}
+}
+
+// meanTracker turns a histogram's running totals into the mean of the
+// observations made since the previous update.
+type meanTracker struct {
+	count int64
+	sum   int64
+	mean  time.Duration
+}
+
+func (t *meanTracker) update(h *histogram) time.Duration {
+	count, sum := h.totals()
+	if count > t.count {
+		t.mean = time.Duration((sum - t.sum) / (count - t.count))
+	}
+	t.count, t.sum = count, sum
+	return t.mean
+}
@@ -1,25 +1,18 @@
 package pool
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/vietpham102301/lightway/pkg/logger"
 )
 
-// spawnWorker increments activeWorkers and launches a new worker goroutine.
-func (p *Pool[T]) spawnWorker() {
-	p.mu.Lock()
-	p.activeWorkers++
-	p.mu.Unlock()
-
-	p.wg.Add(1)
-	go p.worker()
-}
-
-// worker is the main goroutine loop. It pulls jobs from the channel and
+// worker is the main goroutine loop. It pulls jobs from the queue and
 // executes them. Workers above MinWorkers exit after IdleTimeout of
-// inactivity, providing automatic scale-down.
+// inactivity, providing automatic scale-down, and any worker exits between
+// jobs when the pool asks it to retire.
 func (p *Pool[T]) worker() {
 	defer func() {
 		p.mu.Lock()
@@ -32,14 +25,17 @@ func (p *Pool[T]) worker() {
 	defer idleTimer.Stop()
 
 	for {
-		select {
-		case <-p.ctx.Done():
+		if p.ctx.Err() != nil {
+			return
+		}
+		retire, retired := p.retireSignal()
+		if retired {
+			// Pass on a wake-up this worker may have taken.
+			p.queue.signal()
 			return
+		}
 
-		case env, ok := <-p.jobs:
-			if !ok {
-				return
-			}
+		if env, ok := p.queue.pop(); ok {
 			// Reset idle timer each time work arrives.
 			if !idleTimer.Stop() {
 				select {
@@ -49,14 +45,32 @@ func (p *Pool[T]) worker() {
 			}
 			idleTimer.Reset(p.cfg.IdleTimeout)
 
-			p.executeJob(env)
+			// Skip jobs already failed by their context while queued.
+			if env.claim() {
+				p.stats.queueWait.observe(time.Since(env.queuedAt))
+				p.executeJob(env)
+			}
+			p.queue.finish()
+			continue
+		}
+
+		select {
+		case <-p.ctx.Done():
+			return
+
+		case <-p.queue.ready:
+			// Work may be available; loop around and try to pop it.
+
+		case <-retire:
+			// Some workers should retire; loop around to see if this is one.
 
 		case <-idleTimer.C:
 			p.mu.Lock()
-			canShrink := p.activeWorkers > p.cfg.MinWorkers
+			canShrink := p.activeWorkers-p.retiring > p.cfg.MinWorkers
 			p.mu.Unlock()
 
 			if canShrink {
+				p.stats.scaleDowns.Add(1)
 				return
 			}
 			// Below minimum — keep the worker alive.
@@ -68,81 +82,87 @@ func (p *Pool[T]) worker() {
 // executeJob runs a single job with panic recovery and delivers the result.
 // Panic recovery lives here (not in worker) so a panicking job does not kill
 // the worker goroutine — the worker continues processing future jobs.
-func (p *Pool[T]) executeJob(env jobEnvelope[T]) {
+// Failed attempts are retried on the same worker according to the job's
+// retry policy.
+func (p *Pool[T]) executeJob(env *jobEnvelope[T]) {
+	// The job's context may have ended after it was queued but before its
+	// watch could claim it.
+	if err := env.ctx.Err(); err != nil {
+		p.stats.cancelled.Add(1)
+		var zero T
+		env.deliver(zero, err)
+		return
+	}
+
+	ctx, cancel := p.jobContext(env)
+	defer cancel()
+
+	start := time.Now()
 	defer func() {
 		if r := recover(); r != nil {
+			p.stats.durations.observe(time.Since(start))
 			p.stats.panics.Add(1)
 			err := fmt.Errorf("pool: job panicked: %v", r)
 			logger.Error("worker recovered from panic", "panic", r)
 			var zero T
-			env.result <- Result[T]{Value: zero, Err: err}
-			close(env.result)
+			env.deliver(zero, err)
 		}
 	}()
 
-	value, err := env.job.Execute(p.ctx)
+	policy := p.retryPolicyFor(env)
+	value, err := p.attempt(ctx, env.job)
+	for retry := 0; err != nil && policy != nil && ctx.Err() == nil; retry++ {
+		if retry+1 >= policy.MaxAttempts {
+			// A single-attempt policy never retried, so nothing was exhausted.
+			if retry > 0 {
+				p.stats.retriesExhausted.Add(1)
+			}
+			break
+		}
+		if !policy.retryable(err) || !sleepCtx(ctx, policy.backoff(retry)) {
+			break
+		}
+		p.stats.retried.Add(1)
+		value, err = p.attempt(ctx, env.job)
+	}
+
+	p.stats.durations.observe(time.Since(start))
 	p.stats.processed.Add(1)
 	if err != nil {
 		p.stats.failed.Add(1)
 	}
 
-	env.result <- Result[T]{Value: value, Err: err}
-	close(env.result)
+	env.deliver(value, err)
 }
 
-// scaler periodically checks whether new workers should be spawned to handle
-// queue pressure. Scale-down is handled naturally by the idle timer in each
-// worker — no dedicated scale-down goroutine is needed.
-func (p *Pool[T]) scaler() {
-	defer p.wg.Done()
-
-	ticker := time.NewTicker(p.cfg.ScaleInterval)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-p.ctx.Done():
-			return
-
-		case <-ticker.C:
-			queueDepth := len(p.jobs)
-			if queueDepth == 0 {
-				continue
-			}
-
-			p.mu.Lock()
-			current := p.activeWorkers
-			max := p.cfg.MaxWorkers
-			p.mu.Unlock()
-
-			if current >= max {
-				continue
-			}
-
-			// Spawn enough workers to drain the queue, up to MaxWorkers.
-			toSpawn := max - current
-			if queueDepth < toSpawn {
-				toSpawn = queueDepth
-			}
-
-			spawned := 0
-			for i := 0; i < toSpawn; i++ {
-				p.mu.Lock()
-				if p.activeWorkers >= p.cfg.MaxWorkers {
-					p.mu.Unlock()
-					break
-				}
-				p.mu.Unlock()
-				p.spawnWorker()
-				spawned++
-			}
+// attempt runs the job once, bounded by its timeout.
+func (p *Pool[T]) attempt(ctx context.Context, job Job[T]) (T, error) {
+	timeout := p.cfg.JobTimeout
+	if t, ok := job.(Timeouter); ok {
+		timeout = t.Timeout()
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, timeout, ErrJobTimeout)
+		defer cancel()
+	}
 
-			if spawned > 0 {
-				logger.Info("pool: scaled up workers",
-					"added", spawned,
-					"queue_depth", queueDepth,
-				)
-			}
+	value, err := job.Execute(ctx)
+	if errors.Is(context.Cause(ctx), ErrJobTimeout) {
+		p.stats.timedOut.Add(1)
+		if err == nil || errors.Is(err, context.DeadlineExceeded) {
+			err = ErrJobTimeout
+		} else {
+			err = fmt.Errorf("%w: %w", ErrJobTimeout, err)
 		}
 	}
+	return value, err
+}
+
+// jobContext derives the context shared by a job's attempts: it ends when
+// the submitter's context ends or the pool stops.
+func (p *Pool[T]) jobContext(env *jobEnvelope[T]) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(env.ctx)
+	stopPool := context.AfterFunc(p.ctx, cancel)
+	return ctx, func() { stopPool(); cancel() }
 }
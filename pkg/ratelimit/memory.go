@@ -0,0 +1,135 @@
+package ratelimit
+
+import (
+	stdctx "context"
+	"hash/maphash"
+	"sync"
+	"time"
+)
+
+const (
+	// memoryShards is the number of independently locked maps in a MemoryStore.
+	memoryShards = 64
+
+	// sweepEvery is how many Takes a shard serves between expiry sweeps.
+	sweepEvery = 1024
+)
+
+// MemoryStore is an in-process Store. Keys are spread across independently
+// locked shards to keep contention low. Limits only hold within a single
+// process; use RedisStore to share them across replicas.
+type MemoryStore struct {
+	seed   maphash.Seed
+	shards [memoryShards]memoryShard
+	now    func() time.Time
+}
+
+type memoryShard struct {
+	mu      sync.Mutex
+	entries map[string]*memoryEntry
+	ops     int
+}
+
+// memoryEntry holds the state for one key. Token buckets use tokens/last;
+// sliding windows use window/curr/prev.
+type memoryEntry struct {
+	tokens float64
+	last   time.Time
+
+	window int64
+	curr   float64
+	prev   float64
+
+	expiresAt time.Time
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore creates an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	s := &MemoryStore{
+		seed: maphash.MakeSeed(),
+		now:  time.Now,
+	}
+	for i := range s.shards {
+		s.shards[i].entries = make(map[string]*memoryEntry)
+	}
+	return s
+}
+
+// Take implements Store.
+func (s *MemoryStore) Take(_ stdctx.Context, key string, limit Limit) (Result, error) {
+	limit.applyDefaults()
+	now := s.now()
+
+	shard := &s.shards[maphash.String(s.seed, key)%memoryShards]
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	shard.ops++
+	if shard.ops >= sweepEvery {
+		shard.ops = 0
+		shard.sweep(now)
+	}
+
+	e, ok := shard.entries[key]
+	if !ok {
+		e = &memoryEntry{tokens: float64(limit.Burst), last: now}
+		shard.entries[key] = e
+	}
+
+	if limit.Algorithm == SlidingWindow {
+		return e.takeSlidingWindow(limit, now), nil
+	}
+	return e.takeTokenBucket(limit, now), nil
+}
+
+func (e *memoryEntry) takeTokenBucket(limit Limit, now time.Time) Result {
+	elapsed := now.Sub(e.last)
+	if elapsed > 0 {
+		refill := float64(elapsed) * float64(limit.Rate) / float64(limit.Period)
+		e.tokens = min(float64(limit.Burst), e.tokens+refill)
+		e.last = now
+	}
+
+	allowed := e.tokens >= 1
+	if allowed {
+		e.tokens--
+	}
+	res := tokenBucketResult(limit, e.tokens, allowed)
+	e.expiresAt = now.Add(res.ResetAfter)
+	return res
+}
+
+func (e *memoryEntry) takeSlidingWindow(limit Limit, now time.Time) Result {
+	period := int64(limit.Period)
+	nanos := now.UnixNano()
+	window := nanos / period
+
+	switch {
+	case e.window == window:
+	case e.window == window-1:
+		e.prev, e.curr = e.curr, 0
+	default:
+		e.prev, e.curr = 0, 0
+	}
+	e.window = window
+
+	elapsed := float64(nanos-window*period) / float64(period)
+	allowed := e.prev*(1-elapsed)+e.curr+1 <= float64(limit.Rate)
+	if allowed {
+		e.curr++
+	}
+	res := slidingWindowResult(limit, e.curr, e.prev, elapsed, allowed)
+	e.expiresAt = now.Add(res.ResetAfter)
+	return res
+}
+
+// sweep drops entries that have fully recovered. Callers must hold mu.
+func (sh *memoryShard) sweep(now time.Time) {
+	for k, e := range sh.entries {
+		if now.After(e.expiresAt) {
+			delete(sh.entries, k)
+		}
+	}
+}
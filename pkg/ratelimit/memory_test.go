@@ -0,0 +1,149 @@
+package ratelimit
+
+import (
+	stdctx "context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock returns a MemoryStore whose time only moves when advance is called.
+func newFakeClockStore() (*MemoryStore, func(time.Duration)) {
+	s := NewMemoryStore()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var mu sync.Mutex
+	s.now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	return s, func(d time.Duration) {
+		mu.Lock()
+		now = now.Add(d)
+		mu.Unlock()
+	}
+}
+
+// ===========================================================================
+// Token bucket
+// ===========================================================================
+
+func TestMemoryStore_TokenBucket_Burst(t *testing.T) {
+	s, _ := newFakeClockStore()
+	limit := Limit{Rate: 1, Period: time.Second, Burst: 3}
+
+	for i := 0; i < 3; i++ {
+		res, _ := s.Take(stdctx.Background(), "k", limit)
+		if !res.Allowed {
+			t.Fatalf("request %d: expected allowed within burst", i)
+		}
+		if res.Remaining != 2-i {
+			t.Errorf("request %d: expected remaining %d, got %d", i, 2-i, res.Remaining)
+		}
+	}
+
+	res, _ := s.Take(stdctx.Background(), "k", limit)
+	if res.Allowed {
+		t.Fatal("expected request beyond burst to be denied")
+	}
+	if res.RetryAfter != time.Second {
+		t.Errorf("expected RetryAfter 1s, got %v", res.RetryAfter)
+	}
+	if res.ResetAfter != 3*time.Second {
+		t.Errorf("expected ResetAfter 3s, got %v", res.ResetAfter)
+	}
+}
+
+func TestMemoryStore_TokenBucket_Refill(t *testing.T) {
+	s, advance := newFakeClockStore()
+	limit := PerSecond(2)
+
+	s.Take(stdctx.Background(), "k", limit)
+	s.Take(stdctx.Background(), "k", limit)
+	if res, _ := s.Take(stdctx.Background(), "k", limit); res.Allowed {
+		t.Fatal("expected bucket to be empty")
+	}
+
+	advance(500 * time.Millisecond)
+	if res, _ := s.Take(stdctx.Background(), "k", limit); !res.Allowed {
+		t.Fatal("expected one token to be refilled after 500ms")
+	}
+}
+
+func TestMemoryStore_KeysAreIndependent(t *testing.T) {
+	s, _ := newFakeClockStore()
+	limit := PerSecond(1)
+
+	s.Take(stdctx.Background(), "a", limit)
+	if res, _ := s.Take(stdctx.Background(), "b", limit); !res.Allowed {
+		t.Error("expected key 'b' to have its own budget")
+	}
+}
+
+// ===========================================================================
+// Sliding window
+// ===========================================================================
+
+func TestMemoryStore_SlidingWindow(t *testing.T) {
+	s, advance := newFakeClockStore()
+	limit := Limit{Rate: 4, Period: time.Second, Algorithm: SlidingWindow}
+
+	for i := 0; i < 4; i++ {
+		if res, _ := s.Take(stdctx.Background(), "k", limit); !res.Allowed {
+			t.Fatalf("request %d: expected allowed", i)
+		}
+	}
+	res, _ := s.Take(stdctx.Background(), "k", limit)
+	if res.Allowed {
+		t.Fatal("expected 5th request in window to be denied")
+	}
+	if res.RetryAfter <= 0 || res.RetryAfter > 2*time.Second {
+		t.Errorf("expected RetryAfter within (0, 2s], got %v", res.RetryAfter)
+	}
+
+	// Halfway into the next window the previous window still weighs 50%:
+	// 4*0.5 = 2 used, so exactly 2 more requests fit.
+	advance(1500 * time.Millisecond)
+	for i := 0; i < 2; i++ {
+		if res, _ := s.Take(stdctx.Background(), "k", limit); !res.Allowed {
+			t.Fatalf("request %d after slide: expected allowed", i)
+		}
+	}
+	if res, _ := s.Take(stdctx.Background(), "k", limit); res.Allowed {
+		t.Fatal("expected weighted window to be exhausted")
+	}
+
+	// Two full windows later everything is forgotten.
+	advance(2 * time.Second)
+	if res, _ := s.Take(stdctx.Background(), "k", limit); !res.Allowed || res.Remaining != 3 {
+		t.Errorf("expected fresh window with 3 remaining, got %+v", res)
+	}
+}
+
+// ===========================================================================
+// Sweep
+// ===========================================================================
+
+func TestMemoryStore_SweepsRecoveredEntries(t *testing.T) {
+	s, advance := newFakeClockStore()
+	limit := PerSecond(10)
+
+	s.Take(stdctx.Background(), "stale", limit)
+	advance(time.Minute)
+
+	shard := &s.shards[0]
+	shard.mu.Lock()
+	shard.entries["stale"] = &memoryEntry{expiresAt: s.now().Add(-time.Second)}
+	shard.entries["fresh"] = &memoryEntry{expiresAt: s.now().Add(time.Hour)}
+	shard.sweep(s.now())
+	_, staleOK := shard.entries["stale"]
+	_, freshOK := shard.entries["fresh"]
+	shard.mu.Unlock()
+
+	if staleOK {
+		t.Error("expected expired entry to be swept")
+	}
+	if !freshOK {
+		t.Error("expected live entry to be kept")
+	}
+}
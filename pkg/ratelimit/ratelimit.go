@@ -0,0 +1,291 @@
+// Package ratelimit provides token-bucket and sliding-window rate limiting
+// with pluggable stores and an HTTP middleware.
+package ratelimit
+
+import (
+	stdctx "context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vietpham102301/lightway/pkg/context"
+	aerror "github.com/vietpham102301/lightway/pkg/errors"
+	"github.com/vietpham102301/lightway/pkg/logger"
+)
+
+// Algorithm selects how a Limit is enforced.
+type Algorithm int
+
+const (
+	// TokenBucket allows bursts up to Burst and refills at Rate per Period.
+	TokenBucket Algorithm = iota
+
+	// SlidingWindow allows Rate requests in any Period-long window, using a
+	// weighted count of the current and previous fixed windows.
+	SlidingWindow
+)
+
+// Limit describes how many requests a key may make.
+type Limit struct {
+	// Rate is the number of requests allowed per Period. Required.
+	Rate int
+
+	// Period is the window the Rate applies to. Default: 1s
+	Period time.Duration
+
+	// Burst is the token bucket capacity. Ignored by SlidingWindow.
+	// Default: Rate
+	Burst int
+
+	// Algorithm selects the limiter. Default: TokenBucket
+	Algorithm Algorithm
+}
+
+// PerSecond returns a token-bucket Limit of n requests per second.
+func PerSecond(n int) Limit {
+	return Limit{Rate: n, Period: time.Second}
+}
+
+// PerMinute returns a token-bucket Limit of n requests per minute.
+func PerMinute(n int) Limit {
+	return Limit{Rate: n, Period: time.Minute}
+}
+
+func (l *Limit) applyDefaults() {
+	if l.Rate <= 0 {
+		l.Rate = 1
+	}
+	if l.Period <= 0 {
+		l.Period = time.Second
+	}
+	if l.Burst <= 0 {
+		l.Burst = l.Rate
+	}
+}
+
+// capacity is the maximum number of requests available at once.
+func (l Limit) capacity() int {
+	if l.Algorithm == SlidingWindow {
+		return l.Rate
+	}
+	return l.Burst
+}
+
+// Result is the outcome of a single Take.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+
+	// ResetAfter is how long until the key is back at full capacity.
+	ResetAfter time.Duration
+
+	// RetryAfter is how long until the next request may succeed.
+	// Zero when Allowed is true.
+	RetryAfter time.Duration
+}
+
+// Store records request counts and decides whether a request is allowed.
+// Implementations must be safe for concurrent use.
+type Store interface {
+	Take(ctx stdctx.Context, key string, limit Limit) (Result, error)
+}
+
+// tokenBucketResult builds a Result from the token count left after a Take.
+func tokenBucketResult(limit Limit, tokens float64, allowed bool) Result {
+	perToken := float64(limit.Period) / float64(limit.Rate)
+	res := Result{
+		Allowed:    allowed,
+		Limit:      limit.Burst,
+		Remaining:  int(math.Floor(tokens)),
+		ResetAfter: time.Duration((float64(limit.Burst) - tokens) * perToken),
+	}
+	if !allowed {
+		res.RetryAfter = time.Duration((1 - tokens) * perToken)
+	}
+	return res
+}
+
+// slidingWindowResult builds a Result from the counts after a Take. elapsed
+// is the fraction (0–1) of the current window that has already passed.
+func slidingWindowResult(limit Limit, curr, prev, elapsed float64, allowed bool) Result {
+	rate := float64(limit.Rate)
+	period := float64(limit.Period)
+	estimate := prev*(1-elapsed) + curr
+
+	res := Result{
+		Allowed:    allowed,
+		Limit:      limit.Rate,
+		Remaining:  max(0, int(math.Floor(rate-estimate))),
+		ResetAfter: time.Duration((2 - elapsed) * period),
+	}
+	if curr == 0 {
+		res.ResetAfter = time.Duration((1 - elapsed) * period)
+	}
+	if allowed {
+		return res
+	}
+
+	// Solve prev*(1-x) + curr + 1 <= rate for the window fraction x.
+	if curr+1 <= rate && prev > 0 {
+		x := 1 - (rate-curr-1)/prev
+		res.RetryAfter = time.Duration((x - elapsed) * period)
+	} else {
+		// The current window alone is exhausted; in the next window it
+		// becomes prev and must decay until one slot frees up.
+		x := 1 - (rate-1)/curr
+		res.RetryAfter = time.Duration((1 - elapsed + max(0, x)) * period)
+	}
+	if res.RetryAfter <= 0 {
+		res.RetryAfter = time.Millisecond
+	}
+	return res
+}
+
+// KeyFunc derives the rate limit key for a request. Returning "" skips
+// rate limiting for that request.
+type KeyFunc func(r *http.Request) string
+
+// ByIP keys requests by the client IP from RemoteAddr.
+func ByIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// ByRealIP keys requests by the first X-Forwarded-For address, then
+// X-Real-IP, then RemoteAddr. Only use it behind a proxy that overwrites
+// these headers, otherwise clients can pick their own key.
+func ByRealIP(r *http.Request) string {
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+		first, _, _ := strings.Cut(xff, ",")
+		return strings.TrimSpace(first)
+	}
+	if ip := r.Header.Get("X-Real-IP"); ip != "" {
+		return ip
+	}
+	return ByIP(r)
+}
+
+// ByUser keys requests by the user ID stored under context.UserIDKey,
+// falling back to the client IP for anonymous requests.
+func ByUser(r *http.Request) string {
+	if id, ok := r.Context().Value(context.UserIDKey).(int); ok {
+		return "user:" + strconv.Itoa(id)
+	}
+	return "ip:" + ByIP(r)
+}
+
+// ByRoute keys requests by the matched route pattern, so every route gets
+// its own budget. Combine it with another KeyFunc via Compose for per-route,
+// per-client limits.
+func ByRoute(r *http.Request) string {
+	if r.Pattern != "" {
+		return r.Pattern
+	}
+	return r.Method + " " + r.URL.Path
+}
+
+// Compose joins the keys produced by fns with "|". If any fn returns "",
+// the request is not rate limited.
+func Compose(fns ...KeyFunc) KeyFunc {
+	return func(r *http.Request) string {
+		parts := make([]string, len(fns))
+		for i, fn := range fns {
+			parts[i] = fn(r)
+			if parts[i] == "" {
+				return ""
+			}
+		}
+		return strings.Join(parts, "|")
+	}
+}
+
+// Config holds the rate limit middleware configuration.
+type Config struct {
+	// Limit is the budget applied to each key. Limit.Rate is required.
+	Limit Limit
+
+	// Store holds the counters. Default: NewMemoryStore()
+	Store Store
+
+	// KeyFunc derives the key for each request. Default: ByIP
+	KeyFunc KeyFunc
+
+	// Prefix namespaces keys in the store, letting several limiters share
+	// one store. Default: "rl"
+	Prefix string
+
+	// FailClosed rejects requests when the store returns an error.
+	// By default requests are allowed and the error is logged.
+	FailClosed bool
+}
+
+func (c *Config) applyDefaults() {
+	c.Limit.applyDefaults()
+	if c.Store == nil {
+		c.Store = NewMemoryStore()
+	}
+	if c.KeyFunc == nil {
+		c.KeyFunc = ByIP
+	}
+	if c.Prefix == "" {
+		c.Prefix = "rl"
+	}
+}
+
+// New returns a rate limiting middleware. Every response carries
+// RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers; rejected
+// requests get a 429 TooManyRequests AppError and a Retry-After header.
+func New(cfg Config) func(http.Handler) http.Handler {
+	cfg.applyDefaults()
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := cfg.KeyFunc(r)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			res, err := cfg.Store.Take(r.Context(), cfg.Prefix+":"+key, cfg.Limit)
+			if err != nil {
+				logger.Error("ratelimit: store error", "key", key, "err", err)
+				if cfg.FailClosed {
+					c := &context.Context{W: w, R: r}
+					c.JSONResponse(http.StatusServiceUnavailable, nil, aerror.ServiceUnavailable("Service Unavailable"))
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h := w.Header()
+			h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+			h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.ResetAfter)))
+
+			if !res.Allowed {
+				h.Set("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+				c := &context.Context{W: w, R: r}
+				c.JSONResponse(http.StatusTooManyRequests, nil, aerror.TooManyRequests("Too Many Requests"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ceilSeconds rounds d up to whole seconds, as required by the headers.
+func ceilSeconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int((d + time.Second - 1) / time.Second)
+}
@@ -0,0 +1,153 @@
+package ratelimit
+
+import (
+	stdctx "context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/vietpham102301/lightway/pkg/context"
+	aerror "github.com/vietpham102301/lightway/pkg/errors"
+)
+
+type failingStore struct{}
+
+func (failingStore) Take(stdctx.Context, string, Limit) (Result, error) {
+	return Result{}, errors.New("store down")
+}
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+})
+
+// ===========================================================================
+// Middleware
+// ===========================================================================
+
+func TestNew_AllowsThenRejects(t *testing.T) {
+	handler := New(Config{Limit: Limit{Rate: 2, Period: time.Minute}})(okHandler)
+
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("request %d: expected 200, got %d", i, w.Code)
+		}
+		if w.Header().Get("RateLimit-Limit") != "2" {
+			t.Errorf("expected RateLimit-Limit 2, got %q", w.Header().Get("RateLimit-Limit"))
+		}
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", w.Code)
+	}
+	if w.Header().Get("RateLimit-Remaining") != "0" {
+		t.Errorf("expected RateLimit-Remaining 0, got %q", w.Header().Get("RateLimit-Remaining"))
+	}
+	if w.Header().Get("Retry-After") != "30" {
+		t.Errorf("expected Retry-After 30, got %q", w.Header().Get("Retry-After"))
+	}
+
+	var resp context.AppResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if resp.ErrorCode != aerror.CodeTooManyRequests {
+		t.Errorf("expected error code %q, got %q", aerror.CodeTooManyRequests, resp.ErrorCode)
+	}
+}
+
+func TestNew_EmptyKeySkipsLimit(t *testing.T) {
+	handler := New(Config{
+		Limit:   PerMinute(1),
+		KeyFunc: func(r *http.Request) string { return "" },
+	})(okHandler)
+
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("request %d: expected 200, got %d", i, w.Code)
+		}
+	}
+}
+
+func TestNew_StoreError(t *testing.T) {
+	t.Run("fail open", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		New(Config{Limit: PerSecond(1), Store: failingStore{}})(okHandler).
+			ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("expected 200, got %d", w.Code)
+		}
+	})
+
+	t.Run("fail closed", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		New(Config{Limit: PerSecond(1), Store: failingStore{}, FailClosed: true})(okHandler).
+			ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("expected 503, got %d", w.Code)
+		}
+	})
+}
+
+// ===========================================================================
+// KeyFuncs
+// ===========================================================================
+
+func TestKeyFuncs(t *testing.T) {
+	req := httptest.NewRequest("GET", "/users/1", nil)
+	req.RemoteAddr = "10.0.0.1:5555"
+
+	if got := ByIP(req); got != "10.0.0.1" {
+		t.Errorf("ByIP: expected '10.0.0.1', got %q", got)
+	}
+	if got := ByUser(req); got != "ip:10.0.0.1" {
+		t.Errorf("ByUser anonymous: expected 'ip:10.0.0.1', got %q", got)
+	}
+	if got := ByRoute(req); got != "GET /users/1" {
+		t.Errorf("ByRoute: expected 'GET /users/1', got %q", got)
+	}
+
+	authed := req.WithContext(stdctx.WithValue(req.Context(), context.UserIDKey, 42))
+	if got := ByUser(authed); got != "user:42" {
+		t.Errorf("ByUser: expected 'user:42', got %q", got)
+	}
+
+	req.Header.Set("X-Forwarded-For", "203.0.113.5, 10.0.0.1")
+	if got := ByRealIP(req); got != "203.0.113.5" {
+		t.Errorf("ByRealIP: expected '203.0.113.5', got %q", got)
+	}
+
+	if got := Compose(ByRoute, ByIP)(req); got != "GET /users/1|10.0.0.1" {
+		t.Errorf("Compose: unexpected key %q", got)
+	}
+}
+
+// ===========================================================================
+// slidingWindowResult
+// ===========================================================================
+
+func TestSlidingWindowResult_RetryAfter(t *testing.T) {
+	limit := Limit{Rate: 10, Period: 10 * time.Second, Algorithm: SlidingWindow}
+
+	// Previous window full, 20% into the current one with 2 requests:
+	// estimate = 10*0.8 + 2 = 10. One slot frees at x = 1 - 7/10 = 0.3.
+	res := slidingWindowResult(limit, 2, 10, 0.2, false)
+	if res.RetryAfter != time.Second {
+		t.Errorf("expected RetryAfter 1s, got %v", res.RetryAfter)
+	}
+
+	// Current window already full: wait for it to roll and decay by 1/10.
+	res = slidingWindowResult(limit, 10, 0, 0.5, false)
+	if res.RetryAfter != 6*time.Second {
+		t.Errorf("expected RetryAfter 6s, got %v", res.RetryAfter)
+	}
+}
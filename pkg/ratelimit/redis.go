@@ -0,0 +1,142 @@
+package ratelimit
+
+import (
+	stdctx "context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// tokenBucketScript refills and takes one token atomically. Time comes from
+// the Redis server so replicas with skewed clocks share one timeline.
+//
+// KEYS[1] bucket key
+// ARGV[1] rate (tokens per period)
+// ARGV[2] period in microseconds
+// ARGV[3] burst
+// Returns {allowed (0/1), tokens left (string)}.
+var tokenBucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1]) / tonumber(ARGV[2])
+local burst = tonumber(ARGV[3])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+
+local data = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(data[1]) or burst
+local ts = tonumber(data[2]) or now
+if now > ts then
+  tokens = math.min(burst, tokens + (now - ts) * rate)
+  ts = now
+end
+
+local allowed = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+end
+
+redis.call('HSET', KEYS[1], 'tokens', string.format('%.9f', tokens), 'ts', string.format('%d', ts))
+local ttl = math.ceil((burst - tokens) / rate / 1000) + 1000
+redis.call('PEXPIRE', KEYS[1], ttl)
+return {allowed, string.format('%.9f', tokens)}
+`)
+
+// slidingWindowScript counts one request against the weighted current and
+// previous fixed windows atomically.
+//
+// KEYS[1] window key
+// ARGV[1] rate
+// ARGV[2] period in microseconds
+// Returns {allowed (0/1), curr, prev, elapsed fraction (string)}.
+var slidingWindowScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local period = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+local window = math.floor(now / period)
+
+local data = redis.call('HMGET', KEYS[1], 'win', 'curr', 'prev')
+local win = tonumber(data[1])
+local curr = tonumber(data[2]) or 0
+local prev = tonumber(data[3]) or 0
+if win == window - 1 then
+  prev = curr
+  curr = 0
+elseif win ~= window then
+  prev = 0
+  curr = 0
+end
+
+local elapsed = (now - window * period) / period
+local allowed = 0
+if prev * (1 - elapsed) + curr + 1 <= rate then
+  curr = curr + 1
+  allowed = 1
+end
+
+redis.call('HSET', KEYS[1], 'win', string.format('%d', window), 'curr', curr, 'prev', prev)
+local ttl = math.ceil(period * 2 / 1000)
+redis.call('PEXPIRE', KEYS[1], ttl)
+return {allowed, curr, prev, string.format('%.9f', elapsed)}
+`)
+
+// RedisStore is a Store backed by Redis, so limits hold across every replica
+// sharing the same instance. Each Take is a single atomic Lua script call.
+type RedisStore struct {
+	client *redis.Client
+}
+
+var _ Store = (*RedisStore)(nil)
+
+// NewRedisStore creates a store on client, typically from cache.NewRedisClient.
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+// Take implements Store.
+func (s *RedisStore) Take(ctx stdctx.Context, key string, limit Limit) (Result, error) {
+	limit.applyDefaults()
+
+	if limit.Algorithm == SlidingWindow {
+		return s.takeSlidingWindow(ctx, key, limit)
+	}
+	return s.takeTokenBucket(ctx, key, limit)
+}
+
+func (s *RedisStore) takeTokenBucket(ctx stdctx.Context, key string, limit Limit) (Result, error) {
+	vals, err := tokenBucketScript.Run(ctx, s.client, []string{key},
+		limit.Rate, int64(limit.Period/time.Microsecond), limit.Burst).Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("ratelimit: token bucket script: %w", err)
+	}
+	if len(vals) != 2 {
+		return Result{}, fmt.Errorf("ratelimit: unexpected script reply %v", vals)
+	}
+
+	tokens, err := strconv.ParseFloat(fmt.Sprint(vals[1]), 64)
+	if err != nil {
+		return Result{}, fmt.Errorf("ratelimit: parse tokens: %w", err)
+	}
+	return tokenBucketResult(limit, tokens, vals[0] == int64(1)), nil
+}
+
+func (s *RedisStore) takeSlidingWindow(ctx stdctx.Context, key string, limit Limit) (Result, error) {
+	vals, err := slidingWindowScript.Run(ctx, s.client, []string{key},
+		limit.Rate, int64(limit.Period/time.Microsecond)).Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("ratelimit: sliding window script: %w", err)
+	}
+	if len(vals) != 4 {
+		return Result{}, fmt.Errorf("ratelimit: unexpected script reply %v", vals)
+	}
+
+	curr, _ := vals[1].(int64)
+	prev, _ := vals[2].(int64)
+	elapsed, err := strconv.ParseFloat(fmt.Sprint(vals[3]), 64)
+	if err != nil {
+		return Result{}, fmt.Errorf("ratelimit: parse elapsed: %w", err)
+	}
+	return slidingWindowResult(limit, float64(curr), float64(prev), elapsed, vals[0] == int64(1)), nil
+}
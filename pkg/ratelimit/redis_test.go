@@ -0,0 +1,104 @@
+package ratelimit
+
+import (
+	stdctx "context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestRedisStore(t *testing.T) (*RedisStore, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	mr.SetTime(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewRedisStore(client), mr
+}
+
+func TestRedisStore_TokenBucket(t *testing.T) {
+	s, mr := newTestRedisStore(t)
+	ctx := stdctx.Background()
+	limit := Limit{Rate: 1, Period: time.Second, Burst: 2}
+
+	for i := 0; i < 2; i++ {
+		res, err := s.Take(ctx, "k", limit)
+		if err != nil {
+			t.Fatalf("Take: %v", err)
+		}
+		if !res.Allowed {
+			t.Fatalf("request %d: expected allowed", i)
+		}
+	}
+
+	res, err := s.Take(ctx, "k", limit)
+	if err != nil {
+		t.Fatalf("Take: %v", err)
+	}
+	if res.Allowed {
+		t.Fatal("expected bucket to be empty")
+	}
+	if res.RetryAfter != time.Second {
+		t.Errorf("expected RetryAfter 1s, got %v", res.RetryAfter)
+	}
+
+	mr.SetTime(time.Date(2026, 1, 1, 0, 0, 1, 0, time.UTC))
+	if res, _ := s.Take(ctx, "k", limit); !res.Allowed {
+		t.Fatal("expected one token to be refilled after 1s")
+	}
+	if ttl := mr.TTL("k"); ttl <= 0 {
+		t.Errorf("expected key to carry a TTL, got %v", ttl)
+	}
+}
+
+func TestRedisStore_SlidingWindow(t *testing.T) {
+	s, mr := newTestRedisStore(t)
+	ctx := stdctx.Background()
+	limit := Limit{Rate: 2, Period: time.Second, Algorithm: SlidingWindow}
+
+	for i := 0; i < 2; i++ {
+		if res, err := s.Take(ctx, "k", limit); err != nil || !res.Allowed {
+			t.Fatalf("request %d: expected allowed, got %+v (err=%v)", i, res, err)
+		}
+	}
+	if res, _ := s.Take(ctx, "k", limit); res.Allowed {
+		t.Fatal("expected 3rd request to be denied")
+	}
+
+	mr.SetTime(time.Date(2026, 1, 1, 0, 0, 3, 0, time.UTC))
+	if res, _ := s.Take(ctx, "k", limit); !res.Allowed || res.Remaining != 1 {
+		t.Errorf("expected fresh window with 1 remaining, got %+v", res)
+	}
+}
+
+func TestRedisStore_ConnectionError(t *testing.T) {
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+	defer client.Close()
+
+	if _, err := NewRedisStore(client).Take(stdctx.Background(), "k", PerSecond(1)); err == nil {
+		t.Error("expected error when redis is unreachable")
+	}
+}
+
+func TestRedisStore_TokenBucket_SlowRate(t *testing.T) {
+	s, mr := newTestRedisStore(t)
+	ctx := stdctx.Background()
+	limit := PerMinute(1)
+
+	if res, _ := s.Take(ctx, "k", limit); !res.Allowed {
+		t.Fatal("expected first request to be allowed")
+	}
+	mr.SetTime(time.Date(2026, 1, 1, 0, 0, 1, 0, time.UTC))
+	res, err := s.Take(ctx, "k", limit)
+	if err != nil {
+		t.Fatalf("Take: %v", err)
+	}
+	if res.Allowed {
+		t.Fatal("expected fractional refill to keep the bucket empty")
+	}
+	if res.RetryAfter < 58*time.Second || res.RetryAfter > 59*time.Second {
+		t.Errorf("expected RetryAfter ~59s, got %v", res.RetryAfter)
+	}
+}
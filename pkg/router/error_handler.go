@@ -0,0 +1,186 @@
+package router
+
+import (
+	stdctx "context"
+	"errors"
+	"net/http"
+	"runtime/debug"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/vietpham102301/lightway/pkg/context"
+	aerror "github.com/vietpham102301/lightway/pkg/errors"
+	"github.com/vietpham102301/lightway/pkg/i18n"
+	"github.com/vietpham102301/lightway/pkg/logger"
+)
+
+// ErrorHandler renders an error returned by a HandlerFunc. It is only
+// called while the response has not started; errors returned after the
+// handler wrote a response are logged instead.
+type ErrorHandler func(c *context.Context, err error)
+
+// ErrorMapper translates an error into an *AppError before it reaches the
+// ErrorHandler. It returns nil when the error is not recognized.
+type ErrorMapper func(err error) *aerror.AppError
+
+// MapError returns an ErrorMapper that maps any error matching target
+// (via errors.Is) to an AppError with the given status code and message.
+func MapError(target error, code int, msg string) ErrorMapper {
+	return func(err error) *aerror.AppError {
+		if errors.Is(err, target) {
+			return aerror.NewAppError(code, msg, err)
+		}
+		return nil
+	}
+}
+
+// CommonErrorMappers returns mappers for errors that frequently escape
+// handlers unwrapped:
+//   - pgx.ErrNoRows → 404 Not Found
+//   - redis.Nil → 404 Not Found
+//   - context.DeadlineExceeded → 504 Gateway Timeout
+func CommonErrorMappers() []ErrorMapper {
+	return []ErrorMapper{
+		MapError(pgx.ErrNoRows, http.StatusNotFound, "Not Found"),
+		MapError(redis.Nil, http.StatusNotFound, "Not Found"),
+		MapError(stdctx.DeadlineExceeded, http.StatusGatewayTimeout, "Gateway Timeout"),
+	}
+}
+
+// DefaultErrorHandler renders an *AppError as an AppResponse with its status
+// code. Errors joined with errors.Join are rendered together, using the most
+// severe status among them. Any other error is logged and rendered as a 500
+// without leaking the underlying message to the client.
+func DefaultErrorHandler(c *context.Context, err error) {
+	appErrs := aerror.Collect(err)
+	if len(appErrs) == 0 {
+		logUnhandledError(c, err)
+		c.JSONResponse(http.StatusInternalServerError, nil, errors.New("internal server error"))
+		return
+	}
+
+	status := severestStatus(appErrs)
+	if status >= http.StatusInternalServerError {
+		logUnhandledError(c, err)
+	}
+	if len(appErrs) == 1 {
+		c.JSONResponse(status, nil, appErrs[0])
+		return
+	}
+	c.JSONResponse(status, nil, err)
+}
+
+// ProblemErrorHandler renders errors as RFC 7807 application/problem+json
+// responses. Non-AppError failures are logged and reported as a generic 500.
+func ProblemErrorHandler(c *context.Context, err error) {
+	problem := context.Problem{
+		Status:   http.StatusInternalServerError,
+		Instance: c.R.URL.Path,
+	}
+
+	appErrs := aerror.Collect(err)
+	switch len(appErrs) {
+	case 0:
+		logUnhandledError(c, err)
+	case 1:
+		appErr := i18n.Localize(c.Context(), appErrs[0])
+		problem.Status = appErr.Code
+		problem.Detail = appErr.Message
+		problem.Code = appErr.ErrorCode
+		problem.Details = appErr.Details
+		problem.Fields = appErr.Fields
+	default:
+		problem.Status = severestStatus(appErrs)
+		localized := make([]*aerror.AppError, len(appErrs))
+		msgs := make([]string, len(appErrs))
+		for i, e := range appErrs {
+			localized[i] = i18n.Localize(c.Context(), e)
+			msgs[i] = localized[i].Message
+		}
+		problem.Detail = strings.Join(msgs, "; ")
+		problem.Errors = localized
+	}
+	if len(appErrs) > 0 && problem.Status >= http.StatusInternalServerError {
+		logUnhandledError(c, err)
+	}
+
+	c.ProblemResponse(problem)
+}
+
+// severestStatus returns the highest status code among appErrs.
+func severestStatus(appErrs []*aerror.AppError) int {
+	status := appErrs[0].Code
+	for _, e := range appErrs[1:] {
+		if e.Code > status {
+			status = e.Code
+		}
+	}
+	return status
+}
+
+// SetErrorHandler replaces the handler used to render errors returned by
+// routes registered after this call. Groups inherit the handler in effect
+// when they are created.
+func (r *Router) SetErrorHandler(h ErrorHandler) {
+	if h == nil {
+		h = DefaultErrorHandler
+	}
+	r.errorHandler = h
+}
+
+// MapErrors appends error mappers consulted, in order, before the error
+// handler runs. The first mapper returning a non-nil AppError wins.
+// Errors that already contain an *AppError are passed through unchanged.
+func (r *Router) MapErrors(mappers ...ErrorMapper) {
+	r.errorMappers = append(r.errorMappers, mappers...)
+}
+
+// mapError applies mappers to err, returning the first match or err itself.
+func mapError(mappers []ErrorMapper, err error) error {
+	var appErr *aerror.AppError
+	if len(mappers) == 0 || errors.As(err, &appErr) {
+		return err
+	}
+	for _, m := range mappers {
+		if mapped := m(err); mapped != nil {
+			return mapped
+		}
+	}
+	return err
+}
+
+// logLateError logs an error returned after the response had started, when
+// it can no longer reach the client.
+func logLateError(c *context.Context, err error) {
+	logger.Warn("router: error after response started",
+		"method", c.R.Method,
+		"path", c.R.URL.Path,
+		"route", c.R.Pattern,
+		"err", err,
+	)
+}
+
+// logUnhandledError logs a server-side failure together with the request it
+// occurred in. The stack recorded by the AppError is preferred when available;
+// otherwise the current stack is used.
+func logUnhandledError(c *context.Context, err error) {
+	stack := ""
+	var appErr *aerror.AppError
+	if errors.As(err, &appErr) {
+		stack = appErr.StackTrace()
+	}
+	if stack == "" {
+		stack = string(debug.Stack())
+	}
+
+	logger.Error("router: unhandled error",
+		"method", c.R.Method,
+		"path", c.R.URL.Path,
+		"route", c.R.Pattern,
+		"remote_addr", c.R.RemoteAddr,
+		"err", err,
+		"stack", stack,
+	)
+}
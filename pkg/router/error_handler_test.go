@@ -0,0 +1,328 @@
+package router
+
+import (
+	stdctx "context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/vietpham102301/lightway/pkg/context"
+	aerror "github.com/vietpham102301/lightway/pkg/errors"
+)
+
+// ===========================================================================
+// SetErrorHandler
+// ===========================================================================
+
+func TestRouter_SetErrorHandler(t *testing.T) {
+	r := NewRouter()
+	var got error
+	r.SetErrorHandler(func(c *context.Context, err error) {
+		got = err
+		c.W.WriteHeader(http.StatusTeapot)
+	})
+
+	sentinel := errors.New("custom")
+	r.GET("/fail", func(c *context.Context) error { return sentinel })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/fail", nil))
+
+	if w.Code != http.StatusTeapot {
+		t.Errorf("expected status %d, got %d", http.StatusTeapot, w.Code)
+	}
+	if !errors.Is(got, sentinel) {
+		t.Errorf("expected handler to receive sentinel error, got %v", got)
+	}
+}
+
+func TestRouter_SetErrorHandler_InheritedByGroup(t *testing.T) {
+	r := NewRouter()
+	r.SetErrorHandler(func(c *context.Context, err error) {
+		c.W.WriteHeader(http.StatusTeapot)
+	})
+	api := r.Group("/api")
+	api.GET("/fail", func(c *context.Context) error { return errors.New("x") })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/api/fail", nil))
+
+	if w.Code != http.StatusTeapot {
+		t.Errorf("expected group to inherit error handler, got status %d", w.Code)
+	}
+}
+
+func TestRouter_SetErrorHandler_NilRestoresDefault(t *testing.T) {
+	r := NewRouter()
+	r.SetErrorHandler(nil)
+	r.GET("/fail", func(c *context.Context) error { return aerror.NotFound("gone") })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/fail", nil))
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestRouter_ErrorAfterResponseStarted(t *testing.T) {
+	r := NewRouter()
+	called := false
+	r.SetErrorHandler(func(c *context.Context, err error) {
+		called = true
+		c.W.Write([]byte("error"))
+	})
+	r.GET("/partial", func(c *context.Context) error {
+		c.W.WriteHeader(http.StatusOK)
+		c.W.Write([]byte("partial"))
+		return errors.New("stream broke")
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/partial", nil))
+
+	if called {
+		t.Error("expected the error handler not to run after the response started")
+	}
+	if w.Code != http.StatusOK || w.Body.String() != "partial" {
+		t.Errorf("expected the started response untouched, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestDefaultErrorHandler_HidesInternalMessage(t *testing.T) {
+	r := NewRouter()
+	r.GET("/fail", func(c *context.Context) error { return fmt.Errorf("db password wrong") })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/fail", nil))
+
+	var resp context.AppResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if resp.Error != "internal server error" {
+		t.Errorf("expected generic error message, got %q", resp.Error)
+	}
+}
+
+// ===========================================================================
+// ProblemErrorHandler
+// ===========================================================================
+
+func TestProblemErrorHandler_AppError(t *testing.T) {
+	r := NewRouter()
+	r.SetErrorHandler(ProblemErrorHandler)
+	r.GET("/users/{id}", func(c *context.Context) error { return aerror.NotFound("user not found") })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/users/7", nil))
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Errorf("expected problem+json content type, got %q", ct)
+	}
+
+	var p context.Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+		t.Fatalf("failed to unmarshal problem: %v", err)
+	}
+	if p.Type != "about:blank" {
+		t.Errorf("expected type 'about:blank', got %q", p.Type)
+	}
+	if p.Title != "Not Found" {
+		t.Errorf("expected title 'Not Found', got %q", p.Title)
+	}
+	if p.Status != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d", http.StatusNotFound, p.Status)
+	}
+	if p.Detail != "user not found" {
+		t.Errorf("expected detail 'user not found', got %q", p.Detail)
+	}
+	if p.Instance != "/users/7" {
+		t.Errorf("expected instance '/users/7', got %q", p.Instance)
+	}
+}
+
+func TestProblemErrorHandler_GenericError(t *testing.T) {
+	r := NewRouter()
+	r.SetErrorHandler(ProblemErrorHandler)
+	r.GET("/fail", func(c *context.Context) error { return errors.New("secret") })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/fail", nil))
+
+	var p context.Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+		t.Fatalf("failed to unmarshal problem: %v", err)
+	}
+	if p.Status != http.StatusInternalServerError {
+		t.Errorf("expected status %d, got %d", http.StatusInternalServerError, p.Status)
+	}
+	if p.Detail != "" {
+		t.Errorf("expected no detail for internal errors, got %q", p.Detail)
+	}
+}
+
+// ===========================================================================
+// Error mapping
+// ===========================================================================
+
+func TestRouter_MapErrors_CommonMappers(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{"pgx.ErrNoRows", fmt.Errorf("get user: %w", pgx.ErrNoRows), http.StatusNotFound},
+		{"redis.Nil", redis.Nil, http.StatusNotFound},
+		{"DeadlineExceeded", stdctx.DeadlineExceeded, http.StatusGatewayTimeout},
+		{"unmapped", errors.New("boom"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRouter()
+			r.MapErrors(CommonErrorMappers()...)
+			r.GET("/fail", func(c *context.Context) error { return tt.err })
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest("GET", "/fail", nil))
+
+			if w.Code != tt.expected {
+				t.Errorf("expected status %d, got %d", tt.expected, w.Code)
+			}
+		})
+	}
+}
+
+func TestRouter_MapErrors_DomainError(t *testing.T) {
+	errInsufficientFunds := errors.New("insufficient funds")
+
+	r := NewRouter()
+	r.MapErrors(MapError(errInsufficientFunds, http.StatusPaymentRequired, "Insufficient Funds"))
+	r.POST("/pay", func(c *context.Context) error {
+		return fmt.Errorf("charge: %w", errInsufficientFunds)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/pay", nil))
+
+	if w.Code != http.StatusPaymentRequired {
+		t.Errorf("expected status %d, got %d", http.StatusPaymentRequired, w.Code)
+	}
+
+	var resp context.AppResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if resp.Error != "Insufficient Funds" {
+		t.Errorf("expected error 'Insufficient Funds', got %q", resp.Error)
+	}
+}
+
+func TestRouter_MapErrors_AppErrorPassesThrough(t *testing.T) {
+	r := NewRouter()
+	r.MapErrors(func(err error) *aerror.AppError {
+		return aerror.InternalServerError()
+	})
+	r.GET("/fail", func(c *context.Context) error { return aerror.Unauthorized("no token") })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/fail", nil))
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected AppError to bypass mappers, got status %d", w.Code)
+	}
+}
+
+// ===========================================================================
+// Rich AppError rendering
+// ===========================================================================
+
+func TestDefaultErrorHandler_RendersErrorCodeAndFields(t *testing.T) {
+	r := NewRouter()
+	r.POST("/users", func(c *context.Context) error {
+		return aerror.UnprocessableEntity("validation failed",
+			aerror.FieldError{Field: "email", Message: "required"})
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/users", nil))
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected status %d, got %d", http.StatusUnprocessableEntity, w.Code)
+	}
+
+	var resp context.AppResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if resp.ErrorCode != aerror.CodeUnprocessableEntity {
+		t.Errorf("expected error code %q, got %q", aerror.CodeUnprocessableEntity, resp.ErrorCode)
+	}
+	if len(resp.Fields) != 1 || resp.Fields[0].Field != "email" {
+		t.Errorf("expected field error for 'email', got %+v", resp.Fields)
+	}
+}
+
+func TestDefaultErrorHandler_JoinedErrors(t *testing.T) {
+	r := NewRouter()
+	r.POST("/batch", func(c *context.Context) error {
+		return errors.Join(
+			aerror.InvalidRequest(nil),
+			errors.New("internal detail"),
+			aerror.Conflict("duplicate item"),
+		)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/batch", nil))
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected most severe status %d, got %d", http.StatusConflict, w.Code)
+	}
+
+	var resp context.AppResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if resp.Error != "Invalid Request; duplicate item" {
+		t.Errorf("expected joined AppError messages, got %q", resp.Error)
+	}
+	if len(resp.Errors) != 2 {
+		t.Fatalf("expected 2 entries in errors, got %d", len(resp.Errors))
+	}
+	if resp.Errors[1].ErrorCode != aerror.CodeConflict {
+		t.Errorf("expected second error code %q, got %q", aerror.CodeConflict, resp.Errors[1].ErrorCode)
+	}
+}
+
+func TestProblemErrorHandler_ErrorCode(t *testing.T) {
+	r := NewRouter()
+	r.SetErrorHandler(ProblemErrorHandler)
+	r.GET("/admin", func(c *context.Context) error {
+		return aerror.Forbidden("admins only").WithDetail("role", "viewer")
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/admin", nil))
+
+	var p context.Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+		t.Fatalf("failed to unmarshal problem: %v", err)
+	}
+	if p.Code != aerror.CodeForbidden {
+		t.Errorf("expected code %q, got %q", aerror.CodeForbidden, p.Code)
+	}
+	if p.Details["role"] != "viewer" {
+		t.Errorf("expected details.role 'viewer', got %v", p.Details["role"])
+	}
+}
@@ -1,13 +1,15 @@
 package router
 
 import (
-	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"strings"
+	"sync"
 
 	"github.com/vietpham102301/lightway/pkg/context"
-	aerror "github.com/vietpham102301/lightway/pkg/errors"
 )
 
 // color returns the ANSI escape code if colors are enabled, or empty string otherwise.
@@ -32,9 +34,16 @@ const (
 type HandlerFunc func(c *context.Context) error
 type Middleware func(http.Handler) http.Handler
 
+// RouteEntry describes a registered route. Method is "*" for handlers that
+// accept any method, such as those added with Mount.
 type RouteEntry struct {
 	Method string
+	Host   string // empty unless registered on a Host router
 	Path   string
+
+	// Schema describes the request and response types of routes registered
+	// with HandleEndpoint, and is nil otherwise.
+	Schema *Schema
 }
 
 // responseWriter wraps http.ResponseWriter to track if headers were written
@@ -57,76 +66,271 @@ func (rw *responseWriter) Write(b []byte) (int, error) {
 	return rw.ResponseWriter.Write(b)
 }
 
+// HeaderWritten reports whether a response has started, either through this
+// writer or, for writers that track it too, further down the chain (e.g. a
+// timed-out request whose response was already sent by the middleware).
 func (rw *responseWriter) HeaderWritten() bool {
-	return rw.headerWritten
+	if rw.headerWritten {
+		return true
+	}
+	if hw, ok := rw.ResponseWriter.(interface{ HeaderWritten() bool }); ok {
+		return hw.HeaderWritten()
+	}
+	return false
+}
+
+// Flush sends buffered data to the client, so handlers can stream through
+// the wrapper (and any compression middleware beneath it).
+func (rw *responseWriter) Flush() {
+	if !rw.headerWritten {
+		rw.WriteHeader(http.StatusOK)
+	}
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// routeTable is the routing state shared by a router and its groups beyond
+// what the mux holds.
+type routeTable struct {
+	mu sync.RWMutex
+
+	// preflight holds, by mux pattern, the route's middleware in front of
+	// the mux, for CORS preflights to paths without an OPTIONS route.
+	preflight map[string]http.Handler
+
+	// notFound serves GET and HEAD requests no route matches, by host.
+	notFound map[string]http.Handler
+}
+
+func newRouteTable() *routeTable {
+	return &routeTable{
+		preflight: make(map[string]http.Handler),
+		notFound:  make(map[string]http.Handler),
+	}
+}
+
+func (t *routeTable) addPreflight(pattern string, h http.Handler) {
+	t.mu.Lock()
+	t.preflight[pattern] = h
+	t.mu.Unlock()
+}
+
+func (t *routeTable) preflightFor(pattern string) http.Handler {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.preflight[pattern]
+}
+
+func (t *routeTable) setNotFound(host string, h http.Handler) {
+	t.mu.Lock()
+	t.notFound[host] = h
+	t.mu.Unlock()
+}
+
+func (t *routeTable) notFoundFor(host string) http.Handler {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if h, ok := t.notFound[host]; ok {
+		return h
+	}
+	return t.notFound[""]
 }
 
 type Router struct {
-	mux         *http.ServeMux
-	prefix      string
-	middlewares []Middleware
-	routes      *[]RouteEntry
+	mux          *http.ServeMux
+	host         string
+	prefix       string
+	middlewares  []Middleware
+	routes       *[]RouteEntry
+	table        *routeTable
+	errorHandler ErrorHandler
+	errorMappers []ErrorMapper
 }
 
 func NewRouter() *Router {
 	return &Router{
-		mux:         http.NewServeMux(),
-		prefix:      "",
-		middlewares: []Middleware{},
-		routes:      &[]RouteEntry{},
+		mux:          http.NewServeMux(),
+		prefix:       "",
+		middlewares:  []Middleware{},
+		routes:       &[]RouteEntry{},
+		table:        newRouteTable(),
+		errorHandler: DefaultErrorHandler,
 	}
 }
 
 func (r *Router) Group(path string) *Router {
 	return &Router{
-		mux:         r.mux,
-		prefix:      r.prefix + path,
-		middlewares: append([]Middleware(nil), r.middlewares...),
-		routes:      r.routes,
+		mux:          r.mux,
+		host:         r.host,
+		prefix:       r.prefix + path,
+		middlewares:  append([]Middleware(nil), r.middlewares...),
+		routes:       r.routes,
+		table:        r.table,
+		errorHandler: r.errorHandler,
+		errorMappers: append([]ErrorMapper(nil), r.errorMappers...),
 	}
 }
 
+// Host returns a copy of the router whose routes only match requests for
+// host, e.g. "api.example.com"; the request's port is ignored. Routes
+// without a host still match every host, but a host route wins over a
+// hostless one for the same path. The copy shares the prefix, middleware and
+// routes.
+func (r *Router) Host(host string) *Router {
+	g := r.Group("")
+	g.host = host
+	return g
+}
+
 func (r *Router) Use(mw ...Middleware) {
 	r.middlewares = append(r.middlewares, mw...)
 }
 
+// With returns a copy of the router, sharing its prefix and routes, with mw
+// appended to its middleware chain. Use it to apply middleware to single
+// routes:
+//
+//	api.With(router.Timeout(2*time.Second)).GET("/report", reportHandler)
+func (r *Router) With(mw ...Middleware) *Router {
+	g := r.Group("")
+	g.Use(mw...)
+	return g
+}
+
 func (r *Router) Handle(method, path string, handler HandlerFunc) {
-	standardHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	r.handle(method, path, handler, nil)
+}
+
+// HandleEndpoint registers e like Handle and records its Schema in Routes.
+func (r *Router) HandleEndpoint(method, path string, e Endpoint) {
+	schema := e.Schema()
+	r.handle(method, path, e.Serve, &schema)
+}
+
+func (r *Router) handle(method, path string, handler HandlerFunc, schema *Schema) {
+	r.register(method, path, r.adapt(handler), schema)
+}
+
+// adapt turns handler into an http.Handler that reports its errors through
+// the router's error handler.
+func (r *Router) adapt(handler HandlerFunc) http.Handler {
+	errorHandler := r.errorHandler
+	if errorHandler == nil {
+		errorHandler = DefaultErrorHandler
+	}
+	errorMappers := r.errorMappers
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		rw := &responseWriter{ResponseWriter: w}
 		ctx := &context.Context{
 			W: rw,
 			R: r,
 		}
 		err := handler(ctx)
-		if err != nil {
-			if !rw.headerWritten {
-				var appErr *aerror.AppError
-				if errors.As(err, &appErr) {
-					ctx.JSONResponse(appErr.Code, nil, appErr)
-					return
-				}
-
-				ctx.JSONResponse(http.StatusInternalServerError, nil, errors.New("internal server error"))
-			}
+		if err == nil {
+			return
 		}
+		if rw.HeaderWritten() {
+			logLateError(ctx, err)
+			return
+		}
+		errorHandler(ctx, mapError(errorMappers, err))
 	})
+}
 
-	finalHandler := http.Handler(standardHandler)
-	for i := len(r.middlewares) - 1; i >= 0; i-- {
-		finalHandler = r.middlewares[i](finalHandler)
+// HandleHTTP registers a plain http.Handler behind the router's middleware.
+// An empty method matches every method, and a path ending in "/" matches the
+// whole subtree, as with http.ServeMux. The request path is passed through
+// unchanged; use Mount to strip the prefix.
+//
+//	r.HandleHTTP("", "/debug/pprof/", http.DefaultServeMux)
+func (r *Router) HandleHTTP(method, path string, h http.Handler) {
+	r.register(method, path, h, nil)
+}
+
+// Mount serves h under prefix, stripping the router's prefix and prefix
+// from the request path before calling it. Both prefix and every path below
+// it are routed to h for any method.
+//
+//	r.Mount("/gateway", gwmux) // GET /gateway/v1/users reaches gwmux as /v1/users
+func (r *Router) Mount(prefix string, h http.Handler) {
+	prefix = strings.TrimSuffix(prefix, "/")
+	r.register("", prefix+"/", http.StripPrefix(r.prefix+prefix, h), nil)
+	if r.prefix+prefix == "" {
+		return
 	}
+	// The bare prefix reaches h as "/" rather than being redirected.
+	r.mux.Handle(r.host+r.prefix+prefix, r.wrap(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		req2 := new(http.Request)
+		*req2 = *req
+		req2.URL = new(url.URL)
+		*req2.URL = *req.URL
+		req2.URL.Path, req2.URL.RawPath = "/", ""
+		h.ServeHTTP(w, req2)
+	})))
+}
 
-	fullPattern := method + " " + r.prefix + path
+// register wraps h in the router's middleware, records the route and adds
+// it to the mux.
+func (r *Router) register(method, path string, h http.Handler, schema *Schema) {
+	finalHandler := r.wrap(h)
+	fullPath := r.host + r.prefix + path
+	r.record(method, path, schema)
+
+	if method == "" {
+		r.mux.Handle(fullPath, finalHandler)
+		return
+	}
+	pattern := method + " " + fullPath
+	r.mux.Handle(pattern, finalHandler)
+	if method != http.MethodOptions {
+		r.table.addPreflight(pattern, r.wrap(r.mux))
+	}
+}
+
+// record adds a route to Routes.
+func (r *Router) record(method, path string, schema *Schema) {
 	displayPath := r.prefix + path
 	if displayPath == "" {
 		displayPath = "/"
 	}
+	displayMethod := method
+	if displayMethod == "" {
+		displayMethod = "*"
+	}
 	*r.routes = append(*r.routes, RouteEntry{
-		Method: method,
+		Method: displayMethod,
+		Host:   r.host,
 		Path:   displayPath,
+		Schema: schema,
 	})
+}
+
+// handleNotFound serves GET and HEAD requests for this router's host that
+// match no route for any method with handler, behind the router's
+// middleware. Other requests keep the mux's 404 and 405 responses.
+func (r *Router) handleNotFound(path string, handler HandlerFunc) {
+	r.record(http.MethodGet, path, nil)
+	r.table.setNotFound(r.host, r.wrap(r.adapt(handler)))
+}
+
+// wrap applies the router's middleware chain to h.
+func (r *Router) wrap(h http.Handler) http.Handler {
+	for i := len(r.middlewares) - 1; i >= 0; i-- {
+		h = r.middlewares[i](h)
+	}
+	return h
+}
 
-	r.mux.Handle(fullPattern, finalHandler)
+// Routes returns the registered routes in registration order, for example
+// to generate an OpenAPI document from their schemas.
+func (r *Router) Routes() []RouteEntry {
+	return append([]RouteEntry(nil), *r.routes...)
 }
 
 func (r *Router) PrintRoutes() {
@@ -144,16 +348,125 @@ func (r *Router) PrintRoutes() {
 			color(ansiWhite),
 			color(methodColor), route.Method,
 			color(ansiReset),
-			color(ansiBlue), route.Path,
+			color(ansiBlue), route.Host+route.Path,
 			color(ansiReset),
 		)
 	}
 }
 
 func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if isPreflight(req) {
+		if h := r.preflight(req); h != nil {
+			h.ServeHTTP(w, req)
+			return
+		}
+	} else if req.Method == http.MethodGet || req.Method == http.MethodHead {
+		if h := r.table.notFoundFor(stripPort(req.Host)); h != nil {
+			r.serveOrNotFound(w, req, h)
+			return
+		}
+	}
 	r.mux.ServeHTTP(w, req)
 }
 
+// match returns the handler of the route matching req, or of the route a
+// CORS preflight asks about (see preflight).
+func (r *Router) match(req *http.Request) (http.Handler, bool) {
+	if _, pattern := r.mux.Handler(req); pattern != "" {
+		return r.mux, true // the mux sets the path values
+	}
+	if !isPreflight(req) {
+		return nil, false
+	}
+	h := r.preflight(req)
+	return h, h != nil
+}
+
+// isPreflight reports whether req is a CORS preflight.
+func isPreflight(req *http.Request) bool {
+	return req.Method == http.MethodOptions && req.Header.Get("Origin") != "" &&
+		req.Header.Get("Access-Control-Request-Method") != ""
+}
+
+// preflight returns, for a CORS preflight to a path without an OPTIONS
+// route, the middleware of the route for its Access-Control-Request-Method
+// in front of the mux, so a group's CORS policy can answer it. Without a
+// CORS policy the mux replies 405 as for any other OPTIONS request. It
+// returns nil if an OPTIONS route matches or no route does.
+func (r *Router) preflight(req *http.Request) http.Handler {
+	if _, pattern := r.mux.Handler(req); pattern != "" {
+		return nil
+	}
+	probe := req.Clone(req.Context())
+	probe.Method = req.Header.Get("Access-Control-Request-Method")
+	if _, pattern := r.mux.Handler(probe); pattern != "" {
+		return r.table.preflightFor(pattern)
+	}
+	return nil
+}
+
+// serveOrNotFound serves req through the mux, unless the mux would answer
+// with its own 404, meaning no route matches the path for any method; then
+// notFound serves it instead.
+func (r *Router) serveOrNotFound(w http.ResponseWriter, req *http.Request, notFound http.Handler) {
+	nw := &notFoundWriter{ResponseWriter: w, req: req, header: w.Header().Clone()}
+	r.mux.ServeHTTP(nw, req)
+	if nw.notFound {
+		// Drop the headers the mux set for its 404.
+		h := w.Header()
+		clear(h)
+		for k, v := range nw.header {
+			h[k] = v
+		}
+		notFound.ServeHTTP(w, req)
+	}
+}
+
+// notFoundWriter passes a response through, except the mux's own 404, which
+// it discards. The mux sets req.Pattern before calling a route, so a 404
+// with no pattern can only come from the mux.
+type notFoundWriter struct {
+	http.ResponseWriter
+	req      *http.Request
+	header   http.Header // w's header before the mux ran
+	notFound bool
+}
+
+func (w *notFoundWriter) WriteHeader(code int) {
+	if code == http.StatusNotFound && w.req.Pattern == "" {
+		w.notFound = true
+		return
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *notFoundWriter) Write(b []byte) (int, error) {
+	if w.notFound {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush lets routes stream through the wrapper.
+func (w *notFoundWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok && !w.notFound {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *notFoundWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// stripPort returns host without its port, if any.
+func stripPort(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
+
 // WithMiddleware wraps the router with additional middlewares and returns an http.Handler.
 func (r *Router) WithMiddleware(middlewares ...Middleware) http.Handler {
 	handler := http.Handler(r)
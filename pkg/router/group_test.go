@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/vietpham102301/lightway/pkg/context"
+	"github.com/vietpham102301/lightway/pkg/cors"
 	aerror "github.com/vietpham102301/lightway/pkg/errors"
 )
 
@@ -389,6 +390,25 @@ func TestResponseWriter_HeaderWritten(t *testing.T) {
 	}
 }
 
+func TestResponseWriter_Flush(t *testing.T) {
+	inner := httptest.NewRecorder()
+	rw := &responseWriter{ResponseWriter: inner}
+
+	var w http.ResponseWriter = rw
+	f, ok := w.(http.Flusher)
+	if !ok {
+		t.Fatal("expected responseWriter to implement http.Flusher")
+	}
+	f.Flush()
+
+	if !rw.HeaderWritten() {
+		t.Error("expected HeaderWritten to be true after Flush")
+	}
+	if !inner.Flushed {
+		t.Error("expected underlying writer to be flushed")
+	}
+}
+
 // ===========================================================================
 // PrintRoutes (smoke test — just ensure no panic)
 // ===========================================================================
@@ -433,3 +453,256 @@ func TestRouter_RoutesTracked(t *testing.T) {
 		}
 	}
 }
+
+// ===========================================================================
+// OPTIONS Handling
+// ===========================================================================
+
+func TestRouter_OptionsWithoutRoute(t *testing.T) {
+	r := NewRouter()
+	r.GET("/items", func(c *context.Context) error { return nil })
+	r.POST("/items", func(c *context.Context) error { return nil })
+
+	req := httptest.NewRequest("OPTIONS", "/items", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", w.Code)
+	}
+	if got := w.Header().Get("Allow"); got != "GET, HEAD, POST" {
+		t.Errorf("expected Allow 'GET, HEAD, POST', got %q", got)
+	}
+}
+
+func TestRouter_PreflightWithoutCORSPolicy(t *testing.T) {
+	r := NewRouter()
+	r.GET("/items", func(c *context.Context) error { return nil })
+
+	req := httptest.NewRequest("OPTIONS", "/items", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", "GET")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", w.Code)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("expected no CORS headers, got Access-Control-Allow-Origin %q", got)
+	}
+}
+
+func TestRouter_OverlappingWildcardRoutes(t *testing.T) {
+	r := NewRouter()
+	r.Use(cors.Default())
+	r.GET("/a/{x}", func(c *context.Context) error {
+		c.W.Write([]byte("get " + c.R.PathValue("x")))
+		return nil
+	})
+	r.POST("/{y}/b", func(c *context.Context) error {
+		c.W.Write([]byte("post " + c.R.PathValue("y")))
+		return nil
+	})
+
+	for _, tc := range []struct{ method, want string }{
+		{"GET", "get b"},
+		{"POST", "post a"},
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(tc.method, "/a/b", nil))
+		if w.Body.String() != tc.want {
+			t.Errorf("%s /a/b: expected %q, got %d %q", tc.method, tc.want, w.Code, w.Body.String())
+		}
+
+		req := httptest.NewRequest("OPTIONS", "/a/b", nil)
+		req.Header.Set("Origin", "https://anyone.com")
+		req.Header.Set("Access-Control-Request-Method", tc.method)
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusNoContent || w.Header().Get("Access-Control-Allow-Methods") != tc.method {
+			t.Errorf("%s preflight: expected 204 allowing %s, got %d %q",
+				tc.method, tc.method, w.Code, w.Header().Get("Access-Control-Allow-Methods"))
+		}
+	}
+}
+
+func TestRouter_ExplicitOptions(t *testing.T) {
+	r := NewRouter()
+	r.GET("/items", func(c *context.Context) error { return nil })
+	r.OPTIONS("/items", func(c *context.Context) error {
+		c.W.WriteHeader(http.StatusOK)
+		c.W.Write([]byte("custom"))
+		return nil
+	})
+
+	req := httptest.NewRequest("OPTIONS", "/items", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Body.String() != "custom" {
+		t.Errorf("expected explicit OPTIONS handler, got %q", w.Body.String())
+	}
+}
+
+func TestRouter_GroupCORSPreflight(t *testing.T) {
+	r := NewRouter()
+
+	public := r.Group("/public")
+	public.Use(cors.Default())
+	public.GET("/feed", func(c *context.Context) error { return nil })
+
+	admin := r.Group("/admin")
+	adminCfg := cors.DefaultConfig()
+	adminCfg.AllowedOrigins = []string{"https://admin.example.com"}
+	admin.Use(cors.New(adminCfg))
+	admin.POST("/users", func(c *context.Context) error { return nil })
+
+	preflight := func(path, origin, method string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("OPTIONS", path, nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", method)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := preflight("/public/feed", "https://anyone.com", "GET"); w.Code != http.StatusNoContent ||
+		w.Header().Get("Access-Control-Allow-Origin") != "https://anyone.com" {
+		t.Errorf("expected public preflight allowed, got %d %q", w.Code, w.Header().Get("Access-Control-Allow-Origin"))
+	}
+	if w := preflight("/admin/users", "https://anyone.com", "POST"); w.Code != http.StatusForbidden {
+		t.Errorf("expected admin preflight from foreign origin rejected, got %d", w.Code)
+	}
+	if w := preflight("/admin/users", "https://admin.example.com", "POST"); w.Code != http.StatusNoContent {
+		t.Errorf("expected admin preflight from admin origin allowed, got %d", w.Code)
+	}
+}
+
+// ===========================================================================
+// Host Routing and Mounting
+// ===========================================================================
+
+func TestRouter_Host(t *testing.T) {
+	r := NewRouter()
+	r.GET("/whoami", func(c *context.Context) error {
+		c.W.Write([]byte("default"))
+		return nil
+	})
+	acme := r.Host("acme.example.com")
+	acme.GET("/whoami", func(c *context.Context) error {
+		c.W.Write([]byte("acme"))
+		return nil
+	})
+	acme.Group("/api").GET("/ping", func(c *context.Context) error {
+		c.W.Write([]byte("pong"))
+		return nil
+	})
+
+	tests := []struct {
+		host, path string
+		code       int
+		body       string
+	}{
+		{"acme.example.com", "/whoami", http.StatusOK, "acme"},
+		{"acme.example.com:8080", "/whoami", http.StatusOK, "acme"},
+		{"other.example.com", "/whoami", http.StatusOK, "default"},
+		{"acme.example.com", "/api/ping", http.StatusOK, "pong"},
+		{"other.example.com", "/api/ping", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		req.Host = tt.host
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != tt.code {
+			t.Errorf("%s%s: expected status %d, got %d", tt.host, tt.path, tt.code, w.Code)
+		}
+		if tt.body != "" && w.Body.String() != tt.body {
+			t.Errorf("%s%s: expected body %q, got %q", tt.host, tt.path, tt.body, w.Body.String())
+		}
+	}
+
+	routes := r.Routes()
+	if routes[1].Host != "acme.example.com" || routes[1].Path != "/whoami" {
+		t.Errorf("expected host route to be recorded, got %+v", routes[1])
+	}
+}
+
+func TestRouter_Mount(t *testing.T) {
+	var gotPath, gotMethod string
+	external := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		gotPath, gotMethod = req.URL.Path, req.Method
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	r := NewRouter()
+	api := r.Group("/api")
+	api.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("X-Group", "api")
+			next.ServeHTTP(w, req)
+		})
+	})
+	api.Mount("/gateway", external)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("PATCH", "/api/gateway/v1/users?x=1", nil))
+
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected status 202, got %d", w.Code)
+	}
+	if gotPath != "/v1/users" || gotMethod != "PATCH" {
+		t.Errorf("expected PATCH /v1/users, got %s %s", gotMethod, gotPath)
+	}
+	if w.Header().Get("X-Group") != "api" {
+		t.Error("expected group middleware to apply to mounted handler")
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/api/gateway/", nil))
+	if gotPath != "/" {
+		t.Errorf("expected mount root to reach handler as /, got %q", gotPath)
+	}
+
+	for _, method := range []string{"GET", "POST"} {
+		gotPath, gotMethod = "", ""
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, "/api/gateway?x=1", nil))
+		if w.Code != http.StatusAccepted || gotPath != "/" || gotMethod != method {
+			t.Errorf("expected bare prefix to reach handler as %s /, got %d %s %q", method, w.Code, gotMethod, gotPath)
+		}
+		if w.Header().Get("X-Group") != "api" {
+			t.Errorf("%s: expected group middleware on the bare prefix", method)
+		}
+	}
+
+	routes := r.Routes()
+	if len(routes) != 1 || routes[0].Method != "*" || routes[0].Path != "/api/gateway/" {
+		t.Errorf("expected mounted route listed as * /api/gateway/, got %+v", routes)
+	}
+}
+
+func TestRouter_HandleHTTP(t *testing.T) {
+	var gotPath string
+	r := NewRouter()
+	r.HandleHTTP("", "/debug/pprof/", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		gotPath = req.URL.Path
+	}))
+	r.HandleHTTP("GET", "/metrics", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("metrics"))
+	}))
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/debug/pprof/heap", nil))
+	if gotPath != "/debug/pprof/heap" {
+		t.Errorf("expected full path to be kept, got %q", gotPath)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/metrics", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status 405, got %d", w.Code)
+	}
+}
@@ -0,0 +1,237 @@
+package router
+
+import (
+	stdctx "context"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/vietpham102301/lightway/pkg/context"
+	aerror "github.com/vietpham102301/lightway/pkg/errors"
+	"github.com/vietpham102301/lightway/pkg/logger"
+)
+
+// BodyLimit returns a middleware that caps request bodies at maxBytes.
+// Requests declaring a larger Content-Length are rejected up front with a
+// 413 AppError; chunked bodies are cut off while reading, which makes
+// c.BindJSON return the same 413 AppError.
+func BodyLimit(maxBytes int64) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > maxBytes {
+				c := &context.Context{W: w, R: r}
+				c.JSONResponse(http.StatusRequestEntityTooLarge, nil, aerror.PayloadTooLarge("Request body too large"))
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// TimeoutConfig configures the timeout middleware.
+type TimeoutConfig struct {
+	// Timeout is the maximum time a handler may run. Required.
+	Timeout time.Duration
+
+	// Status is the response status when the handler times out before
+	// writing: http.StatusGatewayTimeout or http.StatusServiceUnavailable.
+	// Default: http.StatusGatewayTimeout
+	Status int
+
+	// Message is the AppError message. Default: "Request timed out"
+	Message string
+}
+
+func (c *TimeoutConfig) applyDefaults() {
+	if c.Status == 0 {
+		c.Status = http.StatusGatewayTimeout
+	}
+	if c.Message == "" {
+		c.Message = "Request timed out"
+	}
+}
+
+// Timeout returns a middleware that cancels c.Context() after d and responds
+// with a 504 AppError if the handler has not written anything by then.
+func Timeout(d time.Duration) Middleware {
+	return TimeoutWithConfig(TimeoutConfig{Timeout: d})
+}
+
+// TimeoutWithConfig returns a timeout middleware configured by cfg.
+//
+// The handler runs in its own goroutine so a handler that ignores
+// cancellation cannot hold the connection past the deadline. Once the
+// deadline passes, further writes from the handler fail with
+// http.ErrHandlerTimeout; a response that was already started is cut off.
+// Do not use it on streaming endpoints.
+func TimeoutWithConfig(cfg TimeoutConfig) Middleware {
+	cfg.applyDefaults()
+
+	var timeoutErr *aerror.AppError
+	if cfg.Status == http.StatusServiceUnavailable {
+		timeoutErr = aerror.ServiceUnavailable(cfg.Message)
+	} else {
+		timeoutErr = aerror.GatewayTimeout(cfg.Message)
+		timeoutErr.Code = cfg.Status
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := stdctx.WithTimeout(r.Context(), cfg.Timeout)
+			defer cancel()
+			r = r.WithContext(ctx)
+
+			tw := &timeoutWriter{w: w, ctx: ctx, h: w.Header().Clone()}
+			done := make(chan struct{})
+			panicked := make(chan handlerPanic, 1)
+
+			go func() {
+				defer func() {
+					if rec := recover(); rec != nil {
+						panicked <- handlerPanic{value: rec, stack: debug.Stack()}
+						return
+					}
+					close(done)
+				}()
+				next.ServeHTTP(tw, r)
+			}()
+
+			select {
+			case <-done:
+				return
+			case p := <-panicked:
+				// Re-panic with the handler goroutine's stack attached, so
+				// Recoverer logs where the panic happened rather than here.
+				// net/http must see ErrAbortHandler unchanged.
+				if err, ok := p.value.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+					panic(p.value)
+				}
+				panic(&p)
+			case <-ctx.Done():
+			}
+
+			// The handler may still be running; log a late panic instead of
+			// losing it.
+			go func() {
+				select {
+				case <-done:
+				case p := <-panicked:
+					logger.Error("router: handler panicked after timeout",
+						"panic", fmt.Sprint(p.value),
+						"method", r.Method,
+						"route", r.Pattern,
+						"stack", string(p.stack),
+					)
+				}
+			}()
+
+			tw.mu.Lock()
+			defer tw.mu.Unlock()
+			tw.timedOut = true
+			if tw.wroteHeader || !errors.Is(ctx.Err(), stdctx.DeadlineExceeded) {
+				return
+			}
+			logger.Warn("router: handler timed out",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"route", r.Pattern,
+				"timeout", cfg.Timeout,
+			)
+			c := &context.Context{W: w, R: r}
+			c.JSONResponse(cfg.Status, nil, timeoutErr)
+		})
+	}
+}
+
+// handlerPanic carries a panic out of the timeout middleware's goroutine,
+// together with that goroutine's stack. Recoverer unwraps it.
+type handlerPanic struct {
+	value any
+	stack []byte
+}
+
+// String formats the panic for recoverers other than Recoverer.
+func (p *handlerPanic) String() string {
+	return fmt.Sprintf("%v\n\ngoroutine stack:\n%s", p.value, p.stack)
+}
+
+// timeoutWriter serialises writes between the handler goroutine and the
+// timeout path. The handler works on its own header map, which is copied to
+// the real writer when the status line is written.
+type timeoutWriter struct {
+	w   http.ResponseWriter
+	ctx stdctx.Context
+	h   http.Header
+
+	mu          sync.Mutex
+	wroteHeader bool
+	timedOut    bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.h
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	tw.writeHeaderLocked(code)
+}
+
+// writeHeaderLocked commits the status and headers unless the context has
+// ended, reporting whether the response may be written. Callers must hold mu.
+func (tw *timeoutWriter) writeHeaderLocked(code int) bool {
+	if tw.timedOut || tw.ctx.Err() != nil {
+		// Leave the response to the timeout path.
+		tw.timedOut = true
+		return false
+	}
+	if tw.wroteHeader {
+		return true
+	}
+	tw.wroteHeader = true
+
+	dst := tw.w.Header()
+	for k := range dst {
+		if _, ok := tw.h[k]; !ok {
+			delete(dst, k)
+		}
+	}
+	for k, v := range tw.h {
+		dst[k] = v
+	}
+	tw.w.WriteHeader(code)
+	return true
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if !tw.writeHeaderLocked(http.StatusOK) {
+		return 0, http.ErrHandlerTimeout
+	}
+	return tw.w.Write(b)
+}
+
+// HeaderWritten reports whether the handler's response has started, so the
+// router's error handling does not write a second response.
+func (tw *timeoutWriter) HeaderWritten() bool {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	return tw.wroteHeader || tw.timedOut
+}
+
+func (tw *timeoutWriter) Flush() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if !tw.writeHeaderLocked(http.StatusOK) {
+		return
+	}
+	if f, ok := tw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
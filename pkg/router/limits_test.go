@@ -0,0 +1,272 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vietpham102301/lightway/pkg/context"
+	aerror "github.com/vietpham102301/lightway/pkg/errors"
+)
+
+func decodeAppResponse(t *testing.T, w *httptest.ResponseRecorder) context.AppResponse {
+	t.Helper()
+	var resp context.AppResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v (%s)", err, w.Body.String())
+	}
+	return resp
+}
+
+// ===========================================================================
+// BodyLimit
+// ===========================================================================
+
+func TestBodyLimit_RejectsDeclaredLength(t *testing.T) {
+	called := false
+	r := NewRouter()
+	r.Use(BodyLimit(4))
+	r.POST("/upload", func(c *context.Context) error {
+		called = true
+		return nil
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader("too long")))
+
+	if called {
+		t.Error("expected handler not to run")
+	}
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected status 413, got %d", w.Code)
+	}
+	if resp := decodeAppResponse(t, w); resp.ErrorCode != aerror.CodePayloadTooLarge {
+		t.Errorf("expected error code %s, got %q", aerror.CodePayloadTooLarge, resp.ErrorCode)
+	}
+}
+
+func TestBodyLimit_ChunkedBodyCutOff(t *testing.T) {
+	r := NewRouter()
+	r.Use(BodyLimit(8))
+	r.POST("/json", func(c *context.Context) error {
+		var v map[string]string
+		return c.BindJSON(&v)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/json", bytes.NewReader([]byte(`{"name":"longer than eight"}`)))
+	req.ContentLength = -1
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected status 413, got %d", w.Code)
+	}
+}
+
+func TestBodyLimit_AllowsSmallBody(t *testing.T) {
+	r := NewRouter()
+	r.Use(BodyLimit(64))
+	r.POST("/json", func(c *context.Context) error {
+		var v map[string]string
+		if err := c.BindJSON(&v); err != nil {
+			return err
+		}
+		c.JSONResponse(http.StatusOK, v["name"], nil)
+		return nil
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/json", strings.NewReader(`{"name":"ok"}`)))
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
+	}
+}
+
+// ===========================================================================
+// Timeout
+// ===========================================================================
+
+func TestTimeout_CancelsContextAndReturns504(t *testing.T) {
+	cancelled := make(chan struct{})
+	r := NewRouter()
+	r.Use(Timeout(20 * time.Millisecond))
+	r.GET("/slow", func(c *context.Context) error {
+		<-c.Context().Done()
+		close(cancelled)
+		return c.Context().Err()
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+
+	if w.Code != http.StatusGatewayTimeout {
+		t.Errorf("expected status 504, got %d", w.Code)
+	}
+	if resp := decodeAppResponse(t, w); resp.ErrorCode != aerror.CodeGatewayTimeout {
+		t.Errorf("expected error code %s, got %q", aerror.CodeGatewayTimeout, resp.ErrorCode)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Error("expected handler context to be cancelled")
+	}
+}
+
+func TestTimeout_ServiceUnavailableStatus(t *testing.T) {
+	r := NewRouter()
+	r.Use(TimeoutWithConfig(TimeoutConfig{Timeout: 10 * time.Millisecond, Status: http.StatusServiceUnavailable}))
+	r.GET("/slow", func(c *context.Context) error {
+		<-c.Context().Done()
+		return nil
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", w.Code)
+	}
+}
+
+func TestTimeout_DoesNotWaitForStuckHandler(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	r := NewRouter()
+	r.Use(Timeout(10 * time.Millisecond))
+	r.GET("/stuck", func(c *context.Context) error {
+		<-release // ignores cancellation
+		c.JSONResponse(http.StatusOK, "late", nil)
+		return nil
+	})
+
+	start := time.Now()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/stuck", nil))
+
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected middleware to return near the deadline, took %v", elapsed)
+	}
+	if w.Code != http.StatusGatewayTimeout {
+		t.Errorf("expected status 504, got %d", w.Code)
+	}
+}
+
+func TestTimeout_FastHandlerUnaffected(t *testing.T) {
+	r := NewRouter()
+	r.Use(Timeout(time.Second))
+	r.GET("/fast", func(c *context.Context) error {
+		c.W.Header().Set("X-Handler", "yes")
+		c.JSONResponse(http.StatusCreated, "done", nil)
+		return nil
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/fast", nil))
+
+	if w.Code != http.StatusCreated {
+		t.Errorf("expected status 201, got %d", w.Code)
+	}
+	if w.Header().Get("X-Handler") != "yes" {
+		t.Error("expected handler headers to be copied")
+	}
+	if resp := decodeAppResponse(t, w); resp.Data != "done" {
+		t.Errorf("expected data done, got %v", resp.Data)
+	}
+}
+
+func TestTimeout_StartedResponseNotOverwritten(t *testing.T) {
+	r := NewRouter()
+	r.Use(Timeout(20 * time.Millisecond))
+	r.GET("/partial", func(c *context.Context) error {
+		c.W.WriteHeader(http.StatusOK)
+		c.W.Write([]byte("partial"))
+		<-c.Context().Done()
+		_, err := c.W.Write([]byte("more"))
+		if err != http.ErrHandlerTimeout {
+			t.Errorf("expected ErrHandlerTimeout, got %v", err)
+		}
+		return nil
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/partial", nil))
+	time.Sleep(20 * time.Millisecond) // let the handler observe cancellation
+
+	if w.Code != http.StatusOK || w.Body.String() != "partial" {
+		t.Errorf("expected partial 200 response, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestTimeout_PanicPropagates(t *testing.T) {
+	r := NewRouter()
+	r.Use(Recovery(), Timeout(time.Second))
+	r.GET("/boom", func(c *context.Context) error {
+		panic("boom")
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/boom", nil))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected status 500, got %d", w.Code)
+	}
+}
+
+func panickingHandler(c *context.Context) error {
+	panic("boom")
+}
+
+func TestTimeout_PanicKeepsHandlerStack(t *testing.T) {
+	var recovered any
+	var stack []byte
+	r := NewRouter()
+	r.Use(NewRecoverer(RecoveryConfig{
+		OnPanic: func(_ *http.Request, rec any, s []byte) { recovered, stack = rec, s },
+	}).Middleware(), Timeout(time.Second))
+	r.GET("/boom", panickingHandler)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/boom", nil))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected status 500, got %d", w.Code)
+	}
+	if recovered != "boom" {
+		t.Errorf("expected the handler's panic value, got %v", recovered)
+	}
+	if !strings.Contains(string(stack), "panickingHandler") {
+		t.Errorf("expected the handler's stack, got %s", stack)
+	}
+}
+
+func TestRouter_WithPerRouteMiddleware(t *testing.T) {
+	r := NewRouter()
+	r.With(Timeout(10*time.Millisecond)).GET("/slow", func(c *context.Context) error {
+		<-c.Context().Done()
+		return nil
+	})
+	r.GET("/other", func(c *context.Context) error {
+		if _, ok := c.Context().Deadline(); ok {
+			t.Error("expected no deadline on routes registered without With")
+		}
+		return nil
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	if w.Code != http.StatusGatewayTimeout {
+		t.Errorf("expected status 504, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/other", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
+	}
+}
@@ -0,0 +1,114 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"sync/atomic"
+
+	"github.com/vietpham102301/lightway/pkg/context"
+	"github.com/vietpham102301/lightway/pkg/logger"
+	"github.com/vietpham102301/lightway/pkg/notifier"
+)
+
+// RecoveryConfig configures the panic recovery middleware.
+type RecoveryConfig struct {
+	// Notifier, if set, receives a short summary of every recovered panic.
+	// Notifications are sent asynchronously so they never delay the response.
+	Notifier notifier.Notifier
+
+	// OnPanic, if set, is called synchronously with the recovered value and
+	// stack after the panic has been logged.
+	OnPanic func(r *http.Request, recovered any, stack []byte)
+}
+
+// Recoverer recovers panics raised by downstream handlers, logs them and
+// responds with a 500 AppResponse when nothing has been written yet.
+type Recoverer struct {
+	cfg    RecoveryConfig
+	panics atomic.Int64
+}
+
+// NewRecoverer creates a Recoverer configured by cfg.
+func NewRecoverer(cfg RecoveryConfig) *Recoverer {
+	return &Recoverer{cfg: cfg}
+}
+
+// Recovery returns a panic recovery middleware with default configuration.
+// Register it first so it wraps every other middleware:
+//
+//	r.Use(router.Recovery(), cors.Default())
+func Recovery() Middleware {
+	return NewRecoverer(RecoveryConfig{}).Middleware()
+}
+
+// Panics returns the number of panics recovered so far.
+func (rc *Recoverer) Panics() int64 {
+	return rc.panics.Load()
+}
+
+// Middleware returns the recovery middleware.
+func (rc *Recoverer) Middleware() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw, ok := w.(interface{ HeaderWritten() bool })
+			if !ok {
+				tracked := &responseWriter{ResponseWriter: w}
+				w, rw = tracked, tracked
+			}
+
+			defer func() {
+				rec := recover()
+				if rec == nil {
+					return
+				}
+				// net/http uses ErrAbortHandler to abort a response on purpose.
+				if err, ok := rec.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+					panic(rec)
+				}
+				stack := debug.Stack()
+				// Panics from the Timeout middleware carry the stack of the
+				// goroutine that ran the handler.
+				if hp, ok := rec.(*handlerPanic); ok {
+					rec, stack = hp.value, hp.stack
+				}
+				rc.handlePanic(r, rec, stack)
+				if !rw.HeaderWritten() {
+					context.WriteErrorResponse(w, http.StatusInternalServerError, "internal server error", nil)
+				}
+			}()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// handlePanic records, logs and forwards a recovered panic.
+func (rc *Recoverer) handlePanic(r *http.Request, rec any, stack []byte) {
+	rc.panics.Add(1)
+	requestID := context.RequestID(r)
+
+	logger.Error("router: handler panicked",
+		"panic", rec,
+		"method", r.Method,
+		"path", r.URL.Path,
+		"route", r.Pattern,
+		"request_id", requestID,
+		"stack", string(stack),
+	)
+
+	if rc.cfg.OnPanic != nil {
+		rc.cfg.OnPanic(r, rec, stack)
+	}
+
+	if rc.cfg.Notifier != nil {
+		msg := fmt.Sprintf("🚨 panic in %s %s\nroute: %s\nrequest_id: %s\npanic: %v",
+			r.Method, r.URL.Path, r.Pattern, requestID, rec)
+		go func() {
+			if err := rc.cfg.Notifier.Send(msg); err != nil {
+				logger.Error("router: failed to send panic notification", logger.Err(err))
+			}
+		}()
+	}
+}
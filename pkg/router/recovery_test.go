@@ -0,0 +1,134 @@
+package router
+
+import (
+	stdctx "context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vietpham102301/lightway/pkg/context"
+)
+
+type fakeNotifier struct {
+	mu   sync.Mutex
+	msgs []string
+	sent chan struct{}
+}
+
+func (n *fakeNotifier) Send(message string) error {
+	n.mu.Lock()
+	n.msgs = append(n.msgs, message)
+	n.mu.Unlock()
+	n.sent <- struct{}{}
+	return nil
+}
+
+// ===========================================================================
+// Recovery
+// ===========================================================================
+
+func TestRecovery_Returns500AppResponse(t *testing.T) {
+	r := NewRouter()
+	r.Use(Recovery())
+	r.GET("/boom", func(c *context.Context) error {
+		panic("boom")
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/boom", nil))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected status %d, got %d", http.StatusInternalServerError, w.Code)
+	}
+
+	var resp context.AppResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if resp.Code != http.StatusInternalServerError || resp.Error != "internal server error" {
+		t.Errorf("unexpected response %+v", resp)
+	}
+}
+
+func TestRecovery_HeadersAlreadyWritten(t *testing.T) {
+	r := NewRouter()
+	r.Use(Recovery())
+	r.GET("/partial", func(c *context.Context) error {
+		c.W.WriteHeader(http.StatusOK)
+		c.W.Write([]byte("partial"))
+		panic("late boom")
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/partial", nil))
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected original status %d to be kept, got %d", http.StatusOK, w.Code)
+	}
+	if w.Body.String() != "partial" {
+		t.Errorf("expected no error body appended, got %q", w.Body.String())
+	}
+}
+
+func TestRecoverer_CountsPanicsAndCallsHooks(t *testing.T) {
+	n := &fakeNotifier{sent: make(chan struct{}, 1)}
+	var hookRoute, hookRequestID string
+
+	rc := NewRecoverer(RecoveryConfig{
+		Notifier: n,
+		OnPanic: func(r *http.Request, recovered any, stack []byte) {
+			hookRoute = r.Pattern
+			hookRequestID = context.RequestID(r)
+			if len(stack) == 0 {
+				t.Error("expected non-empty stack")
+			}
+		},
+	})
+
+	r := NewRouter()
+	r.Use(rc.Middleware())
+	r.GET("/users/{id}", func(c *context.Context) error {
+		panic("nil map")
+	})
+
+	req := httptest.NewRequest("GET", "/users/1", nil)
+	req = req.WithContext(stdctx.WithValue(req.Context(), context.RequestIDKey, "req-123"))
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	if rc.Panics() != 1 {
+		t.Errorf("expected 1 panic recorded, got %d", rc.Panics())
+	}
+	if hookRoute != "GET /users/{id}" {
+		t.Errorf("expected route 'GET /users/{id}', got %q", hookRoute)
+	}
+	if hookRequestID != "req-123" {
+		t.Errorf("expected request id 'req-123', got %q", hookRequestID)
+	}
+
+	select {
+	case <-n.sent:
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for panic notification")
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if len(n.msgs) != 1 {
+		t.Fatalf("expected 1 notification, got %d", len(n.msgs))
+	}
+}
+
+func TestRecovery_ErrAbortHandlerRepanics(t *testing.T) {
+	handler := Recovery()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	defer func() {
+		if rec := recover(); rec != http.ErrAbortHandler {
+			t.Errorf("expected ErrAbortHandler to propagate, got %v", rec)
+		}
+	}()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+}
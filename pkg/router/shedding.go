@@ -0,0 +1,175 @@
+package router
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/vietpham102301/lightway/pkg/context"
+	aerror "github.com/vietpham102301/lightway/pkg/errors"
+)
+
+// ShedderConfig configures the load shedding middleware.
+type ShedderConfig struct {
+	// MaxConcurrent is the maximum number of requests handled at once.
+	// Requests beyond it are rejected immediately. Required.
+	MaxConcurrent int
+
+	// TargetLatency enables adaptive limiting when set. The concurrency
+	// limit shrinks while the smoothed handler latency exceeds it and
+	// grows back, up to MaxConcurrent, while latency stays below it.
+	TargetLatency time.Duration
+
+	// MinConcurrent is the floor for the adaptive limit. Default: 1
+	MinConcurrent int
+
+	// RetryAfter is sent in the Retry-After header of rejected requests.
+	// Default: 1s
+	RetryAfter time.Duration
+}
+
+func (c *ShedderConfig) applyDefaults() {
+	if c.MaxConcurrent <= 0 {
+		c.MaxConcurrent = 1
+	}
+	if c.MinConcurrent <= 0 {
+		c.MinConcurrent = 1
+	}
+	if c.MinConcurrent > c.MaxConcurrent {
+		c.MinConcurrent = c.MaxConcurrent
+	}
+	if c.RetryAfter <= 0 {
+		c.RetryAfter = time.Second
+	}
+}
+
+const (
+	// latencySmoothing is the EWMA weight given to each new latency sample.
+	latencySmoothing = 0.1
+
+	// limitBackoff is the fraction the adaptive limit shrinks by at most
+	// once per TargetLatency while latency is too high.
+	limitBackoff = 0.1
+)
+
+// ShedderSnapshot is a point-in-time view of a Shedder.
+type ShedderSnapshot struct {
+	InFlight int           // requests currently being handled
+	Limit    int           // current concurrency limit
+	Latency  time.Duration // smoothed handler latency
+	Admitted int64         // requests let through
+	Shed     int64         // requests rejected with 503
+}
+
+// Shedder rejects requests with 503 Service Unavailable once too many are
+// in flight, so a slow dependency degrades into fast failures instead of an
+// ever-growing pile of goroutines.
+type Shedder struct {
+	cfg ShedderConfig
+	now func() time.Time
+
+	mu           sync.Mutex
+	inFlight     int
+	limit        float64
+	latency      float64 // EWMA in nanoseconds
+	lastDecrease time.Time
+
+	admitted atomic.Int64
+	shed     atomic.Int64
+}
+
+// NewShedder creates a Shedder configured by cfg.
+func NewShedder(cfg ShedderConfig) *Shedder {
+	cfg.applyDefaults()
+	return &Shedder{
+		cfg:   cfg,
+		now:   time.Now,
+		limit: float64(cfg.MaxConcurrent),
+	}
+}
+
+// LoadShedding returns a middleware that allows at most maxConcurrent
+// requests in flight and rejects the rest with 503 and Retry-After.
+func LoadShedding(maxConcurrent int) Middleware {
+	return NewShedder(ShedderConfig{MaxConcurrent: maxConcurrent}).Middleware()
+}
+
+// Stats returns the current shedder state.
+func (s *Shedder) Stats() ShedderSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return ShedderSnapshot{
+		InFlight: s.inFlight,
+		Limit:    int(s.limit),
+		Latency:  time.Duration(s.latency),
+		Admitted: s.admitted.Load(),
+		Shed:     s.shed.Load(),
+	}
+}
+
+// Middleware returns the load shedding middleware.
+func (s *Shedder) Middleware() Middleware {
+	retryAfter := strconv.Itoa(int((s.cfg.RetryAfter + time.Second - 1) / time.Second))
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !s.acquire() {
+				s.shed.Add(1)
+				w.Header().Set("Retry-After", retryAfter)
+				c := &context.Context{W: w, R: r}
+				c.JSONResponse(http.StatusServiceUnavailable, nil, aerror.ServiceUnavailable("Server is overloaded"))
+				return
+			}
+			s.admitted.Add(1)
+
+			start := s.now()
+			defer func() { s.release(s.now().Sub(start)) }()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (s *Shedder) acquire() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.inFlight >= int(s.limit) {
+		return false
+	}
+	s.inFlight++
+	return true
+}
+
+// release frees a slot and, in adaptive mode, adjusts the limit using the
+// request's latency: multiplicative decrease while latency is above target,
+// additive increase while the limit is saturated and latency is healthy.
+func (s *Shedder) release(latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	saturated := s.inFlight >= int(s.limit)
+	s.inFlight--
+
+	if s.latency == 0 {
+		s.latency = float64(latency)
+	} else {
+		s.latency += latencySmoothing * (float64(latency) - s.latency)
+	}
+
+	target := s.cfg.TargetLatency
+	if target <= 0 {
+		return
+	}
+
+	now := s.now()
+	switch {
+	case time.Duration(s.latency) > target:
+		if now.Sub(s.lastDecrease) >= target {
+			s.limit = max(float64(s.cfg.MinConcurrent), s.limit*(1-limitBackoff))
+			s.lastDecrease = now
+		}
+	case saturated:
+		s.limit = min(float64(s.cfg.MaxConcurrent), s.limit+1/s.limit)
+	}
+}
@@ -0,0 +1,105 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vietpham102301/lightway/pkg/context"
+)
+
+func TestShedder_RejectsOverLimit(t *testing.T) {
+	s := NewShedder(ShedderConfig{MaxConcurrent: 2, RetryAfter: 1500 * time.Millisecond})
+	release := make(chan struct{})
+	entered := make(chan struct{}, 2)
+
+	r := NewRouter()
+	r.Use(s.Middleware())
+	r.GET("/work", func(c *context.Context) error {
+		entered <- struct{}{}
+		<-release
+		return nil
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/work", nil))
+		}()
+	}
+	<-entered
+	<-entered
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/work", nil))
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("expected Retry-After 2, got %q", got)
+	}
+	if st := s.Stats(); st.InFlight != 2 || st.Shed != 1 {
+		t.Errorf("expected 2 in flight and 1 shed, got %+v", st)
+	}
+
+	close(release)
+	wg.Wait()
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/work", nil))
+	<-entered
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200 after slots freed, got %d", w.Code)
+	}
+	if st := s.Stats(); st.InFlight != 0 || st.Admitted != 3 {
+		t.Errorf("expected 0 in flight and 3 admitted, got %+v", st)
+	}
+}
+
+func TestShedder_AdaptiveLimit(t *testing.T) {
+	s := NewShedder(ShedderConfig{
+		MaxConcurrent: 10,
+		MinConcurrent: 2,
+		TargetLatency: 100 * time.Millisecond,
+	})
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	// Sustained slow responses shrink the limit down to the floor.
+	for i := 0; i < 100; i++ {
+		s.acquire()
+		now = now.Add(time.Second)
+		s.release(time.Second)
+	}
+	if st := s.Stats(); st.Limit != 2 {
+		t.Errorf("expected limit to shrink to MinConcurrent 2, got %d", st.Limit)
+	}
+
+	// Fast responses at saturation grow it back up to MaxConcurrent.
+	for i := 0; i < 500; i++ {
+		for s.acquire() {
+		}
+		s.release(time.Millisecond)
+		s.mu.Lock()
+		s.inFlight = 0
+		s.mu.Unlock()
+	}
+	if st := s.Stats(); st.Limit != 10 {
+		t.Errorf("expected limit to recover to MaxConcurrent 10, got %d", st.Limit)
+	}
+}
+
+func TestLoadShedding_Defaults(t *testing.T) {
+	s := NewShedder(ShedderConfig{})
+	if st := s.Stats(); st.Limit != 1 {
+		t.Errorf("expected default limit 1, got %d", st.Limit)
+	}
+	if s.cfg.RetryAfter != time.Second {
+		t.Errorf("expected default RetryAfter 1s, got %v", s.cfg.RetryAfter)
+	}
+}
@@ -0,0 +1,370 @@
+package router
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vietpham102301/lightway/pkg/context"
+	aerror "github.com/vietpham102301/lightway/pkg/errors"
+)
+
+// DefaultImmutableMaxAge is the Cache-Control max-age sent for fingerprinted
+// assets such as app.3f9a1c2b.js (one year).
+const DefaultImmutableMaxAge = 365 * 24 * time.Hour
+
+// StaticConfig configures static file serving.
+type StaticConfig struct {
+	// Index is the file served for directory requests and as the SPA
+	// fallback. Default: "index.html"
+	Index string
+
+	// Browse enables HTML directory listings for directories without an
+	// index file. Default: false (such directories return 404)
+	Browse bool
+
+	// SPA serves Index for unknown paths so client-side routes work on
+	// reload. Only page navigations fall back: paths with a file extension,
+	// paths under APIPrefixes and requests that do not accept HTML still
+	// get a 404 AppError.
+	SPA bool
+
+	// APIPrefixes are URL path prefixes never answered by the SPA fallback.
+	// Default: ["/api/"]
+	APIPrefixes []string
+
+	// MaxAge is the Cache-Control max-age for files whose name carries no
+	// content hash. Zero sends "no-cache", so clients revalidate with the
+	// ETag on every use.
+	MaxAge time.Duration
+
+	// ImmutableMaxAge is the Cache-Control max-age for fingerprinted files,
+	// which are also marked immutable. Default: 1 year
+	ImmutableMaxAge time.Duration
+
+	// Fingerprinted reports whether a file name (without directory) carries
+	// a content hash, making the file safe to cache as immutable.
+	// Default: recognises hex hashes of 8+ characters (webpack, Rollup) and
+	// 8-character mixed-case hashes (Vite, esbuild)
+	Fingerprinted func(name string) bool
+}
+
+func (c *StaticConfig) applyDefaults() {
+	if c.Index == "" {
+		c.Index = "index.html"
+	}
+	if c.APIPrefixes == nil {
+		c.APIPrefixes = []string{"/api/"}
+	}
+	if c.ImmutableMaxAge <= 0 {
+		c.ImmutableMaxAge = DefaultImmutableMaxAge
+	}
+	if c.Fingerprinted == nil {
+		c.Fingerprinted = isFingerprinted
+	}
+}
+
+// precompressed lists the encodings looked up next to each file, in order
+// of preference, with their file suffix.
+var precompressed = []struct {
+	encoding string
+	suffix   string
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// Static serves the files in fsys under prefix with default configuration.
+// Use fs.Sub to serve a subdirectory of an embed.FS:
+//
+//	//go:embed dist
+//	var dist embed.FS
+//
+//	sub, _ := fs.Sub(dist, "dist")
+//	r.Static("/assets", sub)
+func (r *Router) Static(prefix string, fsys fs.FS) {
+	r.StaticWithConfig(prefix, fsys, StaticConfig{})
+}
+
+// StaticWithConfig serves the files in fsys under prefix, configured by cfg.
+//
+// Responses carry a content-hash ETag and honour conditional and range
+// requests. When the client accepts it, a precompressed sibling (name.br or
+// name.gz) is served in place of the file. Missing files return a 404
+// AppError through the router's error handler, unless cfg.SPA applies.
+func (r *Router) StaticWithConfig(prefix string, fsys fs.FS, cfg StaticConfig) {
+	cfg.applyDefaults()
+	s := &staticServer{fsys: fsys, cfg: cfg}
+	prefix = strings.TrimSuffix(prefix, "/")
+	if r.prefix+prefix == "" {
+		// A pattern at the root would match every path, turning the 404s
+		// of other methods into 405s; only serve what no route matches.
+		r.handleNotFound("/{path...}", func(c *context.Context) error {
+			c.R.SetPathValue("path", strings.TrimPrefix(c.R.URL.Path, "/"))
+			return s.serve(c)
+		})
+		return
+	}
+	r.GET(prefix+"/{path...}", s.serve)
+}
+
+type staticServer struct {
+	fsys fs.FS
+	cfg  StaticConfig
+
+	// etags caches content hashes by file name.
+	etags sync.Map
+}
+
+type etagEntry struct {
+	modTime time.Time
+	size    int64
+	etag    string
+}
+
+func (s *staticServer) serve(c *context.Context) error {
+	name := path.Clean("/" + c.R.PathValue("path"))[1:]
+	if name == "" {
+		name = "."
+	}
+
+	f, info, err := s.open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s.notFound(c)
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if info.IsDir() {
+		if name != "." && !strings.HasSuffix(c.R.URL.Path, "/") {
+			http.Redirect(c.W, c.R, c.R.URL.Path+"/", http.StatusMovedPermanently)
+			return nil
+		}
+		index := path.Join(name, s.cfg.Index)
+		idx, idxInfo, err := s.open(index)
+		if err == nil && !idxInfo.IsDir() {
+			defer idx.Close()
+			return s.serveFile(c, index, idx, idxInfo)
+		}
+		if s.cfg.Browse {
+			return s.list(c, f)
+		}
+		return aerror.NotFound("Not found")
+	}
+
+	return s.serveFile(c, name, f, info)
+}
+
+func (s *staticServer) open(name string) (fs.File, fs.FileInfo, error) {
+	f, err := s.fsys.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return f, info, nil
+}
+
+// notFound serves the SPA index for page navigations, or a 404 AppError.
+func (s *staticServer) notFound(c *context.Context) error {
+	if !s.cfg.SPA || !s.isNavigation(c.R) {
+		return aerror.NotFound("Not found")
+	}
+	f, info, err := s.open(s.cfg.Index)
+	if err != nil {
+		return aerror.NotFound("Not found")
+	}
+	defer f.Close()
+	return s.serveFile(c, s.cfg.Index, f, info)
+}
+
+// isNavigation reports whether r looks like a browser loading a page rather
+// than a missing asset or an API call.
+func (s *staticServer) isNavigation(r *http.Request) bool {
+	for _, p := range s.cfg.APIPrefixes {
+		if strings.HasPrefix(r.URL.Path, p) || r.URL.Path == strings.TrimSuffix(p, "/") {
+			return false
+		}
+	}
+	if path.Ext(r.URL.Path) != "" {
+		return false
+	}
+	accept := r.Header.Get("Accept")
+	return accept == "" || strings.Contains(accept, "text/html") || strings.Contains(accept, "*/*")
+}
+
+func (s *staticServer) serveFile(c *context.Context, name string, f fs.File, info fs.FileInfo) error {
+	h := c.W.Header()
+	h.Add("Vary", "Accept-Encoding")
+
+	switch {
+	case path.Base(name) == s.cfg.Index:
+		h.Set("Cache-Control", "no-cache")
+	case s.cfg.Fingerprinted(path.Base(name)):
+		h.Set("Cache-Control", "public, max-age="+strconv.FormatInt(int64(s.cfg.ImmutableMaxAge/time.Second), 10)+", immutable")
+	case s.cfg.MaxAge > 0:
+		h.Set("Cache-Control", "public, max-age="+strconv.FormatInt(int64(s.cfg.MaxAge/time.Second), 10))
+	default:
+		h.Set("Cache-Control", "no-cache")
+	}
+
+	accept := c.R.Header.Get("Accept-Encoding")
+	for _, pc := range precompressed {
+		if !acceptsEncoding(accept, pc.encoding) {
+			continue
+		}
+		cf, cinfo, err := s.open(name + pc.suffix)
+		if err != nil {
+			continue
+		}
+		defer cf.Close()
+		if cinfo.IsDir() {
+			continue
+		}
+		h.Set("Content-Encoding", pc.encoding)
+		return s.serveContent(c, name, name+pc.suffix, cf, cinfo)
+	}
+
+	return s.serveContent(c, name, name, f, info)
+}
+
+// serveContent sets the ETag and writes the file with http.ServeContent,
+// which handles conditional and range requests. name determines the
+// Content-Type; file is the name actually read.
+func (s *staticServer) serveContent(c *context.Context, name, file string, f fs.File, info fs.FileInfo) error {
+	rs, ok := f.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(f)
+		if err != nil {
+			return fmt.Errorf("router: read %s: %w", file, err)
+		}
+		rs = bytes.NewReader(data)
+	}
+
+	etag, err := s.etag(file, rs, info)
+	if err != nil {
+		return err
+	}
+	c.W.Header().Set("ETag", etag)
+	http.ServeContent(c.W, c.R, path.Base(name), info.ModTime(), rs)
+	return nil
+}
+
+// etag returns the quoted content hash of a file, reading it only when the
+// cached hash is missing or the file changed. rs is rewound afterwards.
+func (s *staticServer) etag(name string, rs io.ReadSeeker, info fs.FileInfo) (string, error) {
+	if v, ok := s.etags.Load(name); ok {
+		e := v.(etagEntry)
+		if e.modTime.Equal(info.ModTime()) && e.size == info.Size() {
+			return e.etag, nil
+		}
+	}
+
+	sum := sha256.New()
+	if _, err := io.Copy(sum, rs); err != nil {
+		return "", fmt.Errorf("router: hash %s: %w", name, err)
+	}
+	if _, err := rs.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("router: seek %s: %w", name, err)
+	}
+	etag := `"` + hex.EncodeToString(sum.Sum(nil)[:16]) + `"`
+	s.etags.Store(name, etagEntry{modTime: info.ModTime(), size: info.Size(), etag: etag})
+	return etag, nil
+}
+
+func (s *staticServer) list(c *context.Context, dir fs.File) error {
+	rd, ok := dir.(fs.ReadDirFile)
+	if !ok {
+		return aerror.NotFound("Not found")
+	}
+	entries, err := rd.ReadDir(-1)
+	if err != nil {
+		return fmt.Errorf("router: read dir: %w", err)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	var b strings.Builder
+	b.WriteString("<!doctype html>\n<pre>\n")
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() {
+			name += "/"
+		}
+		link := url.URL{Path: name}
+		fmt.Fprintf(&b, "<a href=\"%s\">%s</a>\n", html.EscapeString(link.String()), html.EscapeString(name))
+	}
+	b.WriteString("</pre>\n")
+
+	c.W.Header().Set("Content-Type", "text/html; charset=utf-8")
+	c.W.Header().Set("Cache-Control", "no-cache")
+	c.W.WriteHeader(http.StatusOK)
+	_, err = io.WriteString(c.W, b.String())
+	return err
+}
+
+// isFingerprinted reports whether a file name carries a content hash, as
+// emitted by common bundlers: app.3f9a1c2b.js, index-BH3k9d2a.css. The hash
+// is the last "." or "-" separated segment and is either lowercase hex of at
+// least 8 characters, or exactly 8 letters and digits mixing in upper case.
+// Both must contain a digit, so words like "2024final" do not count.
+func isFingerprinted(name string) bool {
+	base := strings.TrimSuffix(name, path.Ext(name))
+	if i := strings.LastIndexAny(base, ".-"); i >= 0 {
+		base = base[i+1:]
+	} else {
+		return false
+	}
+	if len(base) < 8 {
+		return false
+	}
+	digit, upper, hex := false, false, true
+	for _, r := range base {
+		switch {
+		case r >= '0' && r <= '9':
+			digit = true
+		case r >= 'a' && r <= 'f':
+		case r >= 'g' && r <= 'z', r == '_':
+			hex = false
+		case r >= 'A' && r <= 'Z':
+			upper, hex = true, false
+		default:
+			return false
+		}
+	}
+	return digit && (hex || upper && len(base) == 8)
+}
+
+// acceptsEncoding reports whether an Accept-Encoding header allows enc with
+// a non-zero quality.
+func acceptsEncoding(header, enc string) bool {
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(name), enc) {
+			continue
+		}
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			v, err := strconv.ParseFloat(q, 64)
+			return err == nil && v > 0
+		}
+		return true
+	}
+	return false
+}
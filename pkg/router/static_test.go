@@ -0,0 +1,326 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/vietpham102301/lightway/pkg/context"
+	aerror "github.com/vietpham102301/lightway/pkg/errors"
+)
+
+func staticFS() fstest.MapFS {
+	return fstest.MapFS{
+		"index.html":             {Data: []byte("<html>app</html>")},
+		"app.3f9a1c2b.js":        {Data: []byte("console.log('app')")},
+		"app.3f9a1c2b.js.br":     {Data: []byte("brotli-bytes")},
+		"app.3f9a1c2b.js.gz":     {Data: []byte("gzip-bytes")},
+		"robots.txt":             {Data: []byte("User-agent: *")},
+		"docs/guide.txt":         {Data: []byte("guide")},
+		"docs/images/logo.png":   {Data: []byte("png")},
+		"nested/index.html":      {Data: []byte("nested index")},
+		"nested/other/readme.md": {Data: []byte("readme")},
+	}
+}
+
+func serveStatic(r *Router, path string, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// ===========================================================================
+// File Serving
+// ===========================================================================
+
+func TestStatic_ServesFile(t *testing.T) {
+	r := NewRouter()
+	r.Static("/assets", staticFS())
+
+	w := serveStatic(r, "/assets/robots.txt")
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if w.Body.String() != "User-agent: *" {
+		t.Errorf("expected file contents, got %q", w.Body.String())
+	}
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain") {
+		t.Errorf("expected text/plain, got %q", w.Header().Get("Content-Type"))
+	}
+	if w.Header().Get("Cache-Control") != "no-cache" {
+		t.Errorf("expected no-cache, got %q", w.Header().Get("Cache-Control"))
+	}
+	if w.Header().Get("ETag") == "" {
+		t.Error("expected ETag header")
+	}
+}
+
+func TestStatic_ConditionalRequest(t *testing.T) {
+	r := NewRouter()
+	r.Static("/assets", staticFS())
+
+	etag := serveStatic(r, "/assets/robots.txt").Header().Get("ETag")
+	w := serveStatic(r, "/assets/robots.txt", "If-None-Match", etag)
+
+	if w.Code != http.StatusNotModified {
+		t.Errorf("expected status 304, got %d", w.Code)
+	}
+}
+
+func TestStatic_FingerprintedImmutable(t *testing.T) {
+	r := NewRouter()
+	r.Static("/assets", staticFS())
+
+	w := serveStatic(r, "/assets/app.3f9a1c2b.js")
+
+	if got := w.Header().Get("Cache-Control"); got != "public, max-age=31536000, immutable" {
+		t.Errorf("expected immutable Cache-Control, got %q", got)
+	}
+}
+
+func TestStatic_FingerprintedHook(t *testing.T) {
+	r := NewRouter()
+	r.StaticWithConfig("/assets", staticFS(), StaticConfig{
+		Fingerprinted: func(name string) bool { return name == "robots.txt" },
+	})
+
+	if got := serveStatic(r, "/assets/robots.txt").Header().Get("Cache-Control"); got != "public, max-age=31536000, immutable" {
+		t.Errorf("expected immutable Cache-Control, got %q", got)
+	}
+	if got := serveStatic(r, "/assets/app.3f9a1c2b.js").Header().Get("Cache-Control"); got != "no-cache" {
+		t.Errorf("expected no-cache, got %q", got)
+	}
+}
+
+func TestStatic_Precompressed(t *testing.T) {
+	r := NewRouter()
+	r.Static("/assets", staticFS())
+
+	tests := []struct {
+		accept   string
+		encoding string
+		body     string
+	}{
+		{"gzip, br", "br", "brotli-bytes"},
+		{"gzip", "gzip", "gzip-bytes"},
+		{"br;q=0, gzip", "gzip", "gzip-bytes"},
+		{"", "", "console.log('app')"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			w := serveStatic(r, "/assets/app.3f9a1c2b.js", "Accept-Encoding", tt.accept)
+
+			if got := w.Header().Get("Content-Encoding"); got != tt.encoding {
+				t.Errorf("expected Content-Encoding %q, got %q", tt.encoding, got)
+			}
+			if w.Body.String() != tt.body {
+				t.Errorf("expected body %q, got %q", tt.body, w.Body.String())
+			}
+			if !strings.Contains(w.Header().Get("Content-Type"), "javascript") {
+				t.Errorf("expected javascript Content-Type, got %q", w.Header().Get("Content-Type"))
+			}
+			if w.Header().Get("Vary") != "Accept-Encoding" {
+				t.Errorf("expected Vary Accept-Encoding, got %q", w.Header().Get("Vary"))
+			}
+		})
+	}
+}
+
+func TestStatic_MissingFile(t *testing.T) {
+	r := NewRouter()
+	r.Static("/assets", staticFS())
+
+	w := serveStatic(r, "/assets/missing.js")
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", w.Code)
+	}
+	if resp := decodeAppResponse(t, w); resp.ErrorCode != aerror.CodeNotFound {
+		t.Errorf("expected error code %s, got %q", aerror.CodeNotFound, resp.ErrorCode)
+	}
+}
+
+// ===========================================================================
+// Directories
+// ===========================================================================
+
+func TestStatic_DirectoryIndex(t *testing.T) {
+	r := NewRouter()
+	r.Static("/", staticFS())
+
+	if w := serveStatic(r, "/nested/"); w.Body.String() != "nested index" {
+		t.Errorf("expected nested index, got %q", w.Body.String())
+	}
+	if w := serveStatic(r, "/"); w.Body.String() != "<html>app</html>" {
+		t.Errorf("expected root index, got %q", w.Body.String())
+	}
+
+	w := serveStatic(r, "/nested")
+	if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "/nested/" {
+		t.Errorf("expected redirect to /nested/, got %d %q", w.Code, w.Header().Get("Location"))
+	}
+}
+
+func TestStatic_DirectoryListing(t *testing.T) {
+	t.Run("Disabled", func(t *testing.T) {
+		r := NewRouter()
+		r.Static("/files", staticFS())
+
+		if w := serveStatic(r, "/files/docs/"); w.Code != http.StatusNotFound {
+			t.Errorf("expected status 404, got %d", w.Code)
+		}
+	})
+
+	t.Run("Enabled", func(t *testing.T) {
+		r := NewRouter()
+		r.StaticWithConfig("/files", staticFS(), StaticConfig{Browse: true})
+
+		w := serveStatic(r, "/files/docs/")
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+		body := w.Body.String()
+		if !strings.Contains(body, `<a href="guide.txt">guide.txt</a>`) ||
+			!strings.Contains(body, `<a href="images/">images/</a>`) {
+			t.Errorf("expected listing of guide.txt and images/, got %q", body)
+		}
+	})
+}
+
+// ===========================================================================
+// SPA Fallback
+// ===========================================================================
+
+func TestStatic_SPAFallback(t *testing.T) {
+	r := NewRouter()
+	api := r.Group("/api")
+	api.GET("/users", func(c *context.Context) error {
+		c.W.WriteHeader(http.StatusOK)
+		return nil
+	})
+	api.GET("/users/{id}", func(c *context.Context) error {
+		return aerror.NotFound("user not found")
+	})
+	r.StaticWithConfig("/", staticFS(), StaticConfig{SPA: true})
+
+	t.Run("Root asset", func(t *testing.T) {
+		w := serveStatic(r, "/app.3f9a1c2b.js")
+
+		if w.Code != http.StatusOK || w.Body.String() != "console.log('app')" {
+			t.Fatalf("expected the asset, got %d %q", w.Code, w.Body.String())
+		}
+		if !strings.Contains(w.Header().Get("Content-Type"), "javascript") {
+			t.Errorf("expected javascript Content-Type, got %q", w.Header().Get("Content-Type"))
+		}
+		if w.Header().Get("X-Content-Type-Options") != "" {
+			t.Error("expected no headers left over from the mux's 404")
+		}
+	})
+
+	t.Run("Route's own 404", func(t *testing.T) {
+		w := serveStatic(r, "/api/users/7", "Accept", "text/html")
+
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("expected status 404, got %d", w.Code)
+		}
+		if resp := decodeAppResponse(t, w); resp.Error != "user not found" {
+			t.Errorf("expected the route's error, got %q", resp.Error)
+		}
+	})
+
+	t.Run("Client route", func(t *testing.T) {
+		w := serveStatic(r, "/settings/profile", "Accept", "text/html,application/xhtml+xml")
+
+		if w.Code != http.StatusOK || w.Body.String() != "<html>app</html>" {
+			t.Errorf("expected index.html, got %d %q", w.Code, w.Body.String())
+		}
+		if w.Header().Get("Cache-Control") != "no-cache" {
+			t.Errorf("expected no-cache on index, got %q", w.Header().Get("Cache-Control"))
+		}
+	})
+
+	t.Run("Unknown API route", func(t *testing.T) {
+		w := serveStatic(r, "/api/unknown", "Accept", "text/html")
+
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("expected status 404, got %d", w.Code)
+		}
+		if resp := decodeAppResponse(t, w); resp.ErrorCode != aerror.CodeNotFound {
+			t.Errorf("expected error code %s, got %q", aerror.CodeNotFound, resp.ErrorCode)
+		}
+	})
+
+	t.Run("Unknown API route, other methods", func(t *testing.T) {
+		for _, method := range []string{http.MethodPost, http.MethodDelete, http.MethodOptions} {
+			req := httptest.NewRequest(method, "/api/unknown", nil)
+			req.Header.Set("Origin", "https://app.example.com")
+			req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != http.StatusNotFound {
+				t.Errorf("%s: expected status 404, got %d", method, w.Code)
+			}
+		}
+	})
+
+	t.Run("Known API route, wrong method", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/users", nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusMethodNotAllowed {
+			t.Errorf("expected status 405, got %d", w.Code)
+		}
+	})
+
+	t.Run("Known API route", func(t *testing.T) {
+		if w := serveStatic(r, "/api/users"); w.Code != http.StatusOK || w.Body.Len() != 0 {
+			t.Errorf("expected API handler, got %d %q", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("Missing asset", func(t *testing.T) {
+		if w := serveStatic(r, "/missing.js", "Accept", "*/*"); w.Code != http.StatusNotFound {
+			t.Errorf("expected status 404, got %d", w.Code)
+		}
+	})
+
+	t.Run("JSON client", func(t *testing.T) {
+		if w := serveStatic(r, "/settings", "Accept", "application/json"); w.Code != http.StatusNotFound {
+			t.Errorf("expected status 404, got %d", w.Code)
+		}
+	})
+}
+
+func TestIsFingerprinted(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"app.3f9a1c2b.js", true},
+		{"index-BH3k9d2a.css", true},
+		{"chunk.a1b2c3d4e5f6.js", true},
+		{"app.js", false},
+		{"jquery-mousewheel.js", false},
+		{"logo.png", false},
+		{"app.3f9a.js", false},
+		{"app-5HDGJNL7.js", true},
+		{"report-2024final.pdf", false},
+		{"setup-v1installer.exe", false},
+		{"release-2024Final.zip", false},
+	}
+
+	for _, tt := range tests {
+		if got := isFingerprinted(tt.name); got != tt.want {
+			t.Errorf("isFingerprinted(%q): expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,374 @@
+package router
+
+import (
+	stdctx "context"
+	"encoding"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/vietpham102301/lightway/pkg/context"
+	aerror "github.com/vietpham102301/lightway/pkg/errors"
+)
+
+// TypedFunc is a handler that receives a decoded request and returns the
+// value to send as AppResponse data.
+type TypedFunc[Req, Resp any] func(ctx stdctx.Context, req Req) (Resp, error)
+
+// Validator is implemented by request types that check themselves after
+// binding. An *AppError is returned as is; any other error becomes a 422
+// UNPROCESSABLE_ENTITY AppError with the error's message.
+type Validator interface {
+	Validate() error
+}
+
+// TypedConfig configures a typed handler.
+type TypedConfig struct {
+	// Status is the response status on success. Default: http.StatusOK
+	Status int
+}
+
+func (c *TypedConfig) applyDefaults() {
+	if c.Status == 0 {
+		c.Status = http.StatusOK
+	}
+}
+
+// Schema describes a typed route for documentation generators such as
+// OpenAPI. Request and Response are the handler's type parameters; Params
+// lists the request fields bound from the path, query string and headers.
+type Schema struct {
+	Request  reflect.Type
+	Response reflect.Type
+	Status   int
+	Params   []Param
+}
+
+// Param is a request field bound from outside the body.
+type Param struct {
+	Name  string       // parameter name in the path, query or header
+	In    string       // "path", "query" or "header"
+	Field string       // Go field name
+	Type  reflect.Type // field type
+}
+
+// Endpoint is a handler that can describe itself. Register endpoints with
+// HandleEndpoint to record their Schema in Routes.
+type Endpoint interface {
+	Serve(c *context.Context) error
+	Schema() Schema
+}
+
+// TypedHandler adapts a TypedFunc to the router. Request fields are filled
+// from the JSON body, then from tagged sources, which take precedence:
+//
+//	type GetUserRequest struct {
+//		ID     int    `path:"id"`
+//		Fields string `query:"fields"`
+//		Tenant string `header:"X-Tenant"`
+//	}
+//
+// Tagged fields may be strings, booleans, numbers, time.Duration, types
+// implementing encoding.TextUnmarshaler, pointers to those, or slices of
+// those for repeated query parameters. Unparsable values yield a 400
+// INVALID_REQUEST AppError naming the field.
+type TypedHandler[Req, Resp any] struct {
+	fn     TypedFunc[Req, Resp]
+	cfg    TypedConfig
+	fields []boundField
+}
+
+var _ Endpoint = (*TypedHandler[struct{}, struct{}])(nil)
+
+// Typed returns a handler for fn that responds with 200 OK.
+//
+//	r.HandleEndpoint("GET", "/users/{id}", router.Typed(userHandler.Get))
+//	r.GET("/users/{id}", router.Typed(userHandler.Get).Serve) // without schema
+func Typed[Req, Resp any](fn TypedFunc[Req, Resp]) *TypedHandler[Req, Resp] {
+	return TypedWithConfig(fn, TypedConfig{})
+}
+
+// TypedWithConfig returns a handler for fn configured by cfg.
+func TypedWithConfig[Req, Resp any](fn TypedFunc[Req, Resp], cfg TypedConfig) *TypedHandler[Req, Resp] {
+	cfg.applyDefaults()
+	return &TypedHandler[Req, Resp]{
+		fn:     fn,
+		cfg:    cfg,
+		fields: boundFields(reflect.TypeFor[Req]()),
+	}
+}
+
+// Serve binds the request, calls the handler and writes the AppResponse.
+// Errors are returned to the router's error handling.
+func (h *TypedHandler[Req, Resp]) Serve(c *context.Context) error {
+	var req Req
+	if err := h.bind(c, &req); err != nil {
+		return err
+	}
+
+	resp, err := h.fn(c.Context(), req)
+	if err != nil {
+		return err
+	}
+
+	if h.cfg.Status == http.StatusNoContent {
+		c.W.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	c.JSONResponse(h.cfg.Status, resp, nil)
+	return nil
+}
+
+// Schema implements Endpoint.
+func (h *TypedHandler[Req, Resp]) Schema() Schema {
+	params := make([]Param, len(h.fields))
+	for i, f := range h.fields {
+		params[i] = Param{Name: f.name, In: f.in, Field: f.field, Type: f.typ}
+	}
+	return Schema{
+		Request:  reflect.TypeFor[Req](),
+		Response: reflect.TypeFor[Resp](),
+		Status:   h.cfg.Status,
+		Params:   params,
+	}
+}
+
+func (h *TypedHandler[Req, Resp]) bind(c *context.Context, req *Req) error {
+	v := reflect.ValueOf(req).Elem()
+	if v.Kind() == reflect.Pointer {
+		v.Set(reflect.New(v.Type().Elem()))
+	}
+
+	if hasBody(c.R) {
+		if err := c.BindJSON(v.Addr().Interface()); err != nil && !errors.Is(err, io.EOF) {
+			if _, ok := err.(*aerror.AppError); ok {
+				return err
+			}
+			return aerror.InvalidRequest(err)
+		}
+	}
+
+	if len(h.fields) > 0 {
+		target := reflect.Indirect(v)
+		for _, f := range h.fields {
+			if err := f.bind(c.R, target); err != nil {
+				return err
+			}
+		}
+	}
+
+	if val, ok := v.Addr().Interface().(Validator); ok {
+		return validationError(val.Validate())
+	}
+	if val, ok := v.Interface().(Validator); ok {
+		return validationError(val.Validate())
+	}
+	return nil
+}
+
+func validationError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if len(aerror.Collect(err)) > 0 {
+		return err
+	}
+	return aerror.UnprocessableEntity(err.Error())
+}
+
+// hasBody reports whether r may carry a request body worth decoding.
+func hasBody(r *http.Request) bool {
+	if r.Body == nil || r.Body == http.NoBody || r.ContentLength == 0 {
+		return false
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		return r.ContentLength > 0
+	}
+	return true
+}
+
+// boundField is a request struct field filled from the path, query or headers.
+type boundField struct {
+	index []int
+	field string
+	name  string
+	in    string
+	typ   reflect.Type
+}
+
+var bindTags = []string{"path", "query", "header"}
+
+// boundFields lists the tagged fields of t, following embedded structs.
+func boundFields(t reflect.Type) []boundField {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var fields []boundField
+	for _, sf := range reflect.VisibleFields(t) {
+		if !sf.IsExported() || throughPointer(t, sf.Index) {
+			continue
+		}
+		for _, in := range bindTags {
+			if name, ok := sf.Tag.Lookup(in); ok && name != "" && name != "-" {
+				if !bindable(sf.Type) {
+					panic(fmt.Sprintf("router: cannot bind %s field %s.%s of type %s", in, t.Name(), sf.Name, sf.Type))
+				}
+				fields = append(fields, boundField{
+					index: sf.Index,
+					field: sf.Name,
+					name:  name,
+					in:    in,
+					typ:   sf.Type,
+				})
+				break
+			}
+		}
+	}
+	return fields
+}
+
+// throughPointer reports whether reaching index from t dereferences an
+// embedded pointer, which binding leaves alone.
+func throughPointer(t reflect.Type, index []int) bool {
+	for _, i := range index[:len(index)-1] {
+		f := t.Field(i)
+		if f.Type.Kind() == reflect.Pointer {
+			return true
+		}
+		t = f.Type
+	}
+	return false
+}
+
+// bindable reports whether setValue can parse into a field of type t.
+func bindable(t reflect.Type) bool {
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return bindable(t.Elem())
+	case reflect.Slice:
+		return t.Elem().Kind() != reflect.Slice && bindable(t.Elem())
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+func (f *boundField) bind(r *http.Request, v reflect.Value) error {
+	var values []string
+	switch f.in {
+	case "path":
+		if s := r.PathValue(f.name); s != "" {
+			values = []string{s}
+		}
+	case "query":
+		values = r.URL.Query()[f.name]
+	case "header":
+		values = r.Header.Values(f.name)
+	}
+	if len(values) == 0 {
+		return nil
+	}
+
+	dst := v.FieldByIndex(f.index)
+	if err := setValue(dst, values); err != nil {
+		return aerror.InvalidRequest(err).WithFields(aerror.FieldError{
+			Field:   f.name,
+			Message: err.Error(),
+			Code:    "INVALID",
+		})
+	}
+	return nil
+}
+
+var (
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+	durationType        = reflect.TypeFor[time.Duration]()
+)
+
+// setValue parses values into dst. Only slices use more than the first value.
+func setValue(dst reflect.Value, values []string) error {
+	if dst.Kind() == reflect.Slice && !reflect.PointerTo(dst.Type()).Implements(textUnmarshalerType) {
+		out := reflect.MakeSlice(dst.Type(), len(values), len(values))
+		for i, s := range values {
+			if err := setScalar(out.Index(i), s); err != nil {
+				return err
+			}
+		}
+		dst.Set(out)
+		return nil
+	}
+	return setScalar(dst, values[0])
+}
+
+func setScalar(dst reflect.Value, s string) error {
+	if dst.Kind() == reflect.Pointer {
+		elem := reflect.New(dst.Type().Elem())
+		if err := setScalar(elem.Elem(), s); err != nil {
+			return err
+		}
+		dst.Set(elem)
+		return nil
+	}
+	if dst.CanAddr() && dst.Addr().Type().Implements(textUnmarshalerType) {
+		if err := dst.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s)); err != nil {
+			return fmt.Errorf("invalid value %q", s)
+		}
+		return nil
+	}
+
+	if dst.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return errors.New("must be a duration")
+		}
+		dst.SetInt(int64(d))
+		return nil
+	}
+
+	switch dst.Kind() {
+	case reflect.String:
+		dst.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return errors.New("must be a boolean")
+		}
+		dst.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, dst.Type().Bits())
+		if err != nil {
+			return errors.New("must be an integer")
+		}
+		dst.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, dst.Type().Bits())
+		if err != nil {
+			return errors.New("must be a non-negative integer")
+		}
+		dst.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, dst.Type().Bits())
+		if err != nil {
+			return errors.New("must be a number")
+		}
+		dst.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported type %s", dst.Type())
+	}
+	return nil
+}
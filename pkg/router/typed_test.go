@@ -0,0 +1,294 @@
+package router
+
+import (
+	stdctx "context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vietpham102301/lightway/pkg/context"
+	aerror "github.com/vietpham102301/lightway/pkg/errors"
+)
+
+type updateUserRequest struct {
+	ID      int           `path:"id"`
+	Notify  bool          `query:"notify"`
+	Tags    []string      `query:"tag"`
+	Timeout time.Duration `query:"timeout"`
+	Tenant  *string       `header:"X-Tenant"`
+	Name    string        `json:"name"`
+	Email   string        `json:"email"`
+}
+
+func (r updateUserRequest) Validate() error {
+	if r.Name == "" {
+		return aerror.UnprocessableEntity("Validation failed", aerror.FieldError{Field: "name", Message: "required"})
+	}
+	return nil
+}
+
+type userResponse struct {
+	ID     int      `json:"id"`
+	Name   string   `json:"name"`
+	Tenant string   `json:"tenant"`
+	Tags   []string `json:"tags"`
+}
+
+func updateUser(_ stdctx.Context, req updateUserRequest) (userResponse, error) {
+	if req.ID == 404 {
+		return userResponse{}, aerror.NotFound("User not found")
+	}
+	resp := userResponse{ID: req.ID, Name: req.Name, Tags: req.Tags}
+	if req.Tenant != nil {
+		resp.Tenant = *req.Tenant
+	}
+	return resp, nil
+}
+
+// ===========================================================================
+// Binding
+// ===========================================================================
+
+func TestTyped_BindsPathQueryHeaderAndBody(t *testing.T) {
+	var got updateUserRequest
+	r := NewRouter()
+	r.HandleEndpoint("PUT", "/users/{id}", Typed(func(ctx stdctx.Context, req updateUserRequest) (userResponse, error) {
+		got = req
+		return updateUser(ctx, req)
+	}))
+
+	req := httptest.NewRequest("PUT", "/users/7?notify=true&tag=a&tag=b&timeout=2s",
+		strings.NewReader(`{"name":"Ann","email":"ann@example.com"}`))
+	req.Header.Set("X-Tenant", "acme")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if got.ID != 7 || !got.Notify || got.Timeout != 2*time.Second || got.Name != "Ann" || got.Email != "ann@example.com" {
+		t.Errorf("unexpected binding: %+v", got)
+	}
+	if len(got.Tags) != 2 || got.Tags[0] != "a" || got.Tags[1] != "b" {
+		t.Errorf("expected tags [a b], got %v", got.Tags)
+	}
+	if got.Tenant == nil || *got.Tenant != "acme" {
+		t.Errorf("expected tenant acme, got %v", got.Tenant)
+	}
+
+	resp := decodeAppResponse(t, w)
+	data, _ := resp.Data.(map[string]any)
+	if data["name"] != "Ann" || data["tenant"] != "acme" {
+		t.Errorf("expected response data with name and tenant, got %v", resp.Data)
+	}
+}
+
+func TestTyped_PathOverridesBody(t *testing.T) {
+	var got updateUserRequest
+	r := NewRouter()
+	r.PUT("/users/{id}", Typed(func(_ stdctx.Context, req updateUserRequest) (userResponse, error) {
+		got = req
+		return userResponse{}, nil
+	}).Serve)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("PUT", "/users/3", strings.NewReader(`{"name":"x"}`)))
+
+	if got.ID != 3 {
+		t.Errorf("expected ID 3 from path, got %d", got.ID)
+	}
+}
+
+func TestTyped_InvalidParam(t *testing.T) {
+	r := NewRouter()
+	r.HandleEndpoint("PUT", "/users/{id}", Typed(updateUser))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("PUT", "/users/abc", strings.NewReader(`{"name":"x"}`)))
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", w.Code)
+	}
+	resp := decodeAppResponse(t, w)
+	if resp.ErrorCode != aerror.CodeInvalidRequest {
+		t.Errorf("expected error code %s, got %q", aerror.CodeInvalidRequest, resp.ErrorCode)
+	}
+	if len(resp.Fields) != 1 || resp.Fields[0].Field != "id" {
+		t.Errorf("expected field error for id, got %v", resp.Fields)
+	}
+}
+
+func TestTyped_InvalidBody(t *testing.T) {
+	r := NewRouter()
+	r.HandleEndpoint("PUT", "/users/{id}", Typed(updateUser))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("PUT", "/users/1", strings.NewReader(`{"name":`)))
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
+}
+
+func TestTyped_BodyTooLarge(t *testing.T) {
+	r := NewRouter()
+	r.Use(BodyLimit(8))
+	r.HandleEndpoint("POST", "/users/{id}", Typed(updateUser))
+
+	req := httptest.NewRequest("POST", "/users/1", strings.NewReader(`{"name":"far too long"}`))
+	req.ContentLength = -1
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected status 413, got %d", w.Code)
+	}
+}
+
+func TestTyped_Validation(t *testing.T) {
+	r := NewRouter()
+	r.HandleEndpoint("PUT", "/users/{id}", Typed(updateUser))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("PUT", "/users/1", strings.NewReader(`{}`)))
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status 422, got %d", w.Code)
+	}
+	if resp := decodeAppResponse(t, w); len(resp.Fields) != 1 || resp.Fields[0].Field != "name" {
+		t.Errorf("expected field error for name, got %v", resp.Fields)
+	}
+}
+
+type pageRequest struct {
+	Page int `query:"page"`
+}
+
+func (r *pageRequest) Validate() error {
+	if r.Page < 0 {
+		return errors.New("page must not be negative")
+	}
+	return nil
+}
+
+func TestTyped_PlainValidationError(t *testing.T) {
+	r := NewRouter()
+	r.HandleEndpoint("GET", "/items", Typed(func(_ stdctx.Context, req *pageRequest) ([]string, error) {
+		return []string{}, nil
+	}))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/items?page=-1", nil))
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status 422, got %d", w.Code)
+	}
+	if resp := decodeAppResponse(t, w); resp.Error != "page must not be negative" {
+		t.Errorf("expected validation message, got %q", resp.Error)
+	}
+}
+
+func TestTyped_UnsupportedFieldPanics(t *testing.T) {
+	type badRequest struct {
+		Filter map[string]string `query:"filter"`
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected Typed to panic on an unbindable field")
+		}
+	}()
+	Typed(func(_ stdctx.Context, req badRequest) (struct{}, error) { return struct{}{}, nil })
+}
+
+// ===========================================================================
+// Responses
+// ===========================================================================
+
+func TestTyped_ErrorFlowsThroughRouter(t *testing.T) {
+	r := NewRouter()
+	r.HandleEndpoint("PUT", "/users/{id}", Typed(updateUser))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("PUT", "/users/404", strings.NewReader(`{"name":"x"}`)))
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", w.Code)
+	}
+	if resp := decodeAppResponse(t, w); resp.ErrorCode != aerror.CodeNotFound {
+		t.Errorf("expected error code %s, got %q", aerror.CodeNotFound, resp.ErrorCode)
+	}
+}
+
+func TestTyped_CustomStatus(t *testing.T) {
+	r := NewRouter()
+	r.HandleEndpoint("POST", "/users/{id}", TypedWithConfig(updateUser, TypedConfig{Status: http.StatusCreated}))
+	r.HandleEndpoint("DELETE", "/users/{id}", TypedWithConfig(
+		func(_ stdctx.Context, _ struct{}) (struct{}, error) { return struct{}{}, nil },
+		TypedConfig{Status: http.StatusNoContent},
+	))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/users/1", strings.NewReader(`{"name":"x"}`)))
+	if w.Code != http.StatusCreated {
+		t.Errorf("expected status 201, got %d", w.Code)
+	}
+	if resp := decodeAppResponse(t, w); resp.Code != http.StatusCreated {
+		t.Errorf("expected AppResponse code 201, got %d", resp.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("DELETE", "/users/1", nil))
+	if w.Code != http.StatusNoContent || w.Body.Len() != 0 {
+		t.Errorf("expected empty 204, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+// ===========================================================================
+// Schema
+// ===========================================================================
+
+func TestTyped_SchemaInRoutes(t *testing.T) {
+	r := NewRouter()
+	r.GET("/health", func(c *context.Context) error { return nil })
+	r.HandleEndpoint("PUT", "/users/{id}", TypedWithConfig(updateUser, TypedConfig{Status: http.StatusAccepted}))
+
+	routes := r.Routes()
+	if len(routes) != 2 {
+		t.Fatalf("expected 2 routes, got %d", len(routes))
+	}
+	if routes[0].Schema != nil {
+		t.Error("expected no schema for plain handler")
+	}
+
+	s := routes[1].Schema
+	if s == nil {
+		t.Fatal("expected schema for typed handler")
+	}
+	if s.Request != reflect.TypeFor[updateUserRequest]() || s.Response != reflect.TypeFor[userResponse]() {
+		t.Errorf("unexpected schema types: %v, %v", s.Request, s.Response)
+	}
+	if s.Status != http.StatusAccepted {
+		t.Errorf("expected status 202, got %d", s.Status)
+	}
+
+	want := []Param{
+		{Name: "id", In: "path", Field: "ID"},
+		{Name: "notify", In: "query", Field: "Notify"},
+		{Name: "tag", In: "query", Field: "Tags"},
+		{Name: "timeout", In: "query", Field: "Timeout"},
+		{Name: "X-Tenant", In: "header", Field: "Tenant"},
+	}
+	if len(s.Params) != len(want) {
+		t.Fatalf("expected %d params, got %d", len(want), len(s.Params))
+	}
+	for i, p := range want {
+		if s.Params[i].Name != p.Name || s.Params[i].In != p.In || s.Params[i].Field != p.Field {
+			t.Errorf("param[%d]: expected %+v, got %+v", i, p, s.Params[i])
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package router
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vietpham102301/lightway/pkg/context"
+	aerror "github.com/vietpham102301/lightway/pkg/errors"
+)
+
+// CodeUnsupportedVersion is the AppError code returned when a request names
+// an API version that is not registered.
+const CodeUnsupportedVersion = "UNSUPPORTED_VERSION"
+
+// VersionConfig configures how a VersionSet resolves the requested version.
+// A version segment in the path (/api/v2/users) always wins; otherwise the
+// header, then the Accept parameter, then Default are consulted.
+type VersionConfig struct {
+	// Header is the request header naming the version. The resolved version
+	// is echoed in the same response header. Default: "X-API-Version"
+	Header string
+
+	// MediaTypeParam is the Accept media type parameter naming the version,
+	// as in "Accept: application/json; version=2". Default: "version"
+	MediaTypeParam string
+
+	// Default is the version served when the request names none.
+	// Default: the first version registered
+	Default string
+}
+
+func (c *VersionConfig) applyDefaults() {
+	if c.Header == "" {
+		c.Header = "X-API-Version"
+	}
+	if c.MediaTypeParam == "" {
+		c.MediaTypeParam = "version"
+	}
+}
+
+// VersionOptions configures a single API version.
+type VersionOptions struct {
+	// Deprecated adds a Deprecation header to every response of the version.
+	Deprecated bool
+
+	// DeprecatedAt is sent as the Deprecation date (RFC 9745). Without it a
+	// deprecated version sends "Deprecation: true".
+	DeprecatedAt time.Time
+
+	// Sunset, if set, is sent in the Sunset header (RFC 8594).
+	Sunset time.Time
+
+	// Link, if set, is sent as a Link header with rel="deprecation",
+	// pointing clients to migration notes.
+	Link string
+
+	// Fallback serves routes this version does not define with the handlers
+	// of the previously registered version, and so on down the chain.
+	Fallback bool
+}
+
+// VersionSet serves several versions of an API side by side under one
+// prefix. Create it with Router.Versions and add versions in ascending order
+// with Version.
+type VersionSet struct {
+	cfg      VersionConfig
+	prefix   string
+	parent   *Router
+	versions []*apiVersion
+	byName   map[string]*apiVersion
+}
+
+type apiVersion struct {
+	name     string
+	opts     VersionOptions
+	router   *Router
+	previous *apiVersion
+	headers  http.Header
+}
+
+// Versions creates a VersionSet serving the router's prefix. Each version's
+// routes are registered on the router returned by Version and are reachable
+// both as /prefix/<version>/path and as /prefix/path with the version picked
+// by header or Accept parameter. The router's middleware runs for every
+// versioned request.
+//
+//	api := r.Group("/api")
+//	versions := api.Versions(router.VersionConfig{Default: "v1"})
+//	v1 := versions.Version("v1", router.VersionOptions{Deprecated: true, Sunset: sunset})
+//	v2 := versions.Version("v2", router.VersionOptions{Fallback: true})
+func (r *Router) Versions(cfg VersionConfig) *VersionSet {
+	cfg.applyDefaults()
+	vs := &VersionSet{
+		cfg:    cfg,
+		prefix: r.prefix,
+		parent: r,
+		byName: make(map[string]*apiVersion),
+	}
+	r.mux.Handle(r.host+r.prefix+"/", r.wrap(vs))
+	return vs
+}
+
+// Version registers a version named name, e.g. "v2", and returns the router
+// for its routes. Paths are relative to the VersionSet's prefix.
+func (vs *VersionSet) Version(name string, opts VersionOptions) *Router {
+	v := &apiVersion{
+		name: name,
+		opts: opts,
+		router: &Router{
+			mux:          http.NewServeMux(),
+			prefix:       vs.prefix + "/" + name,
+			routes:       vs.parent.routes,
+			table:        newRouteTable(),
+			errorHandler: vs.parent.errorHandler,
+			errorMappers: append([]ErrorMapper(nil), vs.parent.errorMappers...),
+		},
+		headers: deprecationHeaders(opts),
+	}
+	if n := len(vs.versions); n > 0 {
+		v.previous = vs.versions[n-1]
+	}
+	vs.versions = append(vs.versions, v)
+	vs.byName[name] = v
+	return v.router
+}
+
+func deprecationHeaders(opts VersionOptions) http.Header {
+	h := make(http.Header)
+	if opts.Deprecated || !opts.DeprecatedAt.IsZero() {
+		if opts.DeprecatedAt.IsZero() {
+			h.Set("Deprecation", "true")
+		} else {
+			h.Set("Deprecation", "@"+strconv.FormatInt(opts.DeprecatedAt.Unix(), 10))
+		}
+	}
+	if !opts.Sunset.IsZero() {
+		h.Set("Sunset", opts.Sunset.UTC().Format(http.TimeFormat))
+	}
+	if opts.Link != "" {
+		h.Set("Link", "<"+opts.Link+">; rel=\"deprecation\"")
+	}
+	return h
+}
+
+func (vs *VersionSet) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimPrefix(r.URL.Path, vs.prefix)
+
+	seg, tail := rest, ""
+	if i := strings.IndexByte(rest[1:], '/'); i >= 0 {
+		seg, tail = rest[:i+1], rest[i+1:]
+	}
+	v, ok := vs.byName[strings.TrimPrefix(seg, "/")]
+	if ok {
+		rest = tail
+	} else {
+		name := vs.requested(r)
+		if v = vs.lookup(name); v == nil {
+			c := &context.Context{W: w, R: r}
+			c.JSONResponse(http.StatusBadRequest, nil,
+				aerror.NewAppError(http.StatusBadRequest, "Unsupported API version", nil).
+					WithCode(CodeUnsupportedVersion).
+					WithDetail("version", name))
+			return
+		}
+	}
+
+	h := w.Header()
+	h.Add("Vary", vs.cfg.Header)
+	h.Add("Vary", "Accept")
+	h.Set(vs.cfg.Header, v.name)
+	for k, vals := range v.headers {
+		h[k] = append([]string(nil), vals...)
+	}
+
+	for cand := v; cand != nil; cand = cand.previous {
+		req := vs.rewrite(r, cand, rest)
+		if h, ok := cand.router.match(req); ok {
+			h.ServeHTTP(w, req)
+			return
+		}
+		if !cand.opts.Fallback {
+			break
+		}
+	}
+	// No version defines the route: let the requested version's mux answer
+	// with its 404, 405 or redirect.
+	v.router.mux.ServeHTTP(w, vs.rewrite(r, v, rest))
+}
+
+// requested returns the version named by the header, Accept parameter or
+// default, in that order.
+func (vs *VersionSet) requested(r *http.Request) string {
+	if name := r.Header.Get(vs.cfg.Header); name != "" {
+		return name
+	}
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		_, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err == nil && params[vs.cfg.MediaTypeParam] != "" {
+			return params[vs.cfg.MediaTypeParam]
+		}
+	}
+	if vs.cfg.Default != "" {
+		return vs.cfg.Default
+	}
+	if len(vs.versions) > 0 {
+		return vs.versions[0].name
+	}
+	return ""
+}
+
+// lookup finds a version by name, accepting "2" for "v2".
+func (vs *VersionSet) lookup(name string) *apiVersion {
+	if v, ok := vs.byName[name]; ok {
+		return v
+	}
+	return vs.byName["v"+name]
+}
+
+// rewrite returns a shallow copy of r addressed to rest within version v.
+func (vs *VersionSet) rewrite(r *http.Request, v *apiVersion, rest string) *http.Request {
+	req := new(http.Request)
+	*req = *r
+	u := *r.URL
+	u.Path = v.router.prefix + rest
+	u.RawPath = ""
+	req.URL = &u
+	return req
+}
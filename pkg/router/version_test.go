@@ -0,0 +1,194 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/vietpham102301/lightway/pkg/context"
+)
+
+func textHandler(body string) HandlerFunc {
+	return func(c *context.Context) error {
+		c.W.Write([]byte(body))
+		return nil
+	}
+}
+
+func newVersionedRouter() *Router {
+	r := NewRouter()
+	api := r.Group("/api")
+	api.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("X-Group", "api")
+			next.ServeHTTP(w, req)
+		})
+	})
+	versions := api.Versions(VersionConfig{})
+
+	v1 := versions.Version("v1", VersionOptions{
+		Deprecated: true,
+		Sunset:     time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC),
+		Link:       "https://example.com/migrate",
+	})
+	v1.GET("/users/{id}", func(c *context.Context) error {
+		c.W.Write([]byte("v1 user " + c.Param("id")))
+		return nil
+	})
+	v1.GET("/orders", textHandler("v1 orders"))
+
+	v2 := versions.Version("v2", VersionOptions{Fallback: true})
+	v2.GET("/users/{id}", textHandler("v2 user"))
+
+	v3 := versions.Version("v3", VersionOptions{})
+	v3.GET("/users/{id}", textHandler("v3 user"))
+	return r
+}
+
+func serveVersion(r *Router, path string, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// ===========================================================================
+// Version Resolution
+// ===========================================================================
+
+func TestVersions_Resolution(t *testing.T) {
+	r := newVersionedRouter()
+
+	tests := []struct {
+		name   string
+		path   string
+		header []string
+		body   string
+	}{
+		{"path", "/api/v2/users/1", nil, "v2 user"},
+		{"path wins over header", "/api/v3/users/1", []string{"X-API-Version", "v1"}, "v3 user"},
+		{"header", "/api/users/1", []string{"X-API-Version", "v2"}, "v2 user"},
+		{"header without prefix", "/api/users/1", []string{"X-API-Version", "3"}, "v3 user"},
+		{"accept parameter", "/api/users/1", []string{"Accept", "application/json; version=2"}, "v2 user"},
+		{"default is first version", "/api/users/9", nil, "v1 user 9"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serveVersion(r, tt.path, tt.header...)
+
+			if w.Body.String() != tt.body {
+				t.Errorf("expected %q, got %d %q", tt.body, w.Code, w.Body.String())
+			}
+			if w.Header().Get("X-Group") != "api" {
+				t.Error("expected group middleware to run")
+			}
+		})
+	}
+}
+
+func TestVersions_ResponseHeaders(t *testing.T) {
+	r := newVersionedRouter()
+
+	w := serveVersion(r, "/api/users/1", "X-API-Version", "2")
+	if got := w.Header().Get("X-API-Version"); got != "v2" {
+		t.Errorf("expected resolved version v2, got %q", got)
+	}
+	if vary := w.Header().Values("Vary"); len(vary) != 2 || vary[0] != "X-API-Version" || vary[1] != "Accept" {
+		t.Errorf("expected Vary X-API-Version, Accept, got %v", vary)
+	}
+	if w.Header().Get("Deprecation") != "" {
+		t.Error("expected no Deprecation header on v2")
+	}
+}
+
+func TestVersions_UnsupportedVersion(t *testing.T) {
+	r := newVersionedRouter()
+
+	w := serveVersion(r, "/api/users/1", "X-API-Version", "v9")
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", w.Code)
+	}
+	if resp := decodeAppResponse(t, w); resp.ErrorCode != CodeUnsupportedVersion {
+		t.Errorf("expected error code %s, got %q", CodeUnsupportedVersion, resp.ErrorCode)
+	}
+}
+
+// ===========================================================================
+// Deprecation and Fallback
+// ===========================================================================
+
+func TestVersions_DeprecationHeaders(t *testing.T) {
+	r := newVersionedRouter()
+
+	w := serveVersion(r, "/api/v1/orders")
+
+	if got := w.Header().Get("Deprecation"); got != "true" {
+		t.Errorf("expected Deprecation true, got %q", got)
+	}
+	if got := w.Header().Get("Sunset"); got != "Fri, 01 Jan 2027 00:00:00 GMT" {
+		t.Errorf("expected Sunset date, got %q", got)
+	}
+	if got := w.Header().Get("Link"); got != `<https://example.com/migrate>; rel="deprecation"` {
+		t.Errorf("expected deprecation Link, got %q", got)
+	}
+}
+
+func TestVersions_DeprecatedAt(t *testing.T) {
+	h := deprecationHeaders(VersionOptions{DeprecatedAt: time.Unix(1700000000, 0)})
+
+	if got := h.Get("Deprecation"); got != "@1700000000" {
+		t.Errorf("expected @1700000000, got %q", got)
+	}
+}
+
+func TestVersions_Fallback(t *testing.T) {
+	r := newVersionedRouter()
+
+	w := serveVersion(r, "/api/v2/orders")
+	if w.Body.String() != "v1 orders" {
+		t.Errorf("expected v2 to fall back to v1, got %d %q", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Deprecation") != "" {
+		t.Error("expected headers of the requested version, not the fallback")
+	}
+
+	if w := serveVersion(r, "/api/v3/orders"); w.Code != http.StatusNotFound {
+		t.Errorf("expected v3 without fallback to return 404, got %d", w.Code)
+	}
+}
+
+func TestVersions_MethodNotAllowed(t *testing.T) {
+	r := newVersionedRouter()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v2/users/1", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status 405, got %d", w.Code)
+	}
+}
+
+func TestVersions_RoutesListed(t *testing.T) {
+	r := newVersionedRouter()
+
+	var paths []string
+	for _, route := range r.Routes() {
+		paths = append(paths, route.Path)
+	}
+	want := []string{"/api/v1/users/{id}", "/api/v1/orders", "/api/v2/users/{id}", "/api/v3/users/{id}"}
+	if len(paths) != len(want) {
+		t.Fatalf("expected %v, got %v", want, paths)
+	}
+	for i := range want {
+		if paths[i] != want[i] {
+			t.Errorf("route[%d]: expected %s, got %s", i, want[i], paths[i])
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package scheduler
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func mustParse(t *testing.T, expr string, loc *time.Location) Schedule {
+	t.Helper()
+	s, err := ParseCron(expr, loc)
+	if err != nil {
+		t.Fatalf("ParseCron(%q): unexpected error: %v", expr, err)
+	}
+	return s
+}
+
+func date(y int, m time.Month, d, h, min int) time.Time {
+	return time.Date(y, m, d, h, min, 0, 0, time.UTC)
+}
+
+// ===========================================================================
+// ParseCron — next activation
+// ===========================================================================
+
+func TestCron_Next(t *testing.T) {
+	// 2026-01-01 is a Thursday.
+	from := date(2026, 1, 1, 10, 7)
+	cases := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", date(2026, 1, 1, 10, 8)},
+		{"*/15 * * * *", date(2026, 1, 1, 10, 15)},
+		{"0 * * * *", date(2026, 1, 1, 11, 0)},
+		{"30 9 * * *", date(2026, 1, 2, 9, 30)},
+		{"0 9 * * MON-FRI", date(2026, 1, 2, 9, 0)},
+		{"0 9 * * 1", date(2026, 1, 5, 9, 0)},
+		{"0 0 * * 7", date(2026, 1, 4, 0, 0)},
+		{"0 0 1 * *", date(2026, 2, 1, 0, 0)},
+		{"0 0 29 2 *", date(2028, 2, 29, 0, 0)},
+		{"5/20 10 * * *", date(2026, 1, 1, 10, 25)},
+		{"0 12 1,15 * *", date(2026, 1, 1, 12, 0)},
+		{"0 8 1,15 * *", date(2026, 1, 15, 8, 0)},
+		{"0 0 1 JAN *", date(2027, 1, 1, 0, 0)},
+		{"@hourly", date(2026, 1, 1, 11, 0)},
+		{"@daily", date(2026, 1, 2, 0, 0)},
+		{"@weekly", date(2026, 1, 4, 0, 0)},
+		{"@monthly", date(2026, 2, 1, 0, 0)},
+		{"@yearly", date(2027, 1, 1, 0, 0)},
+	}
+	for _, tc := range cases {
+		got := mustParse(t, tc.expr, time.UTC).Next(from)
+		if !got.Equal(tc.want) {
+			t.Errorf("%q: expected %v, got %v", tc.expr, tc.want, got)
+		}
+	}
+}
+
+func TestCron_DayOfMonthOrDayOfWeek(t *testing.T) {
+	// Either the 10th or any Monday.
+	s := mustParse(t, "0 0 10 * MON", time.UTC)
+	got := s.Next(date(2026, 1, 1, 0, 0))
+	if want := date(2026, 1, 5, 0, 0); !got.Equal(want) {
+		t.Fatalf("expected first Monday %v, got %v", want, got)
+	}
+	got = s.Next(date(2026, 1, 6, 0, 0))
+	if want := date(2026, 1, 10, 0, 0); !got.Equal(want) {
+		t.Fatalf("expected the 10th %v, got %v", want, got)
+	}
+}
+
+func TestCron_TimeZone(t *testing.T) {
+	hcm, err := time.LoadLocation("Asia/Ho_Chi_Minh")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+
+	// 09:00 in Ho Chi Minh City (UTC+7) is 02:00 UTC.
+	for _, s := range []Schedule{
+		mustParse(t, "0 9 * * *", hcm),
+		mustParse(t, "CRON_TZ=Asia/Ho_Chi_Minh 0 9 * * *", time.UTC),
+		mustParse(t, "TZ=Asia/Ho_Chi_Minh 0 9 * * *", nil),
+	} {
+		got := s.Next(date(2026, 1, 1, 0, 0))
+		if want := date(2026, 1, 1, 2, 0); !got.Equal(want) {
+			t.Errorf("expected %v, got %v", want, got.UTC())
+		}
+	}
+}
+
+func TestCron_DaylightSavingGap(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+
+	// 2026-03-08 02:30 does not exist in New York; the next real 02:30 is
+	// the following day.
+	s := mustParse(t, "30 2 * * *", ny)
+	got := s.Next(time.Date(2026, 3, 8, 0, 0, 0, 0, ny))
+	if want := time.Date(2026, 3, 9, 2, 30, 0, 0, ny); !got.Equal(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestCron_NoMatch(t *testing.T) {
+	s := mustParse(t, "0 0 30 2 *", time.UTC)
+	if got := s.Next(date(2026, 1, 1, 0, 0)); !got.IsZero() {
+		t.Fatalf("expected no next run for February 30th, got %v", got)
+	}
+}
+
+// ===========================================================================
+// ParseCron — errors
+// ===========================================================================
+
+func TestCron_InvalidExpressions(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"10-5 * * * *",
+		"*/0 * * * *",
+		"abc * * * *",
+		"CRON_TZ=Nowhere/City * * * * *",
+	} {
+		if _, err := ParseCron(expr, time.UTC); !errors.Is(err, ErrInvalidCron) {
+			t.Errorf("%q: expected ErrInvalidCron, got %v", expr, err)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package scheduler
+
+import (
+	"fmt"
+	"time"
+)
+
+// Schedule decides when a task runs.
+type Schedule interface {
+	// Next returns the first activation strictly after t, or the zero time
+	// if the schedule has no further runs.
+	Next(t time.Time) time.Time
+}
+
+// At returns a schedule that runs once at t.
+func At(t time.Time) Schedule { return onceSchedule{at: t} }
+
+type onceSchedule struct{ at time.Time }
+
+func (s onceSchedule) Next(t time.Time) time.Time {
+	if t.Before(s.at) {
+		return s.at
+	}
+	return time.Time{}
+}
+
+func (s onceSchedule) String() string { return "at " + s.at.Format(time.RFC3339) }
+
+// Every returns a fixed-rate schedule: runs fall on a grid of interval-sized
+// steps from anchor, regardless of how long each run takes.
+func Every(interval time.Duration, anchor time.Time) Schedule {
+	if interval <= 0 {
+		panic("scheduler: Every interval must be positive")
+	}
+	return everySchedule{interval: interval, anchor: anchor}
+}
+
+type everySchedule struct {
+	interval time.Duration
+	anchor   time.Time
+}
+
+func (s everySchedule) Next(t time.Time) time.Time {
+	if t.Before(s.anchor) {
+		return s.anchor
+	}
+	steps := t.Sub(s.anchor)/s.interval + 1
+	return s.anchor.Add(steps * s.interval)
+}
+
+func (s everySchedule) String() string { return fmt.Sprintf("every %s", s.interval) }
@@ -0,0 +1,549 @@
+// Package scheduler runs jobs on a pool.Pool at set times: once at a given
+// time or after a delay, at a fixed rate, or on cron expressions with time
+// zones. Tasks can be listed, paused, resumed and removed at runtime, and
+// time is injectable through Clock for testing. With a Locker, replicas
+// sharing a schedule run each tick only once between them.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/vietpham102301/lightway/pkg/logger"
+	"github.com/vietpham102301/lightway/pkg/pool"
+)
+
+// Sentinel errors returned by the Scheduler.
+var (
+	// ErrTaskExists is returned when adding a task under a name in use.
+	ErrTaskExists = errors.New("scheduler: task already exists")
+
+	// ErrTaskNotFound is returned when no task has the given name.
+	ErrTaskNotFound = errors.New("scheduler: task not found")
+
+	// ErrNoNextRun is returned when adding a schedule that never runs, such
+	// as At with a time in the past.
+	ErrNoNextRun = errors.New("scheduler: schedule has no future runs")
+
+	// ErrInvalidCron wraps cron expression parse errors.
+	ErrInvalidCron = errors.New("scheduler: invalid cron expression")
+)
+
+// MissedPolicy decides what happens to runs that were due more than
+// Config.MissedGrace ago — because the process was busy or asleep, or the
+// clock jumped.
+type MissedPolicy int
+
+const (
+	// MissedRunOnce collapses all missed runs into a single run.
+	MissedRunOnce MissedPolicy = iota
+
+	// MissedSkip drops missed runs; the task waits for its next run.
+	MissedSkip
+
+	// MissedRunAll performs every missed run. Without AllowOverlap they run
+	// one after another.
+	MissedRunAll
+)
+
+// maxCatchUp bounds how many missed runs MissedRunAll replays at once.
+const maxCatchUp = 1000
+
+// claimTimeout bounds a single Locker.Claim call.
+const claimTimeout = 5 * time.Second
+
+// Locker decides which of several replicas runs a tick of a shared task.
+// *lock.Locker implements it on Redis.
+type Locker interface {
+	// Claim reports whether this replica won the run of task scheduled at
+	// at. Exactly one replica should win each (task, at) pair.
+	Claim(ctx context.Context, task string, at time.Time) (bool, error)
+}
+
+// Config holds all tunables for a Scheduler. Zero values produce sensible
+// defaults.
+type Config struct {
+	// Clock is the source of time. Default: SystemClock()
+	Clock Clock
+
+	// Location is the time zone for cron expressions without a CRON_TZ
+	// prefix. Default: time.Local
+	Location *time.Location
+
+	// MissedGrace is how late a run may start before it counts as missed.
+	// Default: 1s
+	MissedGrace time.Duration
+
+	// OnError is called when a run fails or cannot be submitted to the
+	// pool. Default: logs a warning
+	OnError func(task string, err error)
+
+	// Locker, if set, is asked to claim every run before it is submitted;
+	// runs claimed by another replica are dropped. Replicas agree on a run
+	// by its scheduled time, so Scheduler.Every aligns its ticks to
+	// multiples of the interval when a Locker is set; tasks added through
+	// Add with the Every schedule need an anchor shared by all replicas.
+	// Default: none (every replica runs every tick)
+	Locker Locker
+}
+
+func (c *Config) applyDefaults() {
+	if c.Clock == nil {
+		c.Clock = SystemClock()
+	}
+	if c.Location == nil {
+		c.Location = time.Local
+	}
+	if c.MissedGrace <= 0 {
+		c.MissedGrace = time.Second
+	}
+	if c.OnError == nil {
+		c.OnError = func(task string, err error) {
+			logger.Warn("scheduler: task failed", "task", task, "err", err)
+		}
+	}
+}
+
+// Options tune a single task. The zero value runs the task without
+// overlap, jitter or catch-up.
+type Options struct {
+	// AllowOverlap lets a run start while the previous one is still going.
+	// By default such runs are skipped.
+	AllowOverlap bool
+
+	// Jitter delays each run by a random duration in [0, Jitter), spreading
+	// out tasks that share a schedule.
+	Jitter time.Duration
+
+	// Missed is the policy for runs that were due but missed.
+	// Default: MissedRunOnce
+	Missed MissedPolicy
+}
+
+// TaskInfo is a point-in-time view of a task, as returned by List.
+type TaskInfo struct {
+	Name      string
+	Schedule  string    // human-readable schedule
+	Next      time.Time // next scheduled run; zero while paused
+	LastRun   time.Time // when the last run was dispatched
+	Paused    bool
+	Running   bool  // a run is in progress
+	Runs      int64 // runs dispatched to the pool
+	Skipped   int64 // runs skipped because the previous one was still going
+	Missed    int64 // runs dropped by MissedSkip
+	Contended int64 // runs claimed by another replica through Config.Locker
+	Failures  int64 // runs that failed, could not be claimed or could not be submitted
+}
+
+// Scheduler dispatches scheduled tasks into a pool.Pool. T is the pool's
+// result type; results are discarded except for errors, which go to
+// Config.OnError.
+type Scheduler[T any] struct {
+	cfg  Config
+	pool *pool.Pool[T]
+
+	mu    sync.Mutex
+	tasks map[string]*task[T]
+
+	wake    chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+	started bool
+}
+
+type task[T any] struct {
+	name     string
+	schedule Schedule
+	job      pool.Job[T]
+	opts     Options
+
+	next    time.Time // next scheduled run
+	fireAt  time.Time // next plus jitter
+	paused  bool
+	running int
+	queued  []time.Time // catch-up runs waiting for the current run to finish
+	removed bool
+	info    TaskInfo
+}
+
+// run is one scheduled execution of a task.
+type run[T any] struct {
+	task *task[T]
+	at   time.Time // scheduled time, before jitter
+}
+
+// New creates a Scheduler that submits to p. It does not run tasks until
+// Start is called; p must be started separately.
+func New[T any](p *pool.Pool[T], cfg Config) *Scheduler[T] {
+	cfg.applyDefaults()
+	return &Scheduler[T]{
+		cfg:   cfg,
+		pool:  p,
+		tasks: make(map[string]*task[T]),
+		wake:  make(chan struct{}, 1),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+}
+
+// Start launches the scheduling goroutine. Calling Start more than once is
+// a no-op.
+func (s *Scheduler[T]) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return
+	}
+	s.started = true
+	go s.loop()
+}
+
+// Stop halts scheduling and waits for the scheduling goroutine to exit.
+// Runs already submitted to the pool are not cancelled. Stop is safe to
+// call multiple times; a stopped Scheduler cannot be restarted.
+func (s *Scheduler[T]) Stop() {
+	s.mu.Lock()
+	if !s.started {
+		s.mu.Unlock()
+		return
+	}
+	select {
+	case <-s.stop:
+	default:
+		close(s.stop)
+	}
+	s.mu.Unlock()
+	<-s.done
+}
+
+// Add schedules job under name.
+func (s *Scheduler[T]) Add(name string, schedule Schedule, job pool.Job[T], opts Options) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.tasks[name]; ok {
+		return ErrTaskExists
+	}
+	t := &task[T]{name: name, schedule: schedule, job: job, opts: opts}
+	t.info.Name = name
+	t.info.Schedule = describe(schedule)
+	if !s.scheduleFrom(t, s.cfg.Clock.Now()) {
+		return ErrNoNextRun
+	}
+	s.tasks[name] = t
+	s.notify()
+	return nil
+}
+
+// At runs job once at the given time.
+func (s *Scheduler[T]) At(name string, at time.Time, job pool.Job[T], opts Options) error {
+	return s.Add(name, At(at), job, opts)
+}
+
+// After runs job once, d from now.
+func (s *Scheduler[T]) After(name string, d time.Duration, job pool.Job[T], opts Options) error {
+	return s.Add(name, At(s.cfg.Clock.Now().Add(d)), job, opts)
+}
+
+// Every runs job at a fixed rate, first one interval from now. With a
+// Config.Locker the ticks instead fall on wall-clock multiples of interval
+// (since the zero time), so replicas started at different moments schedule
+// the same runs; the first run then comes within one interval.
+func (s *Scheduler[T]) Every(name string, interval time.Duration, job pool.Job[T], opts Options) error {
+	anchor := s.cfg.Clock.Now()
+	if s.cfg.Locker != nil && interval > 0 {
+		anchor = anchor.Truncate(interval)
+	}
+	return s.Add(name, Every(interval, anchor.Add(interval)), job, opts)
+}
+
+// Cron runs job on a cron expression; see ParseCron. Expressions without a
+// CRON_TZ prefix use Config.Location.
+func (s *Scheduler[T]) Cron(name, expr string, job pool.Job[T], opts Options) error {
+	schedule, err := ParseCron(expr, s.cfg.Location)
+	if err != nil {
+		return err
+	}
+	return s.Add(name, schedule, job, opts)
+}
+
+// Remove unschedules a task. A run in progress is not cancelled.
+func (s *Scheduler[T]) Remove(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.tasks[name]
+	if !ok {
+		return ErrTaskNotFound
+	}
+	t.removed = true
+	delete(s.tasks, name)
+	s.notify()
+	return nil
+}
+
+// Pause stops a task from running until Resume. Runs that fall due while
+// paused are dropped rather than treated as missed.
+func (s *Scheduler[T]) Pause(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.tasks[name]
+	if !ok {
+		return ErrTaskNotFound
+	}
+	t.paused = true
+	s.notify()
+	return nil
+}
+
+// Resume re-enables a paused task from its next run after now.
+func (s *Scheduler[T]) Resume(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.tasks[name]
+	if !ok {
+		return ErrTaskNotFound
+	}
+	if !t.paused {
+		return nil
+	}
+	t.paused = false
+	if !s.scheduleFrom(t, s.cfg.Clock.Now()) {
+		delete(s.tasks, name)
+	}
+	s.notify()
+	return nil
+}
+
+// List returns every task, sorted by name.
+func (s *Scheduler[T]) List() []TaskInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	infos := make([]TaskInfo, 0, len(s.tasks))
+	for _, t := range s.tasks {
+		info := t.info
+		info.Paused = t.paused
+		info.Running = t.running > 0
+		if !t.paused {
+			info.Next = t.next
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// notify wakes the loop to re-plan. s.mu must be held.
+func (s *Scheduler[T]) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// scheduleFrom sets the task's next run after from, reporting false if
+// there is none. s.mu must be held.
+func (s *Scheduler[T]) scheduleFrom(t *task[T], from time.Time) bool {
+	t.next = t.schedule.Next(from)
+	if t.next.IsZero() {
+		return false
+	}
+	t.fireAt = t.next
+	if t.opts.Jitter > 0 {
+		t.fireAt = t.fireAt.Add(rand.N(t.opts.Jitter))
+	}
+	return true
+}
+
+func (s *Scheduler[T]) loop() {
+	defer close(s.done)
+	for {
+		var timer Timer
+		var fire <-chan time.Time
+		if at, ok := s.nextFire(); ok {
+			timer = s.cfg.Clock.NewTimer(at.Sub(s.cfg.Clock.Now()))
+			fire = timer.C()
+		}
+
+		select {
+		case <-s.stop:
+			if timer != nil {
+				timer.Stop()
+			}
+			return
+		case <-s.wake:
+		case <-fire:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		s.runDue()
+	}
+}
+
+// nextFire returns the earliest fire time among active tasks.
+func (s *Scheduler[T]) nextFire() (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var earliest time.Time
+	for _, t := range s.tasks {
+		if t.paused {
+			continue
+		}
+		if earliest.IsZero() || t.fireAt.Before(earliest) {
+			earliest = t.fireAt
+		}
+	}
+	return earliest, !earliest.IsZero()
+}
+
+// runDue dispatches every task whose fire time has passed.
+func (s *Scheduler[T]) runDue() {
+	var due []run[T]
+	defer func() {
+		// Submit outside the lock: under pool.BackpressureCallerRuns the run
+		// executes, and finishes, inside submit.
+		for _, r := range due {
+			s.dispatch(r)
+		}
+	}()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.cfg.Clock.Now()
+	for name, t := range s.tasks {
+		if t.paused || t.fireAt.After(now) {
+			continue
+		}
+
+		// Walk every run that has come due, splitting on-time from missed.
+		// Jitter is not lateness, so it extends the grace period.
+		grace := s.cfg.MissedGrace + t.opts.Jitter
+		var onTime, missed []time.Time
+		last := t.next
+		for at := t.next; !at.IsZero() && !at.After(now) && len(onTime)+len(missed) < maxCatchUp; at = t.schedule.Next(at) {
+			if now.Sub(at) > grace {
+				missed = append(missed, at)
+			} else {
+				onTime = append(onTime, at)
+			}
+			last = at
+		}
+
+		runs := onTime
+		switch t.opts.Missed {
+		case MissedSkip:
+			t.info.Missed += int64(len(missed))
+		case MissedRunAll:
+			runs = append(missed, onTime...)
+		default:
+			if len(missed) > 0 && len(onTime) == 0 {
+				runs = missed[len(missed)-1:]
+			}
+		}
+
+		catchUp := t.opts.Missed == MissedRunAll && len(missed) > 0
+		for _, at := range runs {
+			switch {
+			case t.opts.AllowOverlap || t.running == 0:
+				t.running++
+				due = append(due, run[T]{task: t, at: at})
+			case catchUp:
+				t.queued = append(t.queued, at)
+			default:
+				t.info.Skipped++
+			}
+		}
+
+		if !s.scheduleFrom(t, maxTime(last, now)) {
+			delete(s.tasks, name)
+		}
+	}
+}
+
+// dispatch starts a run already counted in task.running: it claims the run
+// through Config.Locker, if any, and submits it to the pool. Claims happen
+// on their own goroutine so a slow Locker does not hold up the loop.
+func (s *Scheduler[T]) dispatch(r run[T]) {
+	if s.cfg.Locker == nil {
+		s.submit(r.task)
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), claimTimeout)
+		won, err := s.cfg.Locker.Claim(ctx, r.task.name, r.at)
+		cancel()
+		switch {
+		case err != nil:
+			s.finish(r.task, fmt.Errorf("scheduler: claim run: %w", err), false)
+		case !won:
+			s.finish(r.task, nil, true)
+		default:
+			s.submit(r.task)
+		}
+	}()
+}
+
+// submit hands a run of t to the pool.
+func (s *Scheduler[T]) submit(t *task[T]) {
+	s.mu.Lock()
+	t.info.Runs++
+	t.info.LastRun = s.cfg.Clock.Now()
+	s.mu.Unlock()
+
+	err := s.pool.SubmitOnComplete(context.Background(), t.job, func(res pool.Result[T]) {
+		s.finish(t, res.Err, false)
+	})
+	if err != nil {
+		s.finish(t, err, false)
+	}
+}
+
+// finish records the end of a run, or of a run another replica claimed,
+// and starts a queued catch-up run.
+func (s *Scheduler[T]) finish(t *task[T], err error, contended bool) {
+	if err != nil {
+		s.cfg.OnError(t.name, err)
+	}
+
+	s.mu.Lock()
+	t.running--
+	if err != nil {
+		t.info.Failures++
+	}
+	if contended {
+		t.info.Contended++
+	}
+	var next run[T]
+	ok := len(t.queued) > 0 && !t.removed
+	if ok {
+		next = run[T]{task: t, at: t.queued[0]}
+		t.queued = t.queued[1:]
+		t.running++
+	}
+	s.mu.Unlock()
+
+	if ok {
+		s.dispatch(next)
+	}
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+// describe returns a human-readable form of a schedule.
+func describe(schedule Schedule) string {
+	if s, ok := schedule.(interface{ String() string }); ok {
+		return s.String()
+	}
+	return "custom"
+}
@@ -0,0 +1,544 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/vietpham102301/lightway/pkg/pool"
+)
+
+// ===========================================================================
+// Helpers
+// ===========================================================================
+
+var epoch = time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+
+func newTestScheduler(t *testing.T, cfg Config) (*Scheduler[int], *FakeClock) {
+	t.Helper()
+	p := pool.New[int](pool.Config{
+		MinWorkers:    4,
+		MaxWorkers:    4,
+		QueueSize:     64,
+		IdleTimeout:   time.Second,
+		ScaleInterval: time.Second,
+	})
+	p.Start()
+	t.Cleanup(p.Stop)
+
+	clock := NewFakeClock(epoch)
+	cfg.Clock = clock
+	if cfg.Location == nil {
+		cfg.Location = time.UTC
+	}
+	s := New(p, cfg)
+	t.Cleanup(s.Stop)
+	return s, clock
+}
+
+// start starts s and waits for it to arm its first timer.
+func start(t *testing.T, s *Scheduler[int], clock *FakeClock) {
+	t.Helper()
+	s.Start()
+	waitFor(t, func() bool { return clock.Timers() == 1 })
+}
+
+func countJob(n *atomic.Int64) pool.Job[int] {
+	return pool.JobFunc[int](func(context.Context) (int, error) {
+		return int(n.Add(1)), nil
+	})
+}
+
+// gateJob counts runs and blocks each one until gate is closed, tracking
+// the peak number of concurrent runs.
+type gateJob struct {
+	gate         <-chan struct{}
+	runs, active atomic.Int64
+	peak         atomic.Int64
+}
+
+func (j *gateJob) Execute(context.Context) (int, error) {
+	n := j.active.Add(1)
+	defer j.active.Add(-1)
+	for {
+		peak := j.peak.Load()
+		if n <= peak || j.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	j.runs.Add(1)
+	<-j.gate
+	return 0, nil
+}
+
+func info(t *testing.T, s *Scheduler[int], name string) TaskInfo {
+	t.Helper()
+	for _, ti := range s.List() {
+		if ti.Name == name {
+			return ti
+		}
+	}
+	t.Fatalf("task %q not listed", name)
+	return TaskInfo{}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout waiting for condition")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// ===========================================================================
+// One-shot tasks
+// ===========================================================================
+
+func TestScheduler_After(t *testing.T) {
+	s, clock := newTestScheduler(t, Config{})
+	var n atomic.Int64
+	if err := s.After("once", time.Minute, countJob(&n), Options{}); err != nil {
+		t.Fatalf("After: unexpected error: %v", err)
+	}
+	start(t, s, clock)
+
+	clock.Advance(30 * time.Second)
+	if got := n.Load(); got != 0 {
+		t.Fatalf("expected no run before the delay, got %d", got)
+	}
+
+	clock.Advance(30 * time.Second)
+	waitFor(t, func() bool { return n.Load() == 1 })
+	waitFor(t, func() bool { return len(s.List()) == 0 })
+}
+
+func TestScheduler_AtInPast(t *testing.T) {
+	s, _ := newTestScheduler(t, Config{})
+	var n atomic.Int64
+	err := s.At("late", epoch.Add(-time.Minute), countJob(&n), Options{})
+	if !errors.Is(err, ErrNoNextRun) {
+		t.Fatalf("expected ErrNoNextRun, got %v", err)
+	}
+}
+
+// ===========================================================================
+// Recurring tasks
+// ===========================================================================
+
+func TestScheduler_Every(t *testing.T) {
+	s, clock := newTestScheduler(t, Config{})
+	var n atomic.Int64
+	if err := s.Every("tick", time.Minute, countJob(&n), Options{}); err != nil {
+		t.Fatalf("Every: unexpected error: %v", err)
+	}
+	start(t, s, clock)
+
+	for i := int64(1); i <= 3; i++ {
+		clock.Advance(time.Minute)
+		waitFor(t, func() bool { return n.Load() == i })
+	}
+
+	waitFor(t, func() bool { return info(t, s, "tick").Runs == 3 })
+	ti := info(t, s, "tick")
+	if want := epoch.Add(4 * time.Minute); !ti.Next.Equal(want) {
+		t.Errorf("expected next run %v, got %v", want, ti.Next)
+	}
+	if ti.Schedule != "every 1m0s" {
+		t.Errorf("expected schedule %q, got %q", "every 1m0s", ti.Schedule)
+	}
+}
+
+func TestScheduler_Cron(t *testing.T) {
+	s, clock := newTestScheduler(t, Config{})
+	var n atomic.Int64
+	if err := s.Cron("report", "*/5 * * * *", countJob(&n), Options{}); err != nil {
+		t.Fatalf("Cron: unexpected error: %v", err)
+	}
+	if got := info(t, s, "report").Next; !got.Equal(epoch.Add(5 * time.Minute)) {
+		t.Fatalf("expected first run at 10:05, got %v", got)
+	}
+	start(t, s, clock)
+
+	clock.Advance(5 * time.Minute)
+	waitFor(t, func() bool { return n.Load() == 1 })
+
+	if _, err := ParseCron("bad", nil); !errors.Is(err, ErrInvalidCron) {
+		t.Fatalf("expected ErrInvalidCron, got %v", err)
+	}
+	if err := s.Cron("bad", "61 * * * *", countJob(&n), Options{}); !errors.Is(err, ErrInvalidCron) {
+		t.Fatalf("expected ErrInvalidCron from Cron, got %v", err)
+	}
+}
+
+// ===========================================================================
+// Overlap and jitter
+// ===========================================================================
+
+func TestScheduler_OverlapSkipped(t *testing.T) {
+	s, clock := newTestScheduler(t, Config{})
+	gate := make(chan struct{})
+	job := &gateJob{gate: gate}
+	if err := s.Every("slow", time.Minute, job, Options{}); err != nil {
+		t.Fatalf("Every: unexpected error: %v", err)
+	}
+	start(t, s, clock)
+
+	clock.Advance(time.Minute)
+	waitFor(t, func() bool { return job.runs.Load() == 1 })
+	if !info(t, s, "slow").Running {
+		t.Fatal("expected task to be running")
+	}
+
+	clock.Advance(time.Minute)
+	waitFor(t, func() bool { return info(t, s, "slow").Skipped == 1 })
+	close(gate)
+	waitFor(t, func() bool { return !info(t, s, "slow").Running })
+
+	if got := job.runs.Load(); got != 1 {
+		t.Errorf("expected 1 run, got %d", got)
+	}
+}
+
+func TestScheduler_AllowOverlap(t *testing.T) {
+	s, clock := newTestScheduler(t, Config{})
+	gate := make(chan struct{})
+	defer close(gate)
+	job := &gateJob{gate: gate}
+	if err := s.Every("slow", time.Minute, job, Options{AllowOverlap: true}); err != nil {
+		t.Fatalf("Every: unexpected error: %v", err)
+	}
+	start(t, s, clock)
+
+	clock.Advance(time.Minute)
+	waitFor(t, func() bool { return job.runs.Load() == 1 })
+	clock.Advance(time.Minute)
+	waitFor(t, func() bool { return job.peak.Load() == 2 })
+
+	if got := info(t, s, "slow").Skipped; got != 0 {
+		t.Errorf("expected no skipped runs, got %d", got)
+	}
+}
+
+func TestScheduler_Jitter(t *testing.T) {
+	s, clock := newTestScheduler(t, Config{})
+	const jitter = 10 * time.Second
+	var n atomic.Int64
+	for _, name := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		opts := Options{Jitter: jitter, Missed: MissedSkip}
+		if err := s.Every(name, time.Minute, countJob(&n), opts); err != nil {
+			t.Fatalf("Every: unexpected error: %v", err)
+		}
+	}
+
+	s.mu.Lock()
+	for name, task := range s.tasks {
+		if task.fireAt.Before(task.next) || !task.fireAt.Before(task.next.Add(jitter)) {
+			t.Errorf("%s: fire time %v outside [%v, %v)", name, task.fireAt, task.next, task.next.Add(jitter))
+		}
+	}
+	s.mu.Unlock()
+	start(t, s, clock)
+
+	// Late by less than the jitter: every run is on time, none missed.
+	clock.Advance(time.Minute + jitter)
+	waitFor(t, func() bool { return n.Load() == 8 })
+	for _, ti := range s.List() {
+		if ti.Missed != 0 {
+			t.Errorf("%s: expected no missed runs, got %d", ti.Name, ti.Missed)
+		}
+	}
+}
+
+// ===========================================================================
+// Missed runs
+// ===========================================================================
+
+func TestScheduler_MissedPolicies(t *testing.T) {
+	cases := []struct {
+		name       string
+		policy     MissedPolicy
+		wantRuns   int64
+		wantMissed int64
+	}{
+		{"skip", MissedSkip, 0, 5},
+		{"run once", MissedRunOnce, 1, 0},
+		{"run all", MissedRunAll, 5, 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, clock := newTestScheduler(t, Config{})
+			gate := make(chan struct{})
+			close(gate)
+			job := &gateJob{gate: gate}
+			if err := s.Every("tick", time.Minute, job, Options{Missed: tc.policy}); err != nil {
+				t.Fatalf("Every: unexpected error: %v", err)
+			}
+			start(t, s, clock)
+
+			// Runs at 1m..5m are all more than MissedGrace late.
+			clock.Advance(5*time.Minute + 30*time.Second)
+			waitFor(t, func() bool {
+				ti := info(t, s, "tick")
+				return ti.Runs == tc.wantRuns && ti.Missed == tc.wantMissed && !ti.Running
+			})
+			if got := job.runs.Load(); got != tc.wantRuns {
+				t.Errorf("expected %d runs, got %d", tc.wantRuns, got)
+			}
+			if got := job.peak.Load(); got > 1 {
+				t.Errorf("expected catch-up runs one at a time, got %d concurrent", got)
+			}
+			if want, got := epoch.Add(6*time.Minute), info(t, s, "tick").Next; !got.Equal(want) {
+				t.Errorf("expected next run %v, got %v", want, got)
+			}
+		})
+	}
+}
+
+// ===========================================================================
+// Runtime management
+// ===========================================================================
+
+func TestScheduler_PauseResumeRemove(t *testing.T) {
+	s, clock := newTestScheduler(t, Config{})
+	var n atomic.Int64
+	if err := s.Every("tick", time.Minute, countJob(&n), Options{}); err != nil {
+		t.Fatalf("Every: unexpected error: %v", err)
+	}
+	start(t, s, clock)
+
+	if err := s.Pause("tick"); err != nil {
+		t.Fatalf("Pause: unexpected error: %v", err)
+	}
+	waitFor(t, func() bool { return clock.Timers() == 0 })
+	ti := info(t, s, "tick")
+	if !ti.Paused || !ti.Next.IsZero() {
+		t.Fatalf("expected paused task with no next run, got %+v", ti)
+	}
+
+	clock.Advance(2*time.Minute + 30*time.Second)
+	if err := s.Resume("tick"); err != nil {
+		t.Fatalf("Resume: unexpected error: %v", err)
+	}
+	if want, got := epoch.Add(3*time.Minute), info(t, s, "tick").Next; !got.Equal(want) {
+		t.Fatalf("expected next run %v after resume, got %v", want, got)
+	}
+	waitFor(t, func() bool { return clock.Timers() == 1 })
+	clock.Advance(30 * time.Second)
+	waitFor(t, func() bool { return n.Load() == 1 })
+	if got := info(t, s, "tick").Missed; got != 0 {
+		t.Errorf("expected paused runs not to count as missed, got %d", got)
+	}
+
+	if err := s.Remove("tick"); err != nil {
+		t.Fatalf("Remove: unexpected error: %v", err)
+	}
+	if got := len(s.List()); got != 0 {
+		t.Fatalf("expected no tasks after Remove, got %d", got)
+	}
+	for _, err := range []error{s.Remove("tick"), s.Pause("tick"), s.Resume("tick")} {
+		if !errors.Is(err, ErrTaskNotFound) {
+			t.Errorf("expected ErrTaskNotFound, got %v", err)
+		}
+	}
+}
+
+func TestScheduler_DuplicateName(t *testing.T) {
+	s, _ := newTestScheduler(t, Config{})
+	var n atomic.Int64
+	if err := s.Every("tick", time.Minute, countJob(&n), Options{}); err != nil {
+		t.Fatalf("Every: unexpected error: %v", err)
+	}
+	if err := s.After("tick", time.Minute, countJob(&n), Options{}); !errors.Is(err, ErrTaskExists) {
+		t.Fatalf("expected ErrTaskExists, got %v", err)
+	}
+}
+
+func TestScheduler_List(t *testing.T) {
+	s, _ := newTestScheduler(t, Config{})
+	var n atomic.Int64
+	_ = s.Every("b", time.Minute, countJob(&n), Options{})
+	_ = s.Cron("a", "0 9 * * *", countJob(&n), Options{})
+
+	list := s.List()
+	if len(list) != 2 || list[0].Name != "a" || list[1].Name != "b" {
+		t.Fatalf("expected tasks [a b], got %+v", list)
+	}
+	if list[0].Schedule != "0 9 * * *" {
+		t.Errorf("expected schedule %q, got %q", "0 9 * * *", list[0].Schedule)
+	}
+}
+
+// ===========================================================================
+// Locker
+// ===========================================================================
+
+// memLocker is an in-memory Locker shared by schedulers standing in for
+// replicas.
+type memLocker struct {
+	mu     sync.Mutex
+	claims map[string]bool
+	err    error
+}
+
+func (l *memLocker) Claim(_ context.Context, task string, at time.Time) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.err != nil {
+		return false, l.err
+	}
+	key := task + "@" + at.String()
+	if l.claims[key] {
+		return false, nil
+	}
+	l.claims[key] = true
+	return true, nil
+}
+
+func TestScheduler_LockerOneReplicaPerTick(t *testing.T) {
+	locker := &memLocker{claims: make(map[string]bool)}
+	var n atomic.Int64
+	var replicas []*Scheduler[int]
+	var clocks []*FakeClock
+	for range 3 {
+		s, clock := newTestScheduler(t, Config{Locker: locker})
+		if err := s.Cron("report", "*/5 * * * *", countJob(&n), Options{}); err != nil {
+			t.Fatalf("Cron: unexpected error: %v", err)
+		}
+		start(t, s, clock)
+		replicas = append(replicas, s)
+		clocks = append(clocks, clock)
+	}
+
+	for tick := int64(1); tick <= 2; tick++ {
+		for _, clock := range clocks {
+			clock.Advance(5 * time.Minute)
+		}
+		waitFor(t, func() bool {
+			var runs, contended int64
+			for _, s := range replicas {
+				ti := info(t, s, "report")
+				runs += ti.Runs
+				contended += ti.Contended
+			}
+			return runs == tick && contended == 2*tick
+		})
+	}
+	waitFor(t, func() bool { return n.Load() == 2 })
+}
+
+func TestScheduler_LockerEveryAlignsReplicas(t *testing.T) {
+	locker := &memLocker{claims: make(map[string]bool)}
+	var n atomic.Int64
+	var replicas []*Scheduler[int]
+	var clocks []*FakeClock
+	// Replicas started 10s and 25s into the same minute.
+	for _, offset := range []time.Duration{10 * time.Second, 25 * time.Second} {
+		s, clock := newTestScheduler(t, Config{Locker: locker})
+		clock.Set(epoch.Add(offset))
+		if err := s.Every("tick", time.Minute, countJob(&n), Options{}); err != nil {
+			t.Fatalf("Every: unexpected error: %v", err)
+		}
+		if next := info(t, s, "tick").Next; !next.Equal(epoch.Add(time.Minute)) {
+			t.Fatalf("expected next run %v, got %v", epoch.Add(time.Minute), next)
+		}
+		start(t, s, clock)
+		replicas = append(replicas, s)
+		clocks = append(clocks, clock)
+	}
+
+	for tick := int64(1); tick <= 2; tick++ {
+		for _, clock := range clocks {
+			clock.Set(epoch.Add(time.Duration(tick) * time.Minute))
+		}
+		waitFor(t, func() bool {
+			var runs, contended int64
+			for _, s := range replicas {
+				ti := info(t, s, "tick")
+				runs += ti.Runs
+				contended += ti.Contended
+			}
+			return runs == tick && contended == tick
+		})
+	}
+	waitFor(t, func() bool { return n.Load() == 2 })
+}
+
+func TestScheduler_LockerError(t *testing.T) {
+	errRedis := errors.New("redis down")
+	failed := make(chan error, 1)
+	s, clock := newTestScheduler(t, Config{
+		Locker:  &memLocker{err: errRedis},
+		OnError: func(_ string, err error) { failed <- err },
+	})
+	var n atomic.Int64
+	if err := s.Every("tick", time.Minute, countJob(&n), Options{}); err != nil {
+		t.Fatalf("Every: unexpected error: %v", err)
+	}
+	start(t, s, clock)
+	clock.Advance(time.Minute)
+
+	select {
+	case err := <-failed:
+		if !errors.Is(err, errRedis) {
+			t.Errorf("expected claim error, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected OnError to be called")
+	}
+	waitFor(t, func() bool { return info(t, s, "tick").Failures == 1 })
+	if got := n.Load(); got != 0 {
+		t.Errorf("expected no run without a claim, got %d", got)
+	}
+}
+
+// ===========================================================================
+// Error handling
+// ===========================================================================
+
+func TestScheduler_OnError(t *testing.T) {
+	errBoom := errors.New("boom")
+	failed := make(chan string, 1)
+	s, clock := newTestScheduler(t, Config{
+		OnError: func(task string, err error) {
+			if errors.Is(err, errBoom) {
+				failed <- task
+			}
+		},
+	})
+	job := pool.JobFunc[int](func(context.Context) (int, error) { return 0, errBoom })
+	if err := s.After("fail", time.Minute, job, Options{}); err != nil {
+		t.Fatalf("After: unexpected error: %v", err)
+	}
+	start(t, s, clock)
+	clock.Advance(time.Minute)
+
+	select {
+	case name := <-failed:
+		if name != "fail" {
+			t.Errorf("expected task %q, got %q", "fail", name)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected OnError to be called")
+	}
+}
+
+func TestScheduler_StopIdempotent(t *testing.T) {
+	s, clock := newTestScheduler(t, Config{})
+	var n atomic.Int64
+	_ = s.Every("tick", time.Minute, countJob(&n), Options{})
+	start(t, s, clock)
+	s.Stop()
+	s.Stop()
+
+	clock.Advance(time.Minute)
+	time.Sleep(10 * time.Millisecond)
+	if got := n.Load(); got != 0 {
+		t.Fatalf("expected no runs after Stop, got %d", got)
+	}
+}
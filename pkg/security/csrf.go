@@ -0,0 +1,234 @@
+package security
+
+import (
+	stdctx "context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/vietpham102301/lightway/pkg/context"
+	aerror "github.com/vietpham102301/lightway/pkg/errors"
+	"github.com/vietpham102301/lightway/pkg/logger"
+)
+
+// CodeCSRFInvalid is the AppError code returned when CSRF validation fails.
+const CodeCSRFInvalid = "CSRF_INVALID"
+
+// csrfTokenLength is the size in bytes of the secret stored in the cookie.
+const csrfTokenLength = 32
+
+// CSRFConfig holds CSRF middleware options.
+type CSRFConfig struct {
+	// CookieName is the cookie holding the token. Default: "csrf_token"
+	CookieName string
+
+	// HeaderName is the request header carrying the token. Default: "X-CSRF-Token"
+	HeaderName string
+
+	// FormField is the form field carrying the token. Default: "csrf_token"
+	FormField string
+
+	// CookiePath is the cookie Path. Default: "/"
+	CookiePath string
+
+	// CookieDomain is the cookie Domain. Default: host-only
+	CookieDomain string
+
+	// CookieMaxAge is the cookie lifetime. Default: 12h
+	CookieMaxAge time.Duration
+
+	// CookieSameSite is the cookie SameSite mode. Default: http.SameSiteLaxMode
+	CookieSameSite http.SameSite
+
+	// InsecureCookie drops the Secure attribute, for local HTTP development.
+	InsecureCookie bool
+
+	// TrustedOrigins are extra origins (scheme://host[:port]) allowed to send
+	// unsafe requests. The request's own host is always trusted.
+	TrustedOrigins []string
+
+	// Exempt, if set, skips CSRF checks for requests it returns true for.
+	// Requests with an "Authorization: Bearer" header are always exempt,
+	// since browsers never attach bearer tokens automatically.
+	Exempt func(r *http.Request) bool
+}
+
+func (c *CSRFConfig) applyDefaults() {
+	if c.CookieName == "" {
+		c.CookieName = "csrf_token"
+	}
+	if c.HeaderName == "" {
+		c.HeaderName = "X-CSRF-Token"
+	}
+	if c.FormField == "" {
+		c.FormField = "csrf_token"
+	}
+	if c.CookiePath == "" {
+		c.CookiePath = "/"
+	}
+	if c.CookieMaxAge <= 0 {
+		c.CookieMaxAge = 12 * time.Hour
+	}
+	if c.CookieSameSite == 0 {
+		c.CookieSameSite = http.SameSiteLaxMode
+	}
+}
+
+// CSRF returns a middleware implementing the double-submit cookie pattern.
+// Safe requests (GET, HEAD, OPTIONS, TRACE) receive a token cookie, and the
+// token is exposed to handlers via c.CSRFToken(). Unsafe requests must echo
+// the token in HeaderName or FormField, and their Origin, if present, must
+// be the request's host or a trusted origin. Failures get a 403 AppError
+// with code CSRF_INVALID.
+//
+// The token from c.CSRFToken() is masked with a fresh pad on every request,
+// so it can be embedded in compressed HTML without leaking the secret.
+// Clients may also send the raw cookie value back.
+func CSRF(cfg CSRFConfig) func(http.Handler) http.Handler {
+	cfg.applyDefaults()
+
+	trusted := make(map[string]bool, len(cfg.TrustedOrigins))
+	for _, o := range cfg.TrustedOrigins {
+		trusted[strings.ToLower(strings.TrimRight(o, "/"))] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if isBearer(r) || (cfg.Exempt != nil && cfg.Exempt(r)) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			secret := cfg.readCookie(r)
+			if secret == nil {
+				secret = make([]byte, csrfTokenLength)
+				rand.Read(secret)
+				cfg.setCookie(w, r, secret)
+			}
+			w.Header().Add("Vary", "Cookie")
+			r = r.WithContext(stdctx.WithValue(r.Context(), context.CSRFTokenKey, maskToken(secret)))
+
+			if isSafeMethod(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if reason := cfg.check(r, secret, trusted); reason != "" {
+				logger.Warn("security: CSRF check failed", "reason", reason, "method", r.Method, "path", r.URL.Path)
+				c := &context.Context{W: w, R: r}
+				c.JSONResponse(http.StatusForbidden, nil,
+					aerror.Forbidden("CSRF token missing or invalid").WithCode(CodeCSRFInvalid))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// check validates an unsafe request, returning a reason on failure.
+func (cfg *CSRFConfig) check(r *http.Request, secret []byte, trusted map[string]bool) string {
+	if origin := r.Header.Get("Origin"); origin != "" && !sameOrigin(origin, r) && !trusted[strings.ToLower(origin)] {
+		return "untrusted origin"
+	}
+
+	submitted := r.Header.Get(cfg.HeaderName)
+	if submitted == "" {
+		submitted = r.PostFormValue(cfg.FormField)
+	}
+	if submitted == "" {
+		return "missing token"
+	}
+	if !tokenMatches(submitted, secret) {
+		return "token mismatch"
+	}
+	return ""
+}
+
+func (cfg *CSRFConfig) readCookie(r *http.Request) []byte {
+	cookie, err := r.Cookie(cfg.CookieName)
+	if err != nil {
+		return nil
+	}
+	secret, err := base64.RawURLEncoding.DecodeString(cookie.Value)
+	if err != nil || len(secret) != csrfTokenLength {
+		return nil
+	}
+	return secret
+}
+
+func (cfg *CSRFConfig) setCookie(w http.ResponseWriter, r *http.Request, secret []byte) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     cfg.CookieName,
+		Value:    base64.RawURLEncoding.EncodeToString(secret),
+		Path:     cfg.CookiePath,
+		Domain:   cfg.CookieDomain,
+		MaxAge:   int(cfg.CookieMaxAge / time.Second),
+		Secure:   !cfg.InsecureCookie,
+		SameSite: cfg.CookieSameSite,
+		// Not HttpOnly: single-page apps read the cookie to set the header.
+	})
+}
+
+// maskToken returns pad || (pad XOR secret), base64url encoded.
+func maskToken(secret []byte) string {
+	out := make([]byte, 2*len(secret))
+	pad := out[:len(secret)]
+	rand.Read(pad)
+	for i, b := range secret {
+		out[len(secret)+i] = pad[i] ^ b
+	}
+	return base64.RawURLEncoding.EncodeToString(out)
+}
+
+// tokenMatches reports whether a submitted token, masked or raw, matches secret.
+func tokenMatches(submitted string, secret []byte) bool {
+	raw, err := base64.RawURLEncoding.DecodeString(submitted)
+	if err != nil {
+		return false
+	}
+	switch len(raw) {
+	case 2 * len(secret):
+		pad, masked := raw[:len(secret)], raw[len(secret):]
+		for i := range masked {
+			masked[i] ^= pad[i]
+		}
+		raw = masked
+	case len(secret):
+	default:
+		return false
+	}
+	return subtle.ConstantTimeCompare(raw, secret) == 1
+}
+
+// sameOrigin reports whether origin names the scheme and host r was sent
+// to. Behind a TLS-terminating proxy the scheme comes from
+// X-Forwarded-Proto.
+func sameOrigin(origin string, r *http.Request) bool {
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	scheme := "http"
+	if isHTTPS(r) {
+		scheme = "https"
+	}
+	return strings.EqualFold(u.Scheme, scheme) && strings.EqualFold(u.Host, r.Host)
+}
+
+func isBearer(r *http.Request) bool {
+	scheme, _, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	return ok && strings.EqualFold(scheme, "Bearer")
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
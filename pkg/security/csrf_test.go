@@ -0,0 +1,246 @@
+package security
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/vietpham102301/lightway/pkg/context"
+	"github.com/vietpham102301/lightway/pkg/router"
+)
+
+// newCSRFRouter returns a router with GET /form exposing the token and
+// POST /submit protected by CSRF.
+func newCSRFRouter(cfg CSRFConfig) *router.Router {
+	r := router.NewRouter()
+	r.Use(CSRF(cfg))
+	r.GET("/form", func(c *context.Context) error {
+		c.JSONResponse(http.StatusOK, c.CSRFToken(), nil)
+		return nil
+	})
+	r.POST("/submit", func(c *context.Context) error {
+		c.JSONResponse(http.StatusOK, "ok", nil)
+		return nil
+	})
+	return r
+}
+
+// fetchToken performs a GET and returns the cookie and masked token.
+func fetchToken(t *testing.T, r http.Handler) (*http.Cookie, string) {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/form", nil))
+
+	cookies := rr.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("expected one cookie, got %d", len(cookies))
+	}
+	var resp context.AppResponse
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	token, _ := resp.Data.(string)
+	if token == "" {
+		t.Fatal("expected token in response")
+	}
+	return cookies[0], token
+}
+
+func TestCSRF_IssuesCookie(t *testing.T) {
+	cookie, token := fetchToken(t, newCSRFRouter(CSRFConfig{}))
+
+	if cookie.Name != "csrf_token" || !cookie.Secure || cookie.HttpOnly {
+		t.Errorf("unexpected cookie attributes %+v", cookie)
+	}
+	if cookie.SameSite != http.SameSiteLaxMode {
+		t.Errorf("expected SameSite Lax, got %v", cookie.SameSite)
+	}
+	if token == cookie.Value {
+		t.Error("expected exposed token to be masked")
+	}
+}
+
+func TestCSRF_ValidTokens(t *testing.T) {
+	r := newCSRFRouter(CSRFConfig{})
+	cookie, token := fetchToken(t, r)
+
+	tests := []struct {
+		name  string
+		build func() *http.Request
+	}{
+		{"masked header", func() *http.Request {
+			req := httptest.NewRequest(http.MethodPost, "/submit", nil)
+			req.Header.Set("X-CSRF-Token", token)
+			return req
+		}},
+		{"raw cookie value in header", func() *http.Request {
+			req := httptest.NewRequest(http.MethodPost, "/submit", nil)
+			req.Header.Set("X-CSRF-Token", cookie.Value)
+			return req
+		}},
+		{"form field", func() *http.Request {
+			form := url.Values{"csrf_token": {token}}
+			req := httptest.NewRequest(http.MethodPost, "/submit", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			return req
+		}},
+		{"same origin", func() *http.Request {
+			req := httptest.NewRequest(http.MethodPost, "/submit", nil)
+			req.Header.Set("Origin", "http://example.com")
+			req.Header.Set("X-CSRF-Token", token)
+			return req
+		}},
+		{"same origin behind TLS proxy", func() *http.Request {
+			req := httptest.NewRequest(http.MethodPost, "/submit", nil)
+			req.Header.Set("Origin", "https://example.com")
+			req.Header.Set("X-Forwarded-Proto", "https")
+			req.Header.Set("X-CSRF-Token", token)
+			return req
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.build()
+			req.AddCookie(cookie)
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Errorf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestCSRF_Rejects(t *testing.T) {
+	r := newCSRFRouter(CSRFConfig{})
+	cookie, token := fetchToken(t, r)
+	_, otherToken := fetchToken(t, r)
+
+	tests := []struct {
+		name   string
+		cookie bool
+		token  string
+		origin string
+	}{
+		{"missing token", true, "", ""},
+		{"no cookie", false, token, ""},
+		{"token from another session", true, otherToken, ""},
+		{"garbage token", true, "not-a-token", ""},
+		{"cross origin", true, token, "https://evil.example"},
+		{"same host, other scheme", true, token, "https://example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/submit", nil)
+			if tt.cookie {
+				req.AddCookie(cookie)
+			}
+			if tt.token != "" {
+				req.Header.Set("X-CSRF-Token", tt.token)
+			}
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusForbidden {
+				t.Fatalf("expected status 403, got %d", rr.Code)
+			}
+			var resp context.AppResponse
+			json.Unmarshal(rr.Body.Bytes(), &resp)
+			if resp.ErrorCode != CodeCSRFInvalid {
+				t.Errorf("expected error code %s, got %q", CodeCSRFInvalid, resp.ErrorCode)
+			}
+		})
+	}
+}
+
+func TestCSRF_RejectsPlainHTTPOriginOnHTTPSSite(t *testing.T) {
+	r := newCSRFRouter(CSRFConfig{})
+	cookie, token := fetchToken(t, r)
+
+	req := httptest.NewRequest(http.MethodPost, "/submit", nil)
+	req.AddCookie(cookie)
+	req.Header.Set("X-Forwarded-Proto", "https")
+	req.Header.Set("Origin", "http://example.com")
+	req.Header.Set("X-CSRF-Token", token)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("expected status 403, got %d", rr.Code)
+	}
+}
+
+func TestCSRF_TrustedOrigin(t *testing.T) {
+	r := newCSRFRouter(CSRFConfig{TrustedOrigins: []string{"https://app.example.com/"}})
+	cookie, token := fetchToken(t, r)
+
+	req := httptest.NewRequest(http.MethodPost, "/submit", nil)
+	req.AddCookie(cookie)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("X-CSRF-Token", token)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", rr.Code)
+	}
+}
+
+func TestCSRF_Exemptions(t *testing.T) {
+	r := newCSRFRouter(CSRFConfig{
+		Exempt: func(r *http.Request) bool { return r.Header.Get("X-Webhook") != "" },
+	})
+
+	tests := []struct {
+		name          string
+		header, value string
+	}{
+		{"bearer token", "Authorization", "Bearer abc"},
+		{"custom exempt", "X-Webhook", "1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/submit", nil)
+			req.Header.Set(tt.header, tt.value)
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Errorf("expected status 200, got %d", rr.Code)
+			}
+			if len(rr.Result().Cookies()) != 0 {
+				t.Error("expected no CSRF cookie for exempt request")
+			}
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/submit", nil)
+	req.Header.Set("Authorization", "Basic abc")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("expected Basic auth not to be exempt, got %d", rr.Code)
+	}
+}
+
+func TestCSRF_ReusesExistingCookie(t *testing.T) {
+	r := newCSRFRouter(CSRFConfig{})
+	cookie, _ := fetchToken(t, r)
+
+	req := httptest.NewRequest(http.MethodGet, "/form", nil)
+	req.AddCookie(cookie)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	if len(rr.Result().Cookies()) != 0 {
+		t.Error("expected no new cookie when a valid one is present")
+	}
+}
@@ -0,0 +1,141 @@
+package security
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vietpham102301/lightway/pkg/context"
+	"github.com/vietpham102301/lightway/pkg/router"
+)
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+})
+
+func TestDefaultHeaders(t *testing.T) {
+	rr := httptest.NewRecorder()
+	DefaultHeaders()(okHandler).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	want := map[string]string{
+		"X-Content-Type-Options":     "nosniff",
+		"X-Frame-Options":            "DENY",
+		"Referrer-Policy":            "strict-origin-when-cross-origin",
+		"Cross-Origin-Opener-Policy": "same-origin",
+	}
+	for k, v := range want {
+		if got := rr.Header().Get(k); got != v {
+			t.Errorf("expected %s %q, got %q", k, v, got)
+		}
+	}
+	if rr.Header().Get("Permissions-Policy") == "" {
+		t.Error("expected Permissions-Policy to be set")
+	}
+	if rr.Header().Get("Strict-Transport-Security") != "" {
+		t.Error("expected no HSTS on plain HTTP")
+	}
+}
+
+func TestHeaders_HSTS(t *testing.T) {
+	cfg := DefaultHeadersConfig()
+	cfg.HSTSMaxAge = time.Hour
+	cfg.HSTSPreload = true
+	h := Headers(cfg)(okHandler)
+
+	tests := []struct {
+		name string
+		req  func() *http.Request
+	}{
+		{"tls", func() *http.Request {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.TLS = &tls.ConnectionState{}
+			return r
+		}},
+		{"forwarded proto", func() *http.Request {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("X-Forwarded-Proto", "https")
+			return r
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, tt.req())
+
+			want := "max-age=3600; includeSubDomains; preload"
+			if got := rr.Header().Get("Strict-Transport-Security"); got != want {
+				t.Errorf("expected HSTS %q, got %q", want, got)
+			}
+		})
+	}
+}
+
+func TestHeaders_EmptyFieldsOmitted(t *testing.T) {
+	rr := httptest.NewRecorder()
+	Headers(HeadersConfig{FrameOptions: "SAMEORIGIN"})(okHandler).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rr.Header().Get("X-Frame-Options") != "SAMEORIGIN" {
+		t.Errorf("expected X-Frame-Options SAMEORIGIN, got %q", rr.Header().Get("X-Frame-Options"))
+	}
+	for _, k := range []string{"X-Content-Type-Options", "Referrer-Policy", "Content-Security-Policy"} {
+		if rr.Header().Get(k) != "" {
+			t.Errorf("expected %s to be unset", k)
+		}
+	}
+}
+
+func TestHeaders_CSPNonce(t *testing.T) {
+	var nonces []string
+	r := router.NewRouter()
+	r.Use(DefaultHeaders())
+	r.GET("/page", func(c *context.Context) error {
+		nonces = append(nonces, c.CSPNonce())
+		return nil
+	})
+
+	var policies []string
+	for i := 0; i < 2; i++ {
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/page", nil))
+		policies = append(policies, rr.Header().Get("Content-Security-Policy"))
+	}
+
+	if nonces[0] == "" || nonces[0] == nonces[1] {
+		t.Fatalf("expected distinct per-request nonces, got %q", nonces)
+	}
+	for i, p := range policies {
+		if !strings.Contains(p, "'nonce-"+nonces[i]+"'") {
+			t.Errorf("expected policy %q to contain nonce %q", p, nonces[i])
+		}
+		if strings.Contains(p, NoncePlaceholder) {
+			t.Errorf("expected placeholder to be replaced in %q", p)
+		}
+	}
+}
+
+func TestHeaders_CSPReportOnlyWithoutNonce(t *testing.T) {
+	var nonce string
+	h := Headers(HeadersConfig{
+		ContentSecurityPolicy: "default-src 'self'",
+		CSPReportOnly:         true,
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nonce = (&context.Context{W: w, R: r}).CSPNonce()
+	}))
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rr.Header().Get("Content-Security-Policy-Report-Only") != "default-src 'self'" {
+		t.Errorf("expected report-only policy, got %q", rr.Header().Get("Content-Security-Policy-Report-Only"))
+	}
+	if rr.Header().Get("Content-Security-Policy") != "" {
+		t.Error("expected enforcing header to be unset")
+	}
+	if nonce != "" {
+		t.Errorf("expected no nonce without placeholder, got %q", nonce)
+	}
+}
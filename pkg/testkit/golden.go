@@ -0,0 +1,114 @@
+package testkit
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// UpdateEnv is the environment variable that makes Golden rewrite snapshot
+// files instead of comparing against them:
+//
+//	UPDATE_GOLDEN=1 go test ./...
+const UpdateEnv = "UPDATE_GOLDEN"
+
+// GoldenDir is the directory holding snapshot files, relative to the test's
+// package directory.
+const GoldenDir = "testdata"
+
+// ignoredValue replaces values at ignored JSON paths in snapshots.
+const ignoredValue = "<ignored>"
+
+// Golden compares the body with testdata/<name>.golden. JSON bodies are
+// re-indented with sorted keys so snapshots are stable, and values at the
+// ignore paths (e.g. "data.created_at", "data.items.0.id") are replaced
+// with "<ignored>" before comparing. Set UPDATE_GOLDEN=1 to write the
+// snapshot instead.
+func (r *Response) Golden(name string, ignore ...string) *Response {
+	r.t.Helper()
+
+	got, err := normalizeSnapshot(r.Body, ignore)
+	if err != nil {
+		r.errorf("golden %s: %v", name, err)
+		return r
+	}
+
+	path := filepath.Join(GoldenDir, name+".golden")
+	if os.Getenv(UpdateEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			r.t.Fatalf("%s: golden %s: %v", r.label, name, err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			r.t.Fatalf("%s: golden %s: %v", r.label, name, err)
+		}
+		return r
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		r.errorf("golden %s: %v (run with %s=1 to create it)", name, err, UpdateEnv)
+		return r
+	}
+	if !bytes.Equal(got, want) {
+		r.errorf("golden %s mismatch (run with %s=1 to update)\n--- want\n%s\n--- got\n%s", name, UpdateEnv, want, got)
+	}
+	return r
+}
+
+// normalizeSnapshot canonicalises JSON bodies and blanks ignored paths.
+// Non-JSON bodies are returned unchanged.
+func normalizeSnapshot(body []byte, ignore []string) ([]byte, error) {
+	var doc any
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		if len(ignore) > 0 {
+			return nil, err
+		}
+		return body, nil
+	}
+
+	for _, path := range ignore {
+		if err := replacePath(doc, path, ignoredValue); err != nil {
+			return nil, err
+		}
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// replacePath sets the value at path in a decoded JSON document.
+func replacePath(doc any, path, value string) error {
+	parent := doc
+	keys := strings.Split(path, ".")
+	if len(keys) > 1 {
+		var err error
+		parent, err = lookupPath(doc, strings.Join(keys[:len(keys)-1], "."))
+		if err != nil {
+			return err
+		}
+	}
+
+	last := keys[len(keys)-1]
+	switch node := parent.(type) {
+	case map[string]any:
+		if _, ok := node[last]; ok {
+			node[last] = value
+		}
+	case []any:
+		if i, err := strconv.Atoi(last); err == nil && i >= 0 && i < len(node) {
+			node[i] = value
+		}
+	}
+	return nil
+}
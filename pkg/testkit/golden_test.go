@@ -0,0 +1,65 @@
+package testkit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// ===========================================================================
+// Golden Snapshots
+// ===========================================================================
+
+func TestGolden_WriteAndCompare(t *testing.T) {
+	t.Chdir(t.TempDir())
+	tk := New(newTestRouter())
+
+	t.Setenv(UpdateEnv, "1")
+	tk.GET("/users/1").Expect(t).Golden("user", "data.auth")
+
+	data, err := os.ReadFile(filepath.Join(GoldenDir, "user.golden"))
+	if err != nil {
+		t.Fatalf("expected snapshot to be written: %v", err)
+	}
+	if !strings.Contains(string(data), `"auth": "<ignored>"`) {
+		t.Errorf("expected ignored path to be blanked, got:\n%s", data)
+	}
+
+	t.Setenv(UpdateEnv, "")
+	tk.GET("/users/1").WithBearer("different").Expect(t).Golden("user", "data.auth")
+
+	rec := &recordingTB{TB: t}
+	tk.GET("/users/2").Expect(rec).Golden("user", "data.auth")
+	if len(rec.errors) != 1 || !strings.Contains(rec.errors[0], "golden user mismatch") {
+		t.Errorf("expected a mismatch failure, got %v", rec.errors)
+	}
+}
+
+func TestGolden_Missing(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv(UpdateEnv, "")
+	rec := &recordingTB{TB: t}
+
+	New(newTestRouter()).GET("/users/1").Expect(rec).Golden("absent")
+
+	if len(rec.errors) != 1 || !strings.Contains(rec.errors[0], UpdateEnv+"=1") {
+		t.Errorf("expected a hint to create the snapshot, got %v", rec.errors)
+	}
+}
+
+func TestNormalizeSnapshot(t *testing.T) {
+	got, err := normalizeSnapshot([]byte(`{"b":1,"a":{"x":[1,2]}}`), []string{"a.x.1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "{\n  \"a\": {\n    \"x\": [\n      1,\n      \"<ignored>\"\n    ]\n  },\n  \"b\": 1\n}\n"
+	if string(got) != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, got)
+	}
+
+	plain, err := normalizeSnapshot([]byte("hello"), nil)
+	if err != nil || string(plain) != "hello" {
+		t.Errorf("expected non-JSON body unchanged, got %q (%v)", plain, err)
+	}
+}
@@ -0,0 +1,86 @@
+package testkit
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"sync"
+	"testing"
+
+	"github.com/vietpham102301/lightway/pkg/jwt"
+)
+
+// testKey is generated once per test binary; RSA key generation is too slow
+// to repeat in every test.
+var testKey = sync.OnceValue(func() *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic("testkit: generate RSA key: " + err.Error())
+	}
+	return key
+})
+
+// PrivateKey returns the RSA key that signs tokens minted by the
+// package-level functions.
+func PrivateKey() *rsa.PrivateKey {
+	return testKey()
+}
+
+// PublicKey returns the key that verifies tokens minted by the
+// package-level functions. Configure the auth middleware under test with it.
+func PublicKey() *rsa.PublicKey {
+	return &testKey().PublicKey
+}
+
+// MintToken returns a token for the user, valid for one hour, signed with
+// PrivateKey via jwt.GenerateToken.
+func MintToken(t testing.TB, userID int, username, role string) string {
+	t.Helper()
+	return NewSigner(PrivateKey()).MintToken(t, userID, username, role)
+}
+
+// ExpiredToken returns a token for the user that expired an hour ago.
+func ExpiredToken(t testing.TB, userID int, username, role string) string {
+	t.Helper()
+	return NewSigner(PrivateKey()).ExpiredToken(t, userID, username, role)
+}
+
+// Signer mints tokens with a given key, such as the one the app under test
+// loads from its own configuration, so its auth middleware needs no
+// test-only setup.
+type Signer struct {
+	key *rsa.PrivateKey
+}
+
+// NewSigner returns a Signer for key. A nil key falls back to PrivateKey.
+func NewSigner(key *rsa.PrivateKey) *Signer {
+	if key == nil {
+		key = PrivateKey()
+	}
+	return &Signer{key: key}
+}
+
+// PublicKey returns the key that verifies tokens minted by s.
+func (s *Signer) PublicKey() *rsa.PublicKey {
+	return &s.key.PublicKey
+}
+
+// MintToken returns a token for the user, valid for one hour.
+func (s *Signer) MintToken(t testing.TB, userID int, username, role string) string {
+	t.Helper()
+	return s.mint(t, userID, username, role, 1)
+}
+
+// ExpiredToken returns a token for the user that expired an hour ago.
+func (s *Signer) ExpiredToken(t testing.TB, userID int, username, role string) string {
+	t.Helper()
+	return s.mint(t, userID, username, role, -1)
+}
+
+func (s *Signer) mint(t testing.TB, userID int, username, role string, expiresInHours int) string {
+	t.Helper()
+	token, err := jwt.GenerateToken(s.key, userID, username, role, expiresInHours)
+	if err != nil {
+		t.Fatalf("testkit: mint token: %v", err)
+	}
+	return token
+}
@@ -0,0 +1,62 @@
+package testkit
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/vietpham102301/lightway/pkg/jwt"
+)
+
+func TestMintToken(t *testing.T) {
+	token := MintToken(t, 42, "ann", "admin")
+
+	claims, err := jwt.ValidateToken(PublicKey(), token)
+	if err != nil {
+		t.Fatalf("expected minted token to validate, got %v", err)
+	}
+	if claims.UserID != 42 || claims.Username != "ann" || claims.Role != "admin" {
+		t.Errorf("unexpected claims: %+v", claims)
+	}
+}
+
+func TestExpiredToken(t *testing.T) {
+	token := ExpiredToken(t, 1, "ann", "user")
+
+	if _, err := jwt.ValidateToken(PublicKey(), token); err == nil {
+		t.Error("expected expired token to be rejected")
+	}
+}
+
+func TestPrivateKey_Stable(t *testing.T) {
+	if PrivateKey() != PrivateKey() {
+		t.Error("expected the same key on every call")
+	}
+}
+
+func TestSigner_OwnKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	signer := NewSigner(key)
+	token := signer.MintToken(t, 7, "bob", "user")
+
+	if _, err := jwt.ValidateToken(&key.PublicKey, token); err != nil {
+		t.Fatalf("expected token to validate with the signer's key, got %v", err)
+	}
+	if _, err := jwt.ValidateToken(PublicKey(), token); err == nil {
+		t.Error("expected token to be rejected by the default key")
+	}
+	if _, err := jwt.ValidateToken(signer.PublicKey(), signer.ExpiredToken(t, 7, "bob", "user")); err == nil {
+		t.Error("expected expired token to be rejected")
+	}
+}
+
+func TestSigner_NilKeyFallsBack(t *testing.T) {
+	token := NewSigner(nil).MintToken(t, 1, "ann", "user")
+
+	if _, err := jwt.ValidateToken(PublicKey(), token); err != nil {
+		t.Errorf("expected token signed with the default key, got %v", err)
+	}
+}
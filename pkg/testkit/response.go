@@ -0,0 +1,212 @@
+package testkit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/vietpham102301/lightway/pkg/context"
+)
+
+// Response is a recorded response. Assertion methods report failures with
+// t.Errorf, so every failed check in a chain is reported, and return the
+// Response for chaining.
+type Response struct {
+	Code    int
+	Headers http.Header
+	Body    []byte
+
+	t     testing.TB
+	label string
+}
+
+func (r *Response) errorf(format string, args ...any) {
+	r.t.Helper()
+	r.t.Errorf("%s: %s", r.label, fmt.Sprintf(format, args...))
+}
+
+// Status asserts the status code.
+func (r *Response) Status(code int) *Response {
+	r.t.Helper()
+	if r.Code != code {
+		r.errorf("expected status %d, got %d\nbody: %s", code, r.Code, r.Body)
+	}
+	return r
+}
+
+// Header asserts that header key has value.
+func (r *Response) Header(key, value string) *Response {
+	r.t.Helper()
+	if got := r.Headers.Get(key); got != value {
+		r.errorf("expected header %s %q, got %q", key, value, got)
+	}
+	return r
+}
+
+// BodyEquals asserts the exact body.
+func (r *Response) BodyEquals(body string) *Response {
+	r.t.Helper()
+	if string(r.Body) != body {
+		r.errorf("expected body %q, got %q", body, r.Body)
+	}
+	return r
+}
+
+// BodyContains asserts that the body contains s.
+func (r *Response) BodyContains(s string) *Response {
+	r.t.Helper()
+	if !bytes.Contains(r.Body, []byte(s)) {
+		r.errorf("expected body to contain %q, got %q", s, r.Body)
+	}
+	return r
+}
+
+// JSON decodes the body into v, failing the test if it is not valid JSON.
+func (r *Response) JSON(v any) *Response {
+	r.t.Helper()
+	if err := json.Unmarshal(r.Body, v); err != nil {
+		r.t.Fatalf("%s: decode JSON body: %v\nbody: %s", r.label, err, r.Body)
+	}
+	return r
+}
+
+// JSONPath asserts the value at a dot-separated path in the JSON body, such
+// as "data.name" or "data.items.0.id". want is compared after a JSON round
+// trip, so numbers of any Go type match.
+func (r *Response) JSONPath(path string, want any) *Response {
+	r.t.Helper()
+	got, err := r.lookup(path)
+	if err != nil {
+		r.errorf("%v", err)
+		return r
+	}
+	if !jsonEqual(got, want) {
+		r.errorf("expected %s = %v, got %v", path, want, got)
+	}
+	return r
+}
+
+// JSONPathExists asserts that path is present in the JSON body.
+func (r *Response) JSONPathExists(path string) *Response {
+	r.t.Helper()
+	if _, err := r.lookup(path); err != nil {
+		r.errorf("%v", err)
+	}
+	return r
+}
+
+// AppResponse decodes the body as an AppResponse.
+func (r *Response) AppResponse() context.AppResponse {
+	r.t.Helper()
+	resp, err := decodeAppResponse(r.Body)
+	if err != nil {
+		r.t.Fatalf("%s: decode AppResponse: %v\nbody: %s", r.label, err, r.Body)
+	}
+	return resp
+}
+
+// OK asserts a 2xx AppResponse without an error.
+func (r *Response) OK() *Response {
+	r.t.Helper()
+	resp := r.AppResponse()
+	if r.Code < 200 || r.Code > 299 || resp.Error != "" {
+		r.errorf("expected success, got %d with error %q", r.Code, resp.Error)
+	}
+	return r
+}
+
+// Data decodes the AppResponse data field into v.
+func (r *Response) Data(v any) *Response {
+	r.t.Helper()
+	var envelope struct {
+		Data json.RawMessage `json:"data"`
+	}
+	r.JSON(&envelope)
+	if err := json.Unmarshal(envelope.Data, v); err != nil {
+		r.t.Fatalf("%s: decode data: %v\ndata: %s", r.label, err, envelope.Data)
+	}
+	return r
+}
+
+// ErrorCode asserts the AppResponse error_code, e.g. errors.CodeNotFound.
+func (r *Response) ErrorCode(code string) *Response {
+	r.t.Helper()
+	if got := r.AppResponse().ErrorCode; got != code {
+		r.errorf("expected error_code %q, got %q", code, got)
+	}
+	return r
+}
+
+// ErrorMessage asserts the AppResponse error message.
+func (r *Response) ErrorMessage(msg string) *Response {
+	r.t.Helper()
+	if got := r.AppResponse().Error; got != msg {
+		r.errorf("expected error %q, got %q", msg, got)
+	}
+	return r
+}
+
+// FieldError asserts that the AppResponse lists a validation error for
+// field.
+func (r *Response) FieldError(field string) *Response {
+	r.t.Helper()
+	resp := r.AppResponse()
+	for _, f := range resp.Fields {
+		if f.Field == field {
+			return r
+		}
+	}
+	r.errorf("expected a field error for %q, got %v", field, resp.Fields)
+	return r
+}
+
+func (r *Response) lookup(path string) (any, error) {
+	var doc any
+	if err := json.Unmarshal(r.Body, &doc); err != nil {
+		return nil, fmt.Errorf("decode JSON body: %w", err)
+	}
+	return lookupPath(doc, path)
+}
+
+// lookupPath walks a decoded JSON document along a dot-separated path.
+func lookupPath(doc any, path string) (any, error) {
+	cur := doc
+	for _, key := range strings.Split(path, ".") {
+		switch node := cur.(type) {
+		case map[string]any:
+			v, ok := node[key]
+			if !ok {
+				return nil, fmt.Errorf("path %s: key %q not found", path, key)
+			}
+			cur = v
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, fmt.Errorf("path %s: index %q out of range (len %d)", path, key, len(node))
+			}
+			cur = node[i]
+		default:
+			return nil, fmt.Errorf("path %s: cannot descend into %T at %q", path, cur, key)
+		}
+	}
+	return cur, nil
+}
+
+// jsonEqual compares a decoded JSON value with a Go value by round-tripping
+// want through encoding/json.
+func jsonEqual(got, want any) bool {
+	data, err := json.Marshal(want)
+	if err != nil {
+		return false
+	}
+	var norm any
+	if err := json.Unmarshal(data, &norm); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(got, norm)
+}
@@ -0,0 +1,90 @@
+package testkit
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	aerror "github.com/vietpham102301/lightway/pkg/errors"
+)
+
+// ===========================================================================
+// AppResponse Assertions
+// ===========================================================================
+
+func TestResponse_AppErrorAssertions(t *testing.T) {
+	tk := New(newTestRouter())
+
+	tk.GET("/users/404").
+		Expect(t).
+		Status(http.StatusNotFound).
+		ErrorCode(aerror.CodeNotFound).
+		ErrorMessage("User not found")
+
+	tk.POST("/users").
+		WithJSON(map[string]string{}).
+		Expect(t).
+		Status(http.StatusUnprocessableEntity).
+		FieldError("name")
+}
+
+func TestResponse_FailuresAreReported(t *testing.T) {
+	tk := New(newTestRouter())
+	rec := &recordingTB{TB: t}
+
+	tk.GET("/users/1").
+		Expect(rec).
+		Status(http.StatusTeapot).
+		JSONPath("data.name", "Bob").
+		JSONPath("data.missing", 1).
+		JSONPath("data.roles.5", "x").
+		Header("X-Missing", "v").
+		ErrorCode(aerror.CodeNotFound)
+
+	if len(rec.errors) != 6 {
+		t.Fatalf("expected 6 failures, got %d: %v", len(rec.errors), rec.errors)
+	}
+	if !strings.HasPrefix(rec.errors[0], "GET /users/1: expected status 418, got 200") {
+		t.Errorf("unexpected status failure: %q", rec.errors[0])
+	}
+	if !strings.Contains(rec.errors[1], `expected data.name = Bob, got Ann`) {
+		t.Errorf("unexpected JSONPath failure: %q", rec.errors[1])
+	}
+	if !strings.Contains(rec.errors[2], `key "missing" not found`) {
+		t.Errorf("unexpected missing key failure: %q", rec.errors[2])
+	}
+}
+
+func TestResponse_NonJSONBody(t *testing.T) {
+	tk := New(newTestRouter())
+	rec := &recordingTB{TB: t}
+
+	tk.GET("/cookie").
+		WithCookie(&http.Cookie{Name: "session", Value: "plain"}).
+		Expect(rec).
+		BodyContains("pla").
+		AppResponse()
+
+	if !rec.fatal {
+		t.Error("expected AppResponse on a non-JSON body to fail fatally")
+	}
+}
+
+func TestLookupPath(t *testing.T) {
+	doc := map[string]any{
+		"data": map[string]any{
+			"items": []any{map[string]any{"id": float64(7)}},
+		},
+	}
+
+	got, err := lookupPath(doc, "data.items.0.id")
+	if err != nil || got != float64(7) {
+		t.Errorf("expected 7, got %v (%v)", got, err)
+	}
+	if _, err := lookupPath(doc, "data.items.x"); err == nil {
+		t.Error("expected error for non-numeric index")
+	}
+	if _, err := lookupPath(doc, "data.items.0.id.deeper"); err == nil {
+		t.Error("expected error descending into a number")
+	}
+}
@@ -0,0 +1,197 @@
+// Package testkit provides a fluent in-process HTTP client for testing
+// router-based services, with assertions that understand AppResponse,
+// golden-file snapshots and helpers for minting JWTs.
+//
+//	tk := testkit.New(r)
+//	tk.GET("/users/1").WithBearer(testkit.MintToken(t, 1, "ann", "admin")).
+//		Expect(t).
+//		Status(200).
+//		JSONPath("data.name", "Ann")
+package testkit
+
+import (
+	"bytes"
+	stdctx "context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/vietpham102301/lightway/pkg/context"
+)
+
+// Client sends requests to a handler, typically a *router.Router, without
+// a network listener. Its default headers are sent with every request.
+type Client struct {
+	handler http.Handler
+	header  http.Header
+}
+
+// New creates a client for h.
+func New(h http.Handler) *Client {
+	return &Client{handler: h, header: make(http.Header)}
+}
+
+// WithHeader returns a copy of the client that sends key: value on every
+// request.
+func (c *Client) WithHeader(key, value string) *Client {
+	cp := &Client{handler: c.handler, header: c.header.Clone()}
+	cp.header.Set(key, value)
+	return cp
+}
+
+// WithBearer returns a copy of the client that authenticates every request
+// with token.
+func (c *Client) WithBearer(token string) *Client {
+	return c.WithHeader("Authorization", "Bearer "+token)
+}
+
+// GET starts a GET request.
+func (c *Client) GET(path string) *Request { return c.Request(http.MethodGet, path) }
+
+// POST starts a POST request.
+func (c *Client) POST(path string) *Request { return c.Request(http.MethodPost, path) }
+
+// PUT starts a PUT request.
+func (c *Client) PUT(path string) *Request { return c.Request(http.MethodPut, path) }
+
+// PATCH starts a PATCH request.
+func (c *Client) PATCH(path string) *Request { return c.Request(http.MethodPatch, path) }
+
+// DELETE starts a DELETE request.
+func (c *Client) DELETE(path string) *Request { return c.Request(http.MethodDelete, path) }
+
+// Request starts a request with any method.
+func (c *Client) Request(method, path string) *Request {
+	return &Request{
+		client: c,
+		method: method,
+		path:   path,
+		header: c.header.Clone(),
+		query:  make(url.Values),
+	}
+}
+
+// Request is a request being built. Its methods modify and return the same
+// Request.
+type Request struct {
+	client  *Client
+	method  string
+	path    string
+	header  http.Header
+	query   url.Values
+	cookies []*http.Cookie
+	body    []byte
+	ctx     stdctx.Context
+	err     error
+}
+
+// WithHeader sets a request header.
+func (r *Request) WithHeader(key, value string) *Request {
+	r.header.Set(key, value)
+	return r
+}
+
+// WithBearer sets an "Authorization: Bearer" header.
+func (r *Request) WithBearer(token string) *Request {
+	return r.WithHeader("Authorization", "Bearer "+token)
+}
+
+// WithQuery adds a query parameter.
+func (r *Request) WithQuery(key, value string) *Request {
+	r.query.Add(key, value)
+	return r
+}
+
+// WithCookie adds a cookie.
+func (r *Request) WithCookie(cookie *http.Cookie) *Request {
+	r.cookies = append(r.cookies, cookie)
+	return r
+}
+
+// WithJSON encodes v as the JSON request body.
+func (r *Request) WithJSON(v any) *Request {
+	data, err := json.Marshal(v)
+	if err != nil {
+		r.err = fmt.Errorf("testkit: encode JSON body: %w", err)
+		return r
+	}
+	return r.WithBody("application/json", data)
+}
+
+// WithBody sets a raw request body and its Content-Type.
+func (r *Request) WithBody(contentType string, body []byte) *Request {
+	r.body = body
+	r.header.Set("Content-Type", contentType)
+	return r
+}
+
+// WithContext sets the request context.
+func (r *Request) WithContext(ctx stdctx.Context) *Request {
+	r.ctx = ctx
+	return r
+}
+
+// Do sends the request and returns the response without assertions bound.
+// It returns an error only if the request could not be built.
+func (r *Request) Do() (*Response, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	target := r.path
+	if len(r.query) > 0 {
+		sep := "?"
+		if strings.Contains(target, "?") {
+			sep = "&"
+		}
+		target += sep + r.query.Encode()
+	}
+
+	var body io.Reader
+	if r.body != nil {
+		body = bytes.NewReader(r.body)
+	}
+	req := httptest.NewRequest(r.method, target, body)
+	if r.ctx != nil {
+		req = req.WithContext(r.ctx)
+	}
+	for k, v := range r.header {
+		req.Header[k] = append([]string(nil), v...)
+	}
+	for _, c := range r.cookies {
+		req.AddCookie(c)
+	}
+
+	rec := httptest.NewRecorder()
+	r.client.handler.ServeHTTP(rec, req)
+	return &Response{
+		Code:    rec.Code,
+		Headers: rec.Header(),
+		Body:    rec.Body.Bytes(),
+	}, nil
+}
+
+// Expect sends the request and returns the response with assertions that
+// report failures to t.
+func (r *Request) Expect(t testing.TB) *Response {
+	t.Helper()
+	resp, err := r.Do()
+	if err != nil {
+		t.Fatalf("%s %s: %v", r.method, r.path, err)
+	}
+	resp.t = t
+	resp.label = r.method + " " + r.path
+	return resp
+}
+
+// decodeAppResponse decodes a body written by Context.JSONResponse.
+func decodeAppResponse(body []byte) (context.AppResponse, error) {
+	var resp context.AppResponse
+	err := json.Unmarshal(body, &resp)
+	return resp, err
+}
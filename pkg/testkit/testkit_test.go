@@ -0,0 +1,135 @@
+package testkit
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/vietpham102301/lightway/pkg/context"
+	aerror "github.com/vietpham102301/lightway/pkg/errors"
+	"github.com/vietpham102301/lightway/pkg/router"
+)
+
+// recordingTB captures assertion failures instead of failing the test.
+type recordingTB struct {
+	testing.TB
+	errors []string
+	fatal  bool
+}
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recordingTB) Fatalf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+	r.fatal = true
+}
+
+func newTestRouter() *router.Router {
+	r := router.NewRouter()
+	r.GET("/users/{id}", func(c *context.Context) error {
+		if c.Param("id") == "404" {
+			return aerror.NotFound("User not found")
+		}
+		c.JSONResponse(http.StatusOK, map[string]any{
+			"id":    c.Param("id"),
+			"name":  "Ann",
+			"auth":  c.R.Header.Get("Authorization"),
+			"page":  c.Query("page"),
+			"roles": []string{"admin", "ops"},
+		}, nil)
+		return nil
+	})
+	r.POST("/users", func(c *context.Context) error {
+		var body struct {
+			Name string `json:"name"`
+		}
+		if err := c.BindJSON(&body); err != nil {
+			return aerror.InvalidRequest(err)
+		}
+		if body.Name == "" {
+			return aerror.UnprocessableEntity("Validation failed", aerror.FieldError{Field: "name", Message: "required"})
+		}
+		c.JSONResponse(http.StatusCreated, body, nil)
+		return nil
+	})
+	r.GET("/cookie", func(c *context.Context) error {
+		cookie, err := c.R.Cookie("session")
+		if err != nil {
+			return aerror.Unauthorized("no session")
+		}
+		c.W.Write([]byte(cookie.Value))
+		return nil
+	})
+	return r
+}
+
+// ===========================================================================
+// Requests
+// ===========================================================================
+
+func TestClient_GET(t *testing.T) {
+	tk := New(newTestRouter())
+
+	tk.GET("/users/1").
+		WithBearer("tok").
+		WithQuery("page", "2").
+		Expect(t).
+		Status(http.StatusOK).
+		OK().
+		JSONPath("data.id", "1").
+		JSONPath("data.name", "Ann").
+		JSONPath("data.auth", "Bearer tok").
+		JSONPath("data.page", "2").
+		JSONPath("data.roles.1", "ops").
+		JSONPath("code", 200).
+		Header("Content-Type", "application/json")
+}
+
+func TestClient_DefaultHeaders(t *testing.T) {
+	base := New(newTestRouter())
+	authed := base.WithBearer("abc")
+
+	authed.GET("/users/1").Expect(t).JSONPath("data.auth", "Bearer abc")
+	base.GET("/users/1").Expect(t).JSONPath("data.auth", "")
+}
+
+func TestClient_POSTJSON(t *testing.T) {
+	tk := New(newTestRouter())
+
+	var created struct {
+		Name string `json:"name"`
+	}
+	tk.POST("/users").
+		WithJSON(map[string]string{"name": "Bob"}).
+		Expect(t).
+		Status(http.StatusCreated).
+		Data(&created)
+
+	if created.Name != "Bob" {
+		t.Errorf("expected name Bob, got %q", created.Name)
+	}
+}
+
+func TestClient_Cookie(t *testing.T) {
+	tk := New(newTestRouter())
+
+	tk.GET("/cookie").
+		WithCookie(&http.Cookie{Name: "session", Value: "s1"}).
+		Expect(t).
+		Status(http.StatusOK).
+		BodyEquals("s1")
+}
+
+func TestClient_WithJSONError(t *testing.T) {
+	tk := New(newTestRouter())
+
+	_, err := tk.POST("/users").WithJSON(make(chan int)).Do()
+	if err == nil || !strings.HasPrefix(err.Error(), "testkit:") {
+		t.Errorf("expected encode error, got %v", err)
+	}
+}